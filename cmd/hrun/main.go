@@ -1,14 +1,31 @@
 package main
 
 import (
+	"bufio"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
 	"time"
+	"unicode"
 
+	"github.com/cassielabs/hrun/internal/bench"
+	"github.com/cassielabs/hrun/internal/cassette"
+	"github.com/cassielabs/hrun/internal/dataset"
 	"github.com/cassielabs/hrun/internal/executor"
+	"github.com/cassielabs/hrun/internal/export"
+	"github.com/cassielabs/hrun/internal/har"
+	"github.com/cassielabs/hrun/internal/importer"
+	"github.com/cassielabs/hrun/internal/mock"
+	"github.com/cassielabs/hrun/internal/openapi"
 	"github.com/cassielabs/hrun/internal/parser"
+	"github.com/cassielabs/hrun/internal/postman"
 	"github.com/cassielabs/hrun/internal/runner"
+	"github.com/cassielabs/hrun/internal/secret"
 	"github.com/cassielabs/hrun/internal/tui"
+	"github.com/charmbracelet/x/term"
 	"github.com/joho/godotenv"
 	"github.com/spf13/cobra"
 )
@@ -18,6 +35,48 @@ var (
 	requestName  string
 	envFile      string
 	timeout      time.Duration
+	retries      int
+	parallel     int
+
+	benchConcurrency int
+	benchRate        float64
+	benchDuration    time.Duration
+	benchIterations  int
+	benchWarmup      time.Duration
+	benchJSON        bool
+
+	recordPath     string
+	replayPath     string
+	cassetteMatch  []string
+	cassetteRedact []string
+
+	mockAddr    string
+	mockLatency time.Duration
+	mockJitter  time.Duration
+	mockQuiet   bool
+
+	updateSnapshots bool
+	schemaDir       string
+	openAPIPath     string
+
+	importOutput string
+
+	importSplit bool
+
+	harPath         string
+	harDomains      []string
+	harContentTypes []string
+
+	tags        []string
+	excludeTags []string
+
+	secretPattern   string
+	runReferenced   bool
+	promptUndefined bool
+
+	exportFormat     string
+	exportEnvRefs    bool
+	exportCollection string
 )
 
 var rootCmd = &cobra.Command{
@@ -42,26 +101,38 @@ var runCmd = &cobra.Command{
 			return fmt.Errorf("failed to parse file: %w", err)
 		}
 
-		for key, value := range httpFile.Variables {
-			if envValue := os.Getenv(key); envValue != "" {
-				httpFile.Variables[key] = envValue
-			} else {
-				httpFile.Variables[key] = value
-			}
+		httpFile.ApplyEnv()
+
+		masker, err := secretMasker()
+		if err != nil {
+			return err
 		}
+		masker.AddFile(httpFile)
 
-		exec := executor.New(timeout)
+		cassetteOpts, saveCassette, err := cassetteOptions(masker)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := saveCassette(); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: Could not save cassette %s: %v\n", recordPath, err)
+			}
+		}()
+
+		harOpts, saveHAR := harOptions(masker)
+		defer func() {
+			if err := saveHAR(); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: Could not save HAR %s: %v\n", harPath, err)
+			}
+		}()
+
+		opts := append([]executor.Option{executor.WithRetries(retries), executor.WithParallel(parallel), executor.WithRunReferenced(runReferenced), executor.WithPrompter(terminalPrompter(masker), promptUndefined), executor.WithExecuteHook(masker.Hook())}, cassetteOpts...)
+		exec := executor.New(timeout, append(opts, harOpts...)...)
 
 		if requestName != "" {
 			for _, req := range httpFile.Requests {
 				if req.Name == requestName {
-					req.ApplyVariables(httpFile.Variables)
-					resp, err := exec.Execute(req)
-					if err != nil {
-						return err
-					}
-					fmt.Println(executor.FormatResponse(resp))
-					return nil
+					return runRequest(exec, httpFile, req, fmt.Sprintf("request '%s'", requestName), masker)
 				}
 			}
 			return fmt.Errorf("request with name '%s' not found", requestName)
@@ -71,14 +142,36 @@ var runCmd = &cobra.Command{
 			if requestIndex > len(httpFile.Requests) {
 				return fmt.Errorf("request index %d out of range (file has %d requests)", requestIndex, len(httpFile.Requests))
 			}
-			req := httpFile.Requests[requestIndex-1]
-			req.ApplyVariables(httpFile.Variables)
-			resp, err := exec.Execute(req)
-			if err != nil {
-				return err
+			return runRequest(exec, httpFile, httpFile.Requests[requestIndex-1], fmt.Sprintf("request %d", requestIndex), masker)
+		}
+
+		// --name and --request pick a request explicitly; a full run honours
+		// tags, @only and @skip.
+		var requests []parser.HTTPRequest
+		for i, selected := range parser.Select(httpFile.Requests, tags, excludeTags) {
+			req := httpFile.Requests[i]
+			if !selected {
+				continue
 			}
-			fmt.Println(executor.FormatResponse(resp))
-			return nil
+			if req.Skip {
+				label := req.Method + " " + req.URL
+				if req.Name != "" {
+					label = "[" + req.Name + "] " + label
+				}
+				fmt.Printf("Skipping %s", label)
+				if req.SkipReason != "" {
+					fmt.Printf(": %s", req.SkipReason)
+				}
+				fmt.Println()
+				continue
+			}
+			requests = append(requests, req)
+		}
+		if len(requests) == 0 && len(httpFile.Requests) > 0 {
+			return fmt.Errorf("no requests in %s to run", args[0])
+		}
+		if httpFile.Requests, err = dataset.Expand(args[0], requests); err != nil {
+			return err
 		}
 
 		responses, err := exec.ExecuteAll(httpFile)
@@ -89,16 +182,43 @@ var runCmd = &cobra.Command{
 		for i, resp := range responses {
 			req := httpFile.Requests[i]
 			fmt.Printf("\n=== Request %d: %s %s ===\n", i+1, req.Method, req.URL)
-			if req.Name != "" {
-				fmt.Printf("Name: %s\n", req.Name)
+			if label := dataset.Label(req); label != "" {
+				fmt.Printf("Name: %s\n", label)
 			}
-			fmt.Println(executor.FormatResponse(resp))
+			fmt.Println(masker.Mask(executor.FormatResponse(resp)))
 		}
 
 		return nil
 	},
 }
 
+// runRequest runs a request picked with --name or --request, once per row
+// of its @data file.
+func runRequest(exec *executor.Executor, httpFile *parser.HTTPFile, req parser.HTTPRequest, label string, masker *secret.Masker) error {
+	iterations, err := dataset.Iterations(httpFile.Path, req)
+	if err != nil {
+		return fmt.Errorf("%s: %w", label, err)
+	}
+	for _, req := range iterations {
+		if req.Iteration > 0 {
+			fmt.Printf("\n=== %s ===\n", dataset.Label(req))
+		}
+		req.Headers = req.Headers.Clone()
+		if err := exec.ApplyVariables(httpFile, &req, httpFile.Variables); err != nil {
+			return fmt.Errorf("%s: %w", label, err)
+		}
+		resp, err := exec.Execute(req)
+		if err != nil {
+			return fmt.Errorf("%s", masker.Mask(err.Error()))
+		}
+		fmt.Println(masker.Mask(executor.FormatResponse(resp)))
+		for varName, varValue := range resp.CapturedVariables {
+			httpFile.Variables[varName] = varValue
+		}
+	}
+	return nil
+}
+
 var tuiCmd = &cobra.Command{
 	Use:   "tui [file]",
 	Short: "Open file in TUI mode",
@@ -115,7 +235,12 @@ var tuiCmd = &cobra.Command{
 			filePath = args[0]
 		}
 
-		return tui.Run(filePath, timeout)
+		masker, err := secretMasker()
+		if err != nil {
+			return err
+		}
+
+		return tui.Run(filePath, timeout, masker, promptUndefined, executor.WithRunReferenced(runReferenced))
 	},
 }
 
@@ -130,25 +255,540 @@ var testCmd = &cobra.Command{
 			}
 		}
 
-		return runner.RunTests(args[0], timeout)
+		masker, err := secretMasker()
+		if err != nil {
+			return err
+		}
+		cassetteOpts, saveCassette, err := cassetteOptions(masker)
+		if err != nil {
+			return err
+		}
+		harOpts, saveHAR := harOptions(masker)
+
+		testErr := runner.RunTests(args[0], runner.Options{
+			Timeout:         timeout,
+			Retries:         retries,
+			UpdateSnapshots: updateSnapshots,
+			SchemaDir:       schemaDir,
+			OpenAPI:         openAPIPath,
+			Tags:            tags,
+			ExcludeTags:     excludeTags,
+			Secrets:         masker,
+			ExecutorOptions: append(append(cassetteOpts, harOpts...), executor.WithRunReferenced(runReferenced), executor.WithPrompter(terminalPrompter(masker), promptUndefined)),
+		})
+		if err := saveCassette(); err != nil {
+			return fmt.Errorf("failed to save cassette %s: %w", recordPath, err)
+		}
+		if err := saveHAR(); err != nil {
+			return fmt.Errorf("failed to save HAR %s: %w", harPath, err)
+		}
+		return testErr
+	},
+}
+
+var benchCmd = &cobra.Command{
+	Use:   "bench [file]",
+	Short: "Load-test a request or a whole file",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if envFile != "" {
+			if err := godotenv.Load(envFile); err != nil {
+				fmt.Printf("Warning: Could not load env file %s: %v\n", envFile, err)
+			}
+		}
+
+		httpFile, err := parser.ParseFile(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to parse file: %w", err)
+		}
+
+		httpFile.ApplyEnv()
+
+		// Like run, --name picks a request explicitly, while a whole file
+		// honours tags, @only and @skip. Both replay every @data row.
+		var requests []parser.HTTPRequest
+		if requestName != "" {
+			for _, req := range httpFile.Requests {
+				if req.Name == requestName {
+					requests = []parser.HTTPRequest{req}
+					break
+				}
+			}
+			if requests == nil {
+				return fmt.Errorf("request with name '%s' not found", requestName)
+			}
+		} else {
+			for i, selected := range parser.Select(httpFile.Requests, tags, excludeTags) {
+				if selected && !httpFile.Requests[i].Skip {
+					requests = append(requests, httpFile.Requests[i])
+				}
+			}
+		}
+		if requests, err = dataset.Expand(args[0], requests); err != nil {
+			return err
+		}
+
+		result, err := bench.Run(cmd.Context(), requests, httpFile.Variables, bench.Options{
+			Concurrency: benchConcurrency,
+			Rate:        benchRate,
+			Duration:    benchDuration,
+			Iterations:  benchIterations,
+			Warmup:      benchWarmup,
+			Timeout:     timeout,
+		})
+		if err != nil {
+			return err
+		}
+
+		if benchJSON {
+			data, err := result.JSON()
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(data))
+			return nil
+		}
+		fmt.Print(result.String())
+		return nil
+	},
+}
+
+var mockCmd = &cobra.Command{
+	Use:   "mock [file]",
+	Short: "Serve the example responses in a file as a mock API",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if envFile != "" {
+			if err := godotenv.Load(envFile); err != nil {
+				fmt.Printf("Warning: Could not load env file %s: %v\n", envFile, err)
+			}
+		}
+
+		httpFile, err := parser.ParseFile(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to parse file: %w", err)
+		}
+
+		httpFile.ApplyEnv()
+
+		opts := mock.Options{Latency: mockLatency, Jitter: mockJitter}
+		if !mockQuiet {
+			opts.Log = os.Stdout
+		}
+		server, err := mock.New(httpFile, opts)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Mock server for %s listening on %s\n\n", args[0], mockAddr)
+		for _, route := range server.Routes() {
+			fmt.Printf("  %s\n", route)
+		}
+		fmt.Println()
+
+		return http.ListenAndServe(mockAddr, server)
+	},
+}
+
+var importCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Generate a .http file from another format",
+}
+
+var importOpenAPICmd = &cobra.Command{
+	Use:   "openapi [spec]",
+	Short: "Generate requests from an OpenAPI 3 spec",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		spec, err := openapi.Load(args[0])
+		if err != nil {
+			return err
+		}
+		return writeImport(importer.OpenAPI(spec))
+	},
+}
+
+var importCurlCmd = &cobra.Command{
+	Use:   "curl [file]",
+	Short: "Convert curl command lines from a file or stdin",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var input []byte
+		var err error
+		if len(args) == 0 || args[0] == "-" {
+			input, err = io.ReadAll(os.Stdin)
+		} else {
+			input, err = os.ReadFile(args[0])
+		}
+		if err != nil {
+			return err
+		}
+
+		file, err := importer.Curl(string(input))
+		if err != nil {
+			return err
+		}
+		return writeImport(file)
+	},
+}
+
+var importHARCmd = &cobra.Command{
+	Use:   "har [file.har]",
+	Short: "Convert requests recorded by browser devtools",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		h, err := har.Load(args[0])
+		if err != nil {
+			return err
+		}
+		file := importer.HAR(h, importer.HARFilter{Domains: harDomains, ContentTypes: harContentTypes})
+		if len(file.Requests) == 0 {
+			return fmt.Errorf("no requests in %s match the filters", args[0])
+		}
+		return writeImport(file)
+	},
+}
+
+var exportCmd = &cobra.Command{
+	Use:   "export [file...]",
+	Short: "Print requests as curl, HTTPie or wget commands, or as a Postman collection",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var httpFiles []*parser.HTTPFile
+		found := false
+		for _, path := range args {
+			httpFile, err := parser.ParseFile(path)
+			if err != nil {
+				return fmt.Errorf("failed to parse file: %w", err)
+			}
+			if requestName != "" {
+				var requests []parser.HTTPRequest
+				for _, req := range httpFile.Requests {
+					if req.Name == requestName {
+						requests = append(requests, req)
+					}
+				}
+				httpFile.Requests = requests
+			}
+			found = found || len(httpFile.Requests) > 0
+			httpFiles = append(httpFiles, httpFile)
+		}
+		if requestName != "" && !found {
+			return fmt.Errorf("request with name '%s' not found", requestName)
+		}
+
+		// Collections keep {{variables}} for Postman to resolve, so the
+		// environment is never written into them.
+		if exportFormat == "postman" {
+			name := exportCollection
+			if name == "" {
+				name = strings.TrimSuffix(filepath.Base(args[0]), filepath.Ext(args[0]))
+			}
+			data, err := postman.Export(name, httpFiles).JSON()
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(data))
+			return nil
+		}
+
+		if envFile != "" {
+			if err := godotenv.Load(envFile); err != nil {
+				fmt.Printf("Warning: Could not load env file %s: %v\n", envFile, err)
+			}
+		}
+
+		var commands []string
+		for _, httpFile := range httpFiles {
+			httpFile.ApplyEnv()
+
+			for _, req := range httpFile.Requests {
+				apply := export.ApplyVariables
+				if exportEnvRefs {
+					apply = export.ApplyEnvRefs
+				}
+				label := req.Name
+				if label == "" {
+					label = req.Method + " " + req.URL
+				}
+				if err := apply(&req, httpFile.Variables); err != nil {
+					return fmt.Errorf("%s: %w", label, err)
+				}
+				command, err := export.Command(exportFormat, req)
+				if err != nil {
+					return err
+				}
+				if req.Name != "" {
+					command = "# " + req.Name + "\n" + command
+				}
+				commands = append(commands, command)
+			}
+		}
+
+		fmt.Println(strings.Join(commands, "\n\n"))
+		return nil
 	},
 }
 
+var importPostmanCmd = &cobra.Command{
+	Use:   "postman [collection.json]",
+	Short: "Convert a Postman v2.1 collection",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		collection, err := postman.Load(args[0])
+		if err != nil {
+			return err
+		}
+
+		files := postman.Import(collection, importSplit)
+		if !importSplit {
+			return writeImport(files[0].HTTP)
+		}
+
+		dir := importOutput
+		if dir == "" {
+			dir = "."
+		}
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+		for _, file := range files {
+			path := filepath.Join(dir, fileName(file.Name)+".http")
+			if err := os.WriteFile(path, []byte(parser.Format(file.HTTP)), 0o644); err != nil {
+				return err
+			}
+			fmt.Printf("Wrote %d requests to %s\n", len(file.HTTP.Requests), path)
+		}
+		return nil
+	},
+}
+
+// fileName turns a folder name into a safe file name.
+func fileName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r == '-' || r == '_' || r == '.' || unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return '-'
+	}, strings.TrimSpace(name))
+	if name = strings.Trim(name, "-."); name == "" {
+		return "collection"
+	}
+	return name
+}
+
+// writeImport writes a generated file to --output, or stdout.
+func writeImport(file *parser.HTTPFile) error {
+	content := parser.Format(file)
+	if importOutput == "" {
+		fmt.Print(content)
+		return nil
+	}
+	if err := os.WriteFile(importOutput, []byte(content), 0o644); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %d requests to %s\n", len(file.Requests), importOutput)
+	return nil
+}
+
+// cassetteOptions builds the executor options for --record and --replay,
+// masking secret values in what is recorded. The returned function writes
+// the cassette when recording and is a no-op otherwise.
+func cassetteOptions(masker *secret.Masker) ([]executor.Option, func() error, error) {
+	noop := func() error { return nil }
+	if recordPath != "" && replayPath != "" {
+		return nil, noop, fmt.Errorf("--record and --replay cannot be used together")
+	}
+	if err := cassette.ValidateMatch(cassetteMatch); err != nil {
+		return nil, noop, err
+	}
+	cfg := cassette.Config{Match: cassetteMatch, Redact: cassetteRedact, Mask: masker.Mask}
+
+	if recordPath != "" {
+		recorder := cassette.NewRecorder(cfg)
+		return []executor.Option{executor.WithMiddleware(recorder.Middleware())}, func() error {
+			return recorder.Save(recordPath)
+		}, nil
+	}
+
+	if replayPath != "" {
+		c, err := cassette.Load(replayPath)
+		if err != nil {
+			return nil, noop, fmt.Errorf("failed to load cassette: %w", err)
+		}
+		return []executor.Option{executor.WithTransport(cassette.NewReplayer(c, cfg))}, noop, nil
+	}
+
+	return nil, noop, nil
+}
+
+// harOptions builds the executor options for --har. The returned function
+// writes the archive with secret values masked, and is a no-op when --har
+// is not set.
+func harOptions(masker *secret.Masker) ([]executor.Option, func() error) {
+	if harPath == "" {
+		return nil, func() error { return nil }
+	}
+	recorder := har.NewRecorder()
+	recorder.MaskWith(masker.Mask)
+	return []executor.Option{executor.WithExecuteHook(recorder.Hook())}, func() error {
+		return recorder.Save(harPath)
+	}
+}
+
+// secretMasker builds the masker for --secret-pattern. Every variable in a
+// .private env file is secret; callers add the file's `@!` variables.
+func secretMasker() (*secret.Masker, error) {
+	masker, err := secret.NewMasker(secretPattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --secret-pattern: %w", err)
+	}
+	if secret.IsPrivateEnvFile(envFile) {
+		vars, err := godotenv.Read(envFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read env file %s: %w", envFile, err)
+		}
+		for name := range vars {
+			masker.AddNames(name)
+		}
+	}
+	return masker, nil
+}
+
+// terminalPrompter asks for prompt variables on stderr and reads the answers
+// from stdin. Secret answers are not echoed when stdin is a terminal, and
+// are masked from then on.
+func terminalPrompter(masker *secret.Masker) executor.Prompter {
+	stdin := bufio.NewReader(os.Stdin)
+	return func(prompt parser.Prompt) (string, error) {
+		label := prompt.Description
+		if label == "" {
+			label = prompt.Name
+		}
+		fmt.Fprintf(os.Stderr, "%s: ", label)
+
+		isSecret := prompt.Secret || masker.IsSecret(prompt.Name)
+		var value string
+		if isSecret && term.IsTerminal(os.Stdin.Fd()) {
+			input, err := term.ReadPassword(os.Stdin.Fd())
+			fmt.Fprintln(os.Stderr)
+			if err != nil {
+				return "", err
+			}
+			value = string(input)
+		} else {
+			line, err := stdin.ReadString('\n')
+			if err != nil && line == "" {
+				fmt.Fprintln(os.Stderr)
+				return "", fmt.Errorf("no input: %w", err)
+			}
+			value = strings.TrimRight(line, "\r\n")
+		}
+
+		if isSecret {
+			masker.AddNames(prompt.Name)
+			masker.Track(map[string]string{prompt.Name: value})
+		}
+		return value, nil
+	}
+}
+
+func addSecretFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&secretPattern, "secret-pattern", secret.DefaultPattern, "Regular expression for variable names whose values are masked in output (empty to match none)")
+}
+
+func addRunReferencedFlag(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&runReferenced, "run-referenced", false, "Run a request referenced as {{name.response...}} that has not run yet, instead of failing")
+}
+
+func addPromptFlag(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&promptUndefined, "prompt-undefined", false, "Ask for the value of any undefined variable instead of failing")
+}
+
+func addCassetteFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&recordPath, "record", "", "Record every request/response pair to a cassette file")
+	cmd.Flags().StringVar(&replayPath, "replay", "", "Serve responses from a cassette file without touching the network")
+	cmd.Flags().StringSliceVar(&cassetteMatch, "cassette-match", nil, "Cassette match rules: method, url, path, body, header:<name> (default method,url,body)")
+	cmd.Flags().StringSliceVar(&cassetteRedact, "cassette-redact", nil, "Header, query and JSON field names to redact when recording (default Authorization,Proxy-Authorization,Cookie,Set-Cookie,X-Api-Key)")
+}
+
+func addTagFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&tags, "tag", nil, "Only run requests with one of these @tag values")
+	cmd.Flags().StringSliceVar(&excludeTags, "exclude-tag", nil, "Skip requests with any of these @tag values")
+}
+
 func init() {
 	runCmd.Flags().IntVar(&requestIndex, "request", 0, "Run specific request by index (1-based)")
 	runCmd.Flags().StringVar(&requestName, "name", "", "Run specific request by name")
 	runCmd.Flags().StringVar(&envFile, "env", "", "Environment file to load")
 	runCmd.Flags().DurationVar(&timeout, "timeout", 30*time.Second, "Request timeout")
+	runCmd.Flags().IntVar(&retries, "retries", 0, "Retry count for requests without an @retry directive")
+	runCmd.Flags().IntVar(&parallel, "parallel", 1, "Run up to N independent requests concurrently")
+	addCassetteFlags(runCmd)
+	addTagFlags(runCmd)
+	runCmd.Flags().StringVar(&harPath, "har", "", "Write every request and response, with timings, to a HAR file")
+	addSecretFlags(runCmd)
+	addRunReferencedFlag(runCmd)
+	addPromptFlag(runCmd)
 
 	tuiCmd.Flags().StringVar(&envFile, "env", "", "Environment file to load")
 	tuiCmd.Flags().DurationVar(&timeout, "timeout", 30*time.Second, "Request timeout")
+	addSecretFlags(tuiCmd)
+	addRunReferencedFlag(tuiCmd)
+	addPromptFlag(tuiCmd)
 
 	testCmd.Flags().StringVar(&envFile, "env", "", "Environment file to load")
 	testCmd.Flags().DurationVar(&timeout, "timeout", 30*time.Second, "Request timeout")
+	testCmd.Flags().IntVar(&retries, "retries", 0, "Retry count for requests without an @retry directive")
+	addCassetteFlags(testCmd)
+	addTagFlags(testCmd)
+	testCmd.Flags().StringVar(&harPath, "har", "", "Write every request and response, with timings, to a HAR file")
+	testCmd.Flags().StringVar(&schemaDir, "schema-dir", "", "Directory of <request name>.json schemas for requests without @schema")
+	testCmd.Flags().BoolVar(&updateSnapshots, "update-snapshots", false, "Write response snapshots to __snapshots__ instead of comparing against them")
+	testCmd.Flags().StringVar(&openAPIPath, "openapi", "", "OpenAPI spec to validate requests and responses against, with a coverage report")
+	addSecretFlags(testCmd)
+	addRunReferencedFlag(testCmd)
+	addPromptFlag(testCmd)
+
+	benchCmd.Flags().StringVar(&requestName, "name", "", "Benchmark a single request by name instead of the whole file")
+	benchCmd.Flags().StringVar(&envFile, "env", "", "Environment file to load")
+	benchCmd.Flags().DurationVar(&timeout, "timeout", 30*time.Second, "Request timeout")
+	benchCmd.Flags().IntVarP(&benchConcurrency, "concurrency", "c", 1, "Number of concurrent workers")
+	benchCmd.Flags().Float64Var(&benchRate, "rate", 0, "Maximum requests per second (0 for unlimited)")
+	benchCmd.Flags().DurationVarP(&benchDuration, "duration", "d", 10*time.Second, "How long to run when --iterations is not set")
+	benchCmd.Flags().IntVarP(&benchIterations, "iterations", "n", 0, "Number of times to replay the request sequence")
+	benchCmd.Flags().DurationVar(&benchWarmup, "warmup", 0, "Warm-up period excluded from results")
+	benchCmd.Flags().BoolVar(&benchJSON, "json", false, "Print results as JSON")
+	addTagFlags(benchCmd)
+
+	mockCmd.Flags().StringVar(&envFile, "env", "", "Environment file to load")
+	mockCmd.Flags().StringVar(&mockAddr, "addr", "localhost:8080", "Address to listen on")
+	mockCmd.Flags().DurationVar(&mockLatency, "latency", 0, "Delay added to every response")
+	mockCmd.Flags().DurationVar(&mockJitter, "jitter", 0, "Random extra delay up to this duration")
+	mockCmd.Flags().BoolVar(&mockQuiet, "quiet", false, "Do not log requests")
 
 	rootCmd.AddCommand(runCmd)
 	rootCmd.AddCommand(tuiCmd)
 	rootCmd.AddCommand(testCmd)
+	rootCmd.AddCommand(benchCmd)
+	rootCmd.AddCommand(mockCmd)
+
+	importCmd.PersistentFlags().StringVarP(&importOutput, "output", "o", "", "File to write (default stdout)")
+	importCmd.AddCommand(importOpenAPICmd)
+	importCmd.AddCommand(importCurlCmd)
+	importPostmanCmd.Flags().BoolVar(&importSplit, "split", false, "Write each top-level folder to its own file in the --output directory")
+	importCmd.AddCommand(importPostmanCmd)
+	importHARCmd.Flags().StringSliceVar(&harDomains, "domain", nil, "Only import requests to these domains and their subdomains")
+	importHARCmd.Flags().StringSliceVar(&harContentTypes, "content-type", nil, "Only import requests whose response type contains one of these, e.g. json")
+	importCmd.AddCommand(importHARCmd)
+	rootCmd.AddCommand(importCmd)
+
+	exportCmd.Flags().StringVar(&exportFormat, "format", export.FormatCurl, "Output format: curl, httpie, wget or postman")
+	exportCmd.Flags().StringVar(&requestName, "name", "", "Export a single request by name instead of the whole file")
+	exportCmd.Flags().StringVar(&envFile, "env", "", "Environment file to load")
+	exportCmd.Flags().BoolVar(&exportEnvRefs, "env-refs", false, "Leave {{variables}} as ${name} shell references instead of resolving them")
+	exportCmd.Flags().StringVar(&exportCollection, "collection", "", "Postman collection name (default: the first file's name)")
+	rootCmd.AddCommand(exportCmd)
 }
 
 func main() {
@@ -0,0 +1,170 @@
+package bench
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/cassielabs/hrun/internal/executor"
+	"github.com/cassielabs/hrun/internal/parser"
+)
+
+type Options struct {
+	Concurrency int
+	// Rate caps requests per second across all workers; zero means
+	// unlimited.
+	Rate float64
+	// Duration bounds the measured run. It is ignored when Iterations is
+	// set.
+	Duration time.Duration
+	// Iterations is the number of times the request sequence is replayed.
+	Iterations int
+	// Warmup is run before measuring starts and is excluded from results.
+	Warmup  time.Duration
+	Timeout time.Duration
+}
+
+type sample struct {
+	latency time.Duration
+	errKind string
+	// completed is set when a response arrived; only those samples count
+	// towards latency.
+	completed bool
+}
+
+// Run replays the requests in order, once per iteration, on Concurrency
+// workers. Each worker keeps its own copy of the variables so captures
+// flow from one request to the next within a sequence.
+func Run(ctx context.Context, requests []parser.HTTPRequest, variables map[string]string, opts Options) (*Result, error) {
+	if len(requests) == 0 {
+		return nil, errors.New("no requests to benchmark")
+	}
+	if opts.Concurrency < 1 {
+		opts.Concurrency = 1
+	}
+	if opts.Iterations <= 0 && opts.Duration <= 0 {
+		return nil, errors.New("either iterations or duration must be set")
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = opts.Concurrency
+	exec := executor.New(opts.Timeout, executor.WithTransport(transport))
+
+	if opts.Warmup > 0 {
+		warmupCtx, cancel := context.WithTimeout(ctx, opts.Warmup)
+		run(warmupCtx, exec, requests, variables, opts, 0, nil)
+		cancel()
+	}
+
+	runCtx := ctx
+	if opts.Iterations <= 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, opts.Duration)
+		defer cancel()
+	}
+
+	var (
+		mu      sync.Mutex
+		samples []sample
+	)
+	start := time.Now()
+	run(runCtx, exec, requests, variables, opts, opts.Iterations, func(s sample) {
+		mu.Lock()
+		samples = append(samples, s)
+		mu.Unlock()
+	})
+	elapsed := time.Since(start)
+
+	return newResult(samples, elapsed), nil
+}
+
+func run(ctx context.Context, exec *executor.Executor, requests []parser.HTTPRequest, variables map[string]string, opts Options, iterations int, record func(sample)) {
+	var limiter <-chan time.Time
+	if opts.Rate > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / opts.Rate))
+		defer ticker.Stop()
+		limiter = ticker.C
+	}
+
+	iterationCh := make(chan struct{})
+	go func() {
+		defer close(iterationCh)
+		for i := 0; iterations <= 0 || i < iterations; i++ {
+			select {
+			case iterationCh <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for w := 0; w < opts.Concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			vars := make(map[string]string, len(variables))
+			for k, v := range variables {
+				vars[k] = v
+			}
+			file := &parser.HTTPFile{Requests: requests, Variables: vars}
+
+			for range iterationCh {
+				for _, req := range requests {
+					if limiter != nil {
+						select {
+						case <-limiter:
+						case <-ctx.Done():
+							return
+						}
+					}
+					if ctx.Err() != nil {
+						return
+					}
+
+					req.Headers = req.Headers.Clone()
+					if err := exec.ApplyVariables(file, &req, vars); err != nil {
+						if record != nil {
+							record(sample{errKind: "variables"})
+						}
+						continue
+					}
+					resp, err := exec.ExecuteContext(ctx, req)
+					// Requests cut off when the run ends are not counted.
+					if ctx.Err() != nil {
+						return
+					}
+					if record != nil {
+						record(sample{latency: resp.Duration, errKind: errorKind(resp, err), completed: err == nil})
+					}
+					for k, v := range resp.CapturedVariables {
+						vars[k] = v
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func errorKind(resp *executor.Response, err error) string {
+	if err != nil {
+		var netErr net.Error
+		switch {
+		case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+			return "timeout"
+		case errors.As(err, &netErr):
+			return "network"
+		default:
+			return "error"
+		}
+	}
+	if resp.StatusCode >= 400 {
+		return fmt.Sprintf("status %d", resp.StatusCode)
+	}
+	return ""
+}
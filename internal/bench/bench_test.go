@@ -0,0 +1,202 @@
+package bench
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cassielabs/hrun/internal/parser"
+)
+
+func TestRun_Iterations(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		if r.URL.Path == "/login" {
+			_, _ = w.Write([]byte(`{"token":"t"}`))
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer t" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if n%5 == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	requests := []parser.HTTPRequest{
+		{Method: "POST", URL: "{{baseUrl}}/login", Captures: []parser.CaptureRule{{VariableName: "token", JSONPath: "token"}}},
+		{Method: "GET", URL: "{{baseUrl}}/me", Headers: http.Header{"Authorization": []string{"Bearer {{token}}"}}},
+	}
+
+	result, err := Run(context.Background(), requests, map[string]string{"baseUrl": server.URL}, Options{
+		Concurrency: 3,
+		Iterations:  10,
+		Timeout:     5 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if result.Requests != 20 {
+		t.Errorf("Expected 20 requests, got %d", result.Requests)
+	}
+	if result.Errors["status 401"] != 0 {
+		t.Errorf("Expected captures to flow between requests, got %d 401s", result.Errors["status 401"])
+	}
+	if result.Failures != result.Errors["status 503"] || result.Failures == 0 {
+		t.Errorf("Expected failures to be broken down by status, got %v", result.Errors)
+	}
+	if result.Latency.P50 > result.Latency.P90 || result.Latency.P90 > result.Latency.P99 || result.Latency.P99 > result.Latency.Max {
+		t.Errorf("Expected ordered percentiles, got %+v", result.Latency)
+	}
+
+	total := 0
+	for _, b := range result.Histogram {
+		total += b.Count
+	}
+	if total != result.Requests {
+		t.Errorf("Expected histogram to count all %d requests, got %d", result.Requests, total)
+	}
+}
+
+func TestRun_DurationAndRate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	result, err := Run(context.Background(), []parser.HTTPRequest{{Method: "GET", URL: server.URL}}, nil, Options{
+		Concurrency: 4,
+		Rate:        50,
+		Duration:    300 * time.Millisecond,
+		Timeout:     5 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if result.Requests == 0 || result.Requests > 20 {
+		t.Errorf("Expected rate-limited request count, got %d", result.Requests)
+	}
+}
+
+func TestRun_DurationCancelsInFlight(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(2 * time.Second):
+		}
+	}))
+	defer server.Close()
+
+	start := time.Now()
+	result, err := Run(context.Background(), []parser.HTTPRequest{{Method: "GET", URL: server.URL}}, nil, Options{
+		Concurrency: 2,
+		Duration:    200 * time.Millisecond,
+		Timeout:     5 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected in-flight requests to be cancelled at the deadline, took %v", elapsed)
+	}
+	if result.Requests != 0 || result.Failures != 0 {
+		t.Errorf("Expected requests cut off by the deadline not to be counted, got %d requests and %d failures", result.Requests, result.Failures)
+	}
+}
+
+func TestRun_RequiresBound(t *testing.T) {
+	if _, err := Run(context.Background(), []parser.HTTPRequest{{Method: "GET", URL: "http://localhost"}}, nil, Options{}); err == nil {
+		t.Error("Expected error when neither iterations nor duration is set")
+	}
+	if _, err := Run(context.Background(), nil, nil, Options{Iterations: 1}); err == nil {
+		t.Error("Expected error for empty request list")
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	var latencies []time.Duration
+	for i := 1; i <= 100; i++ {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+
+	tests := []struct {
+		p        float64
+		expected time.Duration
+	}{
+		{50, 50 * time.Millisecond},
+		{90, 90 * time.Millisecond},
+		{99, 99 * time.Millisecond},
+		{100, 100 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		if got := percentile(latencies, tt.p); got != tt.expected {
+			t.Errorf("p%v: expected %v, got %v", tt.p, tt.expected, got)
+		}
+	}
+}
+
+func TestResult_Output(t *testing.T) {
+	samples := []sample{
+		{latency: time.Millisecond, completed: true},
+		{latency: 2 * time.Millisecond, completed: true},
+		{latency: 40 * time.Millisecond, errKind: "timeout"},
+	}
+	result := newResult(samples, time.Second)
+
+	text := result.String()
+	for _, expected := range []string{"Requests:   3 (1 failed)", "p99", "timeout", "Histogram:"} {
+		if !strings.Contains(text, expected) {
+			t.Errorf("Expected %q in output:\n%s", expected, text)
+		}
+	}
+
+	data, err := result.JSON()
+	if err != nil {
+		t.Fatalf("JSON failed: %v", err)
+	}
+	var decoded Result
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if decoded.Requests != 3 || decoded.Errors["timeout"] != 1 {
+		t.Errorf("Unexpected decoded result: %+v", decoded)
+	}
+}
+
+func TestResult_LatencyFromCompletedRequests(t *testing.T) {
+	samples := []sample{
+		{latency: 10 * time.Millisecond, completed: true},
+		{latency: 20 * time.Millisecond, errKind: "status 500", completed: true},
+		{errKind: "variables"},
+		{errKind: "variables"},
+		{latency: 30 * time.Second, errKind: "timeout"},
+	}
+	result := newResult(samples, time.Second)
+
+	if result.Requests != 5 || result.Failures != 4 || result.Errors["variables"] != 2 {
+		t.Errorf("Expected every sample in the counts, got %+v", result)
+	}
+	if result.Latency.Min != 10 || result.Latency.Max != 20 || result.Latency.Mean != 15 {
+		t.Errorf("Expected latency from the two responses only, got %+v", result.Latency)
+	}
+	count := 0
+	for _, bucket := range result.Histogram {
+		count += bucket.Count
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 samples in the histogram, got %d", count)
+	}
+
+	if result := newResult([]sample{{errKind: "variables"}}, time.Second); result.Histogram != nil || result.Latency.Max != 0 {
+		t.Errorf("Expected no latency without responses, got %+v", result)
+	}
+}
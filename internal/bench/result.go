@@ -0,0 +1,184 @@
+package bench
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+type Latency struct {
+	Min  float64 `json:"min_ms"`
+	Mean float64 `json:"mean_ms"`
+	P50  float64 `json:"p50_ms"`
+	P90  float64 `json:"p90_ms"`
+	P99  float64 `json:"p99_ms"`
+	Max  float64 `json:"max_ms"`
+}
+
+// Bucket counts samples with a latency up to UpperBound milliseconds and
+// above the previous bucket's bound.
+type Bucket struct {
+	UpperBound float64 `json:"le_ms"`
+	Count      int     `json:"count"`
+}
+
+type Result struct {
+	Requests   int            `json:"requests"`
+	Failures   int            `json:"failures"`
+	Elapsed    float64        `json:"elapsed_ms"`
+	Throughput float64        `json:"throughput_rps"`
+	Latency    Latency        `json:"latency"`
+	Errors     map[string]int `json:"errors"`
+	Histogram  []Bucket       `json:"histogram"`
+}
+
+func newResult(samples []sample, elapsed time.Duration) *Result {
+	result := &Result{
+		Requests: len(samples),
+		Elapsed:  millis(elapsed),
+		Errors:   make(map[string]int),
+	}
+	if elapsed > 0 {
+		result.Throughput = float64(len(samples)) / elapsed.Seconds()
+	}
+	if len(samples) == 0 {
+		return result
+	}
+
+	// Requests that never got a response, such as timeouts or variables
+	// that did not resolve, are only counted as errors.
+	var latencies []time.Duration
+	var total time.Duration
+	for _, s := range samples {
+		if s.completed {
+			latencies = append(latencies, s.latency)
+			total += s.latency
+		}
+		if s.errKind != "" {
+			result.Failures++
+			result.Errors[s.errKind]++
+		}
+	}
+	if len(latencies) == 0 {
+		return result
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	result.Latency = Latency{
+		Min:  millis(latencies[0]),
+		Mean: millis(total / time.Duration(len(latencies))),
+		P50:  millis(percentile(latencies, 50)),
+		P90:  millis(percentile(latencies, 90)),
+		P99:  millis(percentile(latencies, 99)),
+		Max:  millis(latencies[len(latencies)-1]),
+	}
+	result.Histogram = histogram(latencies)
+	return result
+}
+
+// percentile uses the nearest-rank method on sorted latencies.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// histogram buckets sorted latencies HDR-style: bucket bounds double from
+// 0.1ms and each power of two is split into four linear sub-buckets, so
+// relative precision stays constant across the range. Empty leading and
+// trailing buckets are dropped.
+func histogram(sorted []time.Duration) []Bucket {
+	var bounds []float64
+	max := millis(sorted[len(sorted)-1])
+	for base := 0.1; ; base *= 2 {
+		for sub := 1; sub <= 4; sub++ {
+			bounds = append(bounds, base+base*float64(sub)/4)
+		}
+		if bounds[len(bounds)-1] >= max {
+			break
+		}
+	}
+
+	buckets := make([]Bucket, len(bounds))
+	i := 0
+	for b, bound := range bounds {
+		buckets[b].UpperBound = math.Round(bound*1000) / 1000
+		for i < len(sorted) && millis(sorted[i]) <= bound {
+			buckets[b].Count++
+			i++
+		}
+	}
+	if i < len(sorted) {
+		buckets[len(buckets)-1].Count += len(sorted) - i
+	}
+
+	first, last := 0, len(buckets)-1
+	for first < last && buckets[first].Count == 0 {
+		first++
+	}
+	for last > first && buckets[last].Count == 0 {
+		last--
+	}
+	return buckets[first : last+1]
+}
+
+func millis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func (r *Result) JSON() ([]byte, error) {
+	return json.MarshalIndent(r, "", "  ")
+}
+
+func (r *Result) String() string {
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "Requests:   %d (%d failed)\n", r.Requests, r.Failures)
+	fmt.Fprintf(&buf, "Elapsed:    %.2fs\n", r.Elapsed/1000)
+	fmt.Fprintf(&buf, "Throughput: %.2f req/s\n", r.Throughput)
+
+	fmt.Fprintln(&buf, "\nLatency:")
+	fmt.Fprintf(&buf, "  min  %8.2fms\n", r.Latency.Min)
+	fmt.Fprintf(&buf, "  mean %8.2fms\n", r.Latency.Mean)
+	fmt.Fprintf(&buf, "  p50  %8.2fms\n", r.Latency.P50)
+	fmt.Fprintf(&buf, "  p90  %8.2fms\n", r.Latency.P90)
+	fmt.Fprintf(&buf, "  p99  %8.2fms\n", r.Latency.P99)
+	fmt.Fprintf(&buf, "  max  %8.2fms\n", r.Latency.Max)
+
+	if len(r.Errors) > 0 {
+		fmt.Fprintln(&buf, "\nErrors:")
+		kinds := make([]string, 0, len(r.Errors))
+		for kind := range r.Errors {
+			kinds = append(kinds, kind)
+		}
+		sort.Strings(kinds)
+		for _, kind := range kinds {
+			fmt.Fprintf(&buf, "  %-12s %d\n", kind, r.Errors[kind])
+		}
+	}
+
+	if len(r.Histogram) > 0 {
+		fmt.Fprintln(&buf, "\nHistogram:")
+		maxCount := 0
+		for _, b := range r.Histogram {
+			if b.Count > maxCount {
+				maxCount = b.Count
+			}
+		}
+		for _, b := range r.Histogram {
+			bar := 0
+			if maxCount > 0 {
+				bar = b.Count * 40 / maxCount
+			}
+			fmt.Fprintf(&buf, "  <= %9.3fms %6d %s\n", b.UpperBound, b.Count, strings.Repeat("█", bar))
+		}
+	}
+
+	return buf.String()
+}
@@ -0,0 +1,302 @@
+package cassette
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+const (
+	MatchMethod = "method"
+	MatchURL    = "url"
+	MatchPath   = "path"
+	MatchBody   = "body"
+	// MatchHeaderPrefix selects a header, as in "header:X-Tenant".
+	MatchHeaderPrefix = "header:"
+
+	redactedValue = "REDACTED"
+)
+
+var (
+	DefaultMatch  = []string{MatchMethod, MatchURL, MatchBody}
+	DefaultRedact = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key"}
+)
+
+// Config controls how requests are matched against recorded interactions
+// and which names are redacted when recording. Redact names are compared
+// case-insensitively with header names, query parameters and JSON object
+// keys in request and response bodies. Mask, when set, also replaces secret
+// values wherever they appear, and is applied to live requests before they
+// are matched so both sides agree.
+type Config struct {
+	Match  []string
+	Redact []string
+	Mask   func(string) string
+}
+
+type Cassette struct {
+	Version      int           `json:"version"`
+	Interactions []Interaction `json:"interactions"`
+}
+
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+type Request struct {
+	Method     string      `json:"method"`
+	URL        string      `json:"url"`
+	Headers    http.Header `json:"headers"`
+	Body       string      `json:"body,omitempty"`
+	BodySHA256 string      `json:"body_sha256"`
+}
+
+type Response struct {
+	StatusCode int         `json:"status_code"`
+	Status     string      `json:"status"`
+	Headers    http.Header `json:"headers"`
+	Body       string      `json:"body"`
+}
+
+func Load(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c Cassette
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("invalid cassette %s: %w", path, err)
+	}
+	return &c, nil
+}
+
+func (c *Cassette) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+func (cfg Config) match() []string {
+	if len(cfg.Match) == 0 {
+		return DefaultMatch
+	}
+	return cfg.Match
+}
+
+func (cfg Config) redact() []string {
+	if cfg.Redact == nil {
+		return DefaultRedact
+	}
+	return cfg.Redact
+}
+
+func (cfg Config) redacts(name string) bool {
+	for _, r := range cfg.redact() {
+		if strings.EqualFold(r, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (cfg Config) mask(text string) string {
+	if cfg.Mask == nil {
+		return text
+	}
+	return cfg.Mask(text)
+}
+
+func (cfg Config) redactHeaders(headers http.Header) http.Header {
+	redacted := headers.Clone()
+	for name, values := range redacted {
+		for i := range values {
+			if cfg.redacts(name) {
+				values[i] = redactedValue
+			} else {
+				values[i] = cfg.mask(values[i])
+			}
+		}
+	}
+	return redacted
+}
+
+func (cfg Config) redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.RawQuery == "" {
+		return cfg.mask(raw)
+	}
+	query := u.Query()
+	changed := false
+	for name, values := range query {
+		if cfg.redacts(name) {
+			for i := range values {
+				values[i] = redactedValue
+			}
+			changed = true
+		}
+	}
+	if changed {
+		u.RawQuery = query.Encode()
+	}
+	return cfg.mask(u.String())
+}
+
+func (cfg Config) redactBody(body string) string {
+	var data interface{}
+	if err := json.Unmarshal([]byte(body), &data); err != nil {
+		return cfg.mask(body)
+	}
+	if !cfg.redactJSON(data) {
+		return cfg.mask(body)
+	}
+	redacted, err := json.Marshal(data)
+	if err != nil {
+		return cfg.mask(body)
+	}
+	return cfg.mask(string(redacted))
+}
+
+// redactInteraction redacts an interaction again, so values that only
+// became secret after it was recorded, such as a token captured from its
+// own response, are masked too.
+func (cfg Config) redactInteraction(interaction Interaction) Interaction {
+	req := interaction.Request
+	req.URL = cfg.redactURL(req.URL)
+	req.Headers = cfg.redactHeaders(req.Headers)
+	req.Body = cfg.redactBody(req.Body)
+	req.BodySHA256 = bodySHA256(req.Body)
+
+	resp := interaction.Response
+	resp.Headers = cfg.redactHeaders(resp.Headers)
+	resp.Body = cfg.redactBody(resp.Body)
+	return Interaction{Request: req, Response: resp}
+}
+
+func (cfg Config) redactJSON(value interface{}) bool {
+	changed := false
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if cfg.redacts(key) {
+				v[key] = redactedValue
+				changed = true
+			} else if cfg.redactJSON(child) {
+				changed = true
+			}
+		}
+	case []interface{}:
+		for _, child := range v {
+			if cfg.redactJSON(child) {
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+// matches reports whether a live request, already redacted the same way
+// recordings are, satisfies every match rule against a recorded one.
+func (cfg Config) matches(recorded, live Request) bool {
+	for _, rule := range cfg.match() {
+		switch {
+		case rule == MatchMethod:
+			if recorded.Method != live.Method {
+				return false
+			}
+		case rule == MatchURL:
+			if recorded.URL != live.URL {
+				return false
+			}
+		case rule == MatchPath:
+			if urlPath(recorded.URL) != urlPath(live.URL) {
+				return false
+			}
+		case rule == MatchBody:
+			if recorded.BodySHA256 != live.BodySHA256 {
+				return false
+			}
+		case strings.HasPrefix(rule, MatchHeaderPrefix):
+			name := strings.TrimPrefix(rule, MatchHeaderPrefix)
+			if recorded.Headers.Get(name) != live.Headers.Get(name) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// ValidateMatch checks match rule names given on the command line.
+func ValidateMatch(rules []string) error {
+	for _, rule := range rules {
+		switch {
+		case rule == MatchMethod, rule == MatchURL, rule == MatchPath, rule == MatchBody:
+		case strings.HasPrefix(rule, MatchHeaderPrefix) && len(rule) > len(MatchHeaderPrefix):
+		default:
+			return fmt.Errorf("unknown cassette match rule %q", rule)
+		}
+	}
+	return nil
+}
+
+func (cfg Config) newRequest(req *http.Request) (Request, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return Request{}, err
+	}
+	// The hash covers the redacted body, so it gives nothing away and
+	// matches however the secrets in it were set when replaying.
+	body = cfg.redactBody(body)
+	return Request{
+		Method:     req.Method,
+		URL:        cfg.redactURL(req.URL.String()),
+		Headers:    cfg.redactHeaders(req.Header),
+		Body:       body,
+		BodySHA256: bodySHA256(body),
+	}, nil
+}
+
+func bodySHA256(body string) string {
+	sum := sha256.Sum256([]byte(body))
+	return hex.EncodeToString(sum[:])
+}
+
+func urlPath(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	return u.Scheme + "://" + u.Host + u.Path
+}
+
+func readRequestBody(req *http.Request) (string, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return "", nil
+	}
+	if req.GetBody == nil {
+		data, err := io.ReadAll(req.Body)
+		if err != nil {
+			return "", err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(data))
+		return string(data), nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = body.Close()
+	}()
+	data, err := io.ReadAll(body)
+	return string(data), err
+}
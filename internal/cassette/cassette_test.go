@@ -0,0 +1,239 @@
+package cassette
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cassielabs/hrun/internal/executor"
+	"github.com/cassielabs/hrun/internal/parser"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "session=abc")
+		_, _ = fmt.Fprintf(w, `{"call":%d,"path":%q,"token":"server-secret"}`, n, r.URL.Path)
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	cfg := Config{Redact: append([]string{"token", "api_key"}, DefaultRedact...)}
+
+	recorder := NewRecorder(cfg)
+	exec := executor.New(5*time.Second, executor.WithMiddleware(recorder.Middleware()))
+	requests := []parser.HTTPRequest{
+		{Method: "GET", URL: server.URL + "/users?api_key=k1", Headers: http.Header{"Authorization": []string{"Bearer secret"}}},
+		{Method: "POST", URL: server.URL + "/users", Body: `{"name":"a"}`},
+		{Method: "POST", URL: server.URL + "/users", Body: `{"name":"b"}`},
+	}
+	for _, req := range requests {
+		if _, err := exec.Execute(req); err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+	}
+	if err := recorder.Save(path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	c, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(c.Interactions) != 3 {
+		t.Fatalf("Expected 3 interactions, got %d", len(c.Interactions))
+	}
+	first := c.Interactions[0]
+	if first.Request.Headers.Get("Authorization") != redactedValue {
+		t.Errorf("Expected Authorization to be redacted, got %q", first.Request.Headers.Get("Authorization"))
+	}
+	if strings.Contains(first.Request.URL, "k1") {
+		t.Errorf("Expected api_key query parameter to be redacted, got %q", first.Request.URL)
+	}
+	if first.Response.Headers.Get("Set-Cookie") != redactedValue {
+		t.Errorf("Expected Set-Cookie to be redacted, got %q", first.Response.Headers.Get("Set-Cookie"))
+	}
+	if strings.Contains(first.Response.Body, "server-secret") {
+		t.Errorf("Expected token field to be redacted, got %q", first.Response.Body)
+	}
+
+	server.Close()
+	replay := executor.New(5*time.Second, executor.WithTransport(NewReplayer(c, cfg)))
+
+	resp, err := replay.Execute(requests[2])
+	if err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+	if !strings.Contains(resp.Body, `"call":3`) {
+		t.Errorf("Expected body hash to select the third recording, got %q", resp.Body)
+	}
+
+	resp, err = replay.Execute(requests[0])
+	if err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || !strings.Contains(resp.Body, `"call":1`) {
+		t.Errorf("Unexpected replayed response: %d %q", resp.StatusCode, resp.Body)
+	}
+
+	if _, err := replay.Execute(parser.HTTPRequest{Method: "DELETE", URL: server.URL + "/users"}); err == nil {
+		t.Error("Expected error for unrecorded request")
+	}
+}
+
+func TestRecordAndReplay_Mask(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"session":"sess-123"}`))
+	}))
+	defer server.Close()
+
+	// sess-123 only becomes secret once it has been captured from the
+	// response, after the interaction was recorded.
+	var mu sync.Mutex
+	secrets := []string{"hunter22"}
+	mask := func(text string) string {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, value := range secrets {
+			text = strings.ReplaceAll(text, value, "••••")
+		}
+		return text
+	}
+	cfg := Config{Mask: mask}
+
+	recorder := NewRecorder(cfg)
+	exec := executor.New(5*time.Second, executor.WithMiddleware(recorder.Middleware()))
+	req := parser.HTTPRequest{
+		Method:  "POST",
+		URL:     server.URL + "/login?user=hunter22",
+		Headers: http.Header{"X-Password": []string{"hunter22"}},
+		Body:    `{"password":"hunter22"}`,
+	}
+	if _, err := exec.Execute(req); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	mu.Lock()
+	secrets = append(secrets, "sess-123")
+	mu.Unlock()
+
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	if err := recorder.Save(path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	for _, value := range []string{"hunter22", "sess-123"} {
+		if strings.Contains(string(data), value) {
+			t.Errorf("Expected %q to be masked, got %s", value, data)
+		}
+	}
+
+	c, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	recorded := c.Interactions[0].Request
+	if recorded.BodySHA256 == bodySHA256(req.Body) || recorded.BodySHA256 != bodySHA256(recorded.Body) {
+		t.Errorf("Expected the hash of the masked body, got %s for %q", recorded.BodySHA256, recorded.Body)
+	}
+
+	server.Close()
+	replay := executor.New(5*time.Second, executor.WithTransport(NewReplayer(c, cfg)))
+	if _, err := replay.Execute(req); err != nil {
+		t.Errorf("Expected the masked recording to match on replay, got %v", err)
+	}
+}
+
+func TestReplay_RepeatedRequestsInOrder(t *testing.T) {
+	c := &Cassette{Version: 1}
+	for i := 1; i <= 2; i++ {
+		c.Interactions = append(c.Interactions, Interaction{
+			Request:  Request{Method: "GET", URL: "http://example.com/poll", BodySHA256: emptySHA256},
+			Response: Response{StatusCode: 200, Status: "200 OK", Body: fmt.Sprintf("poll %d", i)},
+		})
+	}
+
+	exec := executor.New(5*time.Second, executor.WithTransport(NewReplayer(c, Config{})))
+	for _, expected := range []string{"poll 1", "poll 2", "poll 2"} {
+		resp, err := exec.Execute(parser.HTTPRequest{Method: "GET", URL: "http://example.com/poll"})
+		if err != nil {
+			t.Fatalf("Replay failed: %v", err)
+		}
+		if resp.Body != expected {
+			t.Errorf("Expected %q, got %q", expected, resp.Body)
+		}
+	}
+}
+
+func TestConfig_Matches(t *testing.T) {
+	recorded := Request{
+		Method:     "GET",
+		URL:        "http://example.com/users?page=1",
+		Headers:    http.Header{"X-Tenant": []string{"a"}},
+		BodySHA256: emptySHA256,
+	}
+
+	tests := []struct {
+		name     string
+		match    []string
+		live     Request
+		expected bool
+	}{
+		{
+			name:     "Default rules",
+			live:     Request{Method: "GET", URL: "http://example.com/users?page=1", BodySHA256: emptySHA256},
+			expected: true,
+		},
+		{
+			name:     "Different query with url rule",
+			live:     Request{Method: "GET", URL: "http://example.com/users?page=2", BodySHA256: emptySHA256},
+			expected: false,
+		},
+		{
+			name:     "Different query with path rule",
+			match:    []string{MatchMethod, MatchPath},
+			live:     Request{Method: "GET", URL: "http://example.com/users?page=2"},
+			expected: true,
+		},
+		{
+			name:     "Header rule",
+			match:    []string{MatchMethod, "header:X-Tenant"},
+			live:     Request{Method: "GET", URL: "http://other", Headers: http.Header{"X-Tenant": []string{"b"}}},
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{Match: tt.match}
+			if got := cfg.matches(recorded, tt.live); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestValidateMatch(t *testing.T) {
+	if err := ValidateMatch([]string{"method", "path", "header:X-Tenant"}); err != nil {
+		t.Errorf("Expected valid rules, got %v", err)
+	}
+	if err := ValidateMatch([]string{"header:"}); err == nil {
+		t.Error("Expected error for header rule without a name")
+	}
+	if err := ValidateMatch([]string{"query"}); err == nil {
+		t.Error("Expected error for unknown rule")
+	}
+}
+
+const emptySHA256 = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
@@ -0,0 +1,69 @@
+package cassette
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/cassielabs/hrun/internal/executor"
+)
+
+// Recorder saves every request/response pair that passes through its
+// middleware.
+type Recorder struct {
+	cfg      Config
+	mu       sync.Mutex
+	cassette Cassette
+}
+
+func NewRecorder(cfg Config) *Recorder {
+	return &Recorder{cfg: cfg, cassette: Cassette{Version: 1}}
+}
+
+func (r *Recorder) Middleware() executor.Middleware {
+	return func(next executor.RoundTripper) executor.RoundTripper {
+		return executor.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			recorded, err := r.cfg.newRequest(req)
+			if err != nil {
+				return nil, err
+			}
+
+			resp, err := next.RoundTrip(req)
+			if err != nil {
+				return resp, err
+			}
+
+			body, err := io.ReadAll(resp.Body)
+			_ = resp.Body.Close()
+			if err != nil {
+				return nil, err
+			}
+			resp.Body = io.NopCloser(bytes.NewReader(body))
+
+			r.mu.Lock()
+			r.cassette.Interactions = append(r.cassette.Interactions, Interaction{
+				Request: recorded,
+				Response: Response{
+					StatusCode: resp.StatusCode,
+					Status:     resp.Status,
+					Headers:    r.cfg.redactHeaders(resp.Header),
+					Body:       r.cfg.redactBody(string(body)),
+				},
+			})
+			r.mu.Unlock()
+
+			return resp, nil
+		})
+	}
+}
+
+func (r *Recorder) Save(path string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c := Cassette{Version: r.cassette.Version}
+	for _, interaction := range r.cassette.Interactions {
+		c.Interactions = append(c.Interactions, r.cfg.redactInteraction(interaction))
+	}
+	return c.Save(path)
+}
@@ -0,0 +1,61 @@
+package cassette
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Replayer is a transport that answers from a cassette and never touches
+// the network. Identical requests are answered with their recordings in
+// order; once those are used up the last one is repeated.
+type Replayer struct {
+	cfg      Config
+	cassette *Cassette
+	mu       sync.Mutex
+	used     []bool
+}
+
+func NewReplayer(c *Cassette, cfg Config) *Replayer {
+	return &Replayer{cfg: cfg, cassette: c, used: make([]bool, len(c.Interactions))}
+}
+
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	live, err := r.cfg.newRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	found := -1
+	for i, interaction := range r.cassette.Interactions {
+		if !r.cfg.matches(interaction.Request, live) {
+			continue
+		}
+		found = i
+		if !r.used[i] {
+			break
+		}
+	}
+	if found == -1 {
+		return nil, fmt.Errorf("no recorded response for %s %s", req.Method, live.URL)
+	}
+	r.used[found] = true
+
+	recorded := r.cassette.Interactions[found].Response
+	return &http.Response{
+		StatusCode:    recorded.StatusCode,
+		Status:        recorded.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        recorded.Headers.Clone(),
+		Body:          io.NopCloser(strings.NewReader(recorded.Body)),
+		ContentLength: int64(len(recorded.Body)),
+		Request:       req,
+	}, nil
+}
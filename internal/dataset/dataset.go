@@ -0,0 +1,145 @@
+// Package dataset loads the rows of `# @data` files, for requests that run
+// once per row.
+package dataset
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cassielabs/hrun/internal/parser"
+)
+
+// Load reads a CSV file whose first line names the columns, or a JSON
+// array of objects. JSON strings are used as they are; other values are
+// kept as JSON text.
+func Load(path string) ([]map[string]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []map[string]string
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		rows, err = parseJSON(content)
+	} else {
+		rows, err = parseCSV(content)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("%s has no rows", path)
+	}
+	return rows, nil
+}
+
+func parseCSV(content []byte) ([]map[string]string, error) {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(content, []byte("\ufeff"))))
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	header := records[0]
+	rows := make([]map[string]string, 0, len(records)-1)
+	for _, record := range records[1:] {
+		row := make(map[string]string, len(header))
+		for i, column := range header {
+			row[strings.TrimSpace(column)] = record[i]
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func parseJSON(content []byte) ([]map[string]string, error) {
+	var objects []map[string]json.RawMessage
+	if err := json.Unmarshal(content, &objects); err != nil {
+		return nil, fmt.Errorf("expected a JSON array of objects: %w", err)
+	}
+
+	rows := make([]map[string]string, 0, len(objects))
+	for _, object := range objects {
+		row := make(map[string]string, len(object))
+		for key, raw := range object {
+			var text string
+			if err := json.Unmarshal(raw, &text); err == nil {
+				row[key] = text
+			} else if string(raw) != "null" {
+				row[key] = string(raw)
+			} else {
+				row[key] = ""
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// Iterations returns a copy of req for each row of its @data file, which is
+// relative to the .http file at httpFilePath. A request without @data, or
+// one that is skipped, is returned as it is.
+func Iterations(httpFilePath string, req parser.HTTPRequest) ([]parser.HTTPRequest, error) {
+	if req.Data == "" || req.Skip {
+		return []parser.HTTPRequest{req}, nil
+	}
+
+	path := req.Data
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(httpFilePath), path)
+	}
+	rows, err := Load(path)
+	if err != nil {
+		return nil, fmt.Errorf("@data: %w", err)
+	}
+
+	iterations := make([]parser.HTTPRequest, len(rows))
+	for i, row := range rows {
+		iteration := req
+		iteration.Iteration = i + 1
+		iteration.Variables = make(map[string]string, len(req.Variables)+len(row))
+		for k, v := range req.Variables {
+			iteration.Variables[k] = v
+		}
+		for k, v := range row {
+			iteration.Variables[k] = v
+		}
+		iterations[i] = iteration
+	}
+	return iterations, nil
+}
+
+// Expand replaces each request with its iterations.
+func Expand(httpFilePath string, requests []parser.HTTPRequest) ([]parser.HTTPRequest, error) {
+	var expanded []parser.HTTPRequest
+	for _, req := range requests {
+		iterations, err := Iterations(httpFilePath, req)
+		if err != nil {
+			label := req.Name
+			if label == "" {
+				label = req.Method + " " + req.URL
+			}
+			return nil, fmt.Errorf("%s: %w", label, err)
+		}
+		expanded = append(expanded, iterations...)
+	}
+	return expanded, nil
+}
+
+// Label names a request in output, with its iteration as in createUser#2.
+// It is empty for an unnamed request without @data.
+func Label(req parser.HTTPRequest) string {
+	if req.Iteration == 0 {
+		return req.Name
+	}
+	return fmt.Sprintf("%s#%d", req.Name, req.Iteration)
+}
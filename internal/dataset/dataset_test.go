@@ -0,0 +1,97 @@
+package dataset
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cassielabs/hrun/internal/parser"
+)
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	csvPath := filepath.Join(dir, "users.csv")
+	if err := os.WriteFile(csvPath, []byte("\ufeffname, email,age\nAda,ada@example.com,36\n\"Smith, Jo\",jo@example.com,\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	jsonPath := filepath.Join(dir, "users.json")
+	if err := os.WriteFile(jsonPath, []byte(`[{"name": "Ada", "age": 36, "admin": true, "tags": ["a"], "manager": null}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := Load(csvPath)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	expected := []map[string]string{
+		{"name": "Ada", "email": "ada@example.com", "age": "36"},
+		{"name": "Smith, Jo", "email": "jo@example.com", "age": ""},
+	}
+	if !reflect.DeepEqual(rows, expected) {
+		t.Errorf("Expected CSV rows %v, got %v", expected, rows)
+	}
+
+	rows, err = Load(jsonPath)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	expected = []map[string]string{{"name": "Ada", "age": "36", "admin": "true", "tags": `["a"]`, "manager": ""}}
+	if !reflect.DeepEqual(rows, expected) {
+		t.Errorf("Expected JSON rows %v, got %v", expected, rows)
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"header.csv":  "name,email\n",
+		"ragged.csv":  "name,email\nAda\n",
+		"object.json": `{"name": "Ada"}`,
+		"empty.json":  `[]`,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Load(path); err == nil || !strings.Contains(err.Error(), name) {
+			t.Errorf("Expected an error naming %s, got %v", name, err)
+		}
+	}
+}
+
+func TestIterations(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "users.csv"), []byte("name,role\nAda,admin\nLin,viewer\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	httpFilePath := filepath.Join(dir, "api.http")
+
+	req := parser.HTTPRequest{Name: "createUser", Data: "./users.csv", Variables: map[string]string{"role": "guest", "team": "core"}}
+	iterations, err := Iterations(httpFilePath, req)
+	if err != nil {
+		t.Fatalf("Iterations failed: %v", err)
+	}
+	if len(iterations) != 2 {
+		t.Fatalf("Expected 2 iterations, got %d", len(iterations))
+	}
+	second := iterations[1]
+	if Label(second) != "createUser#2" || second.Variables["name"] != "Lin" || second.Variables["role"] != "viewer" || second.Variables["team"] != "core" {
+		t.Errorf("Unexpected second iteration %s with %v", Label(second), second.Variables)
+	}
+	if req.Variables["role"] != "guest" {
+		t.Errorf("Expected the request's variables to be left alone, got %v", req.Variables)
+	}
+
+	req.Skip = true
+	if iterations, err := Iterations(httpFilePath, req); err != nil || len(iterations) != 1 || iterations[0].Iteration != 0 {
+		t.Errorf("Expected a skipped request to run once, got %d (%v)", len(iterations), err)
+	}
+
+	_, err = Expand(httpFilePath, []parser.HTTPRequest{{Method: "GET", URL: "/users", Data: "missing.csv"}})
+	if err == nil || !strings.Contains(err.Error(), "GET /users: @data:") {
+		t.Errorf("Expected a missing data file to be reported, got %v", err)
+	}
+}
@@ -0,0 +1,129 @@
+package executor
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/antchfx/htmlquery"
+	"github.com/antchfx/xmlquery"
+	"github.com/antchfx/xpath"
+	"github.com/cassielabs/hrun/internal/parser"
+	"github.com/tidwall/gjson"
+)
+
+// applyCaptureRules evaluates each capture against the response. Captures
+// that find nothing are left unset and described in the returned warnings.
+func applyCaptureRules(resp *Response, captures []parser.CaptureRule) (map[string]string, []string) {
+	capturedVars := make(map[string]string)
+	var warnings []string
+
+	for _, capture := range captures {
+		value, err := captureValue(resp, capture)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("%s: %v", capture.VariableName, err))
+			continue
+		}
+		capturedVars[capture.VariableName] = value
+	}
+
+	return capturedVars, warnings
+}
+
+func captureValue(resp *Response, capture parser.CaptureRule) (string, error) {
+	source, arg := capture.Source()
+	switch source {
+	case parser.CaptureStatus:
+		return strconv.Itoa(resp.StatusCode), nil
+
+	case parser.CaptureHeader:
+		values := resp.Headers.Values(arg)
+		if len(values) == 0 {
+			return "", fmt.Errorf("no %s header in the response", arg)
+		}
+		return strings.Join(values, ", "), nil
+
+	case parser.CaptureCookie:
+		for _, cookie := range (&http.Response{Header: resp.Headers}).Cookies() {
+			if cookie.Name == arg {
+				return cookie.Value, nil
+			}
+		}
+		return "", fmt.Errorf("no %s cookie set by the response", arg)
+
+	case parser.CaptureRegex:
+		re, err := regexp.Compile(arg)
+		if err != nil {
+			return "", fmt.Errorf("invalid regex: %v", err)
+		}
+		match := re.FindStringSubmatch(resp.Body)
+		if match == nil {
+			return "", fmt.Errorf("regex %s does not match the body", arg)
+		}
+		if len(match) > 1 {
+			return match[1], nil
+		}
+		return match[0], nil
+
+	case parser.CaptureXPath:
+		return xpathValue(resp, arg)
+
+	default:
+		if !gjson.Valid(resp.Body) {
+			return "", fmt.Errorf("body is not JSON, so %s cannot be read", arg)
+		}
+		result := gjson.Get(resp.Body, arg)
+		if !result.Exists() {
+			return "", fmt.Errorf("%s not found in the body", arg)
+		}
+		return result.String(), nil
+	}
+}
+
+// xpathValue evaluates an XPath expression against an XML or HTML body.
+// Node results give the text of the first node; expressions such as
+// count() or string() give their value.
+func xpathValue(resp *Response, expression string) (string, error) {
+	expr, err := xpath.Compile(expression)
+	if err != nil {
+		return "", fmt.Errorf("invalid xpath: %v", err)
+	}
+
+	var nav xpath.NodeNavigator
+	body := strings.NewReader(resp.Body)
+	if isHTML(resp) {
+		doc, err := htmlquery.Parse(body)
+		if err != nil {
+			return "", fmt.Errorf("body is not HTML: %v", err)
+		}
+		nav = htmlquery.CreateXPathNavigator(doc)
+	} else {
+		doc, err := xmlquery.Parse(body)
+		if err != nil {
+			return "", fmt.Errorf("body is not XML: %v", err)
+		}
+		nav = xmlquery.CreateXPathNavigator(doc)
+	}
+
+	switch result := expr.Evaluate(nav).(type) {
+	case *xpath.NodeIterator:
+		if !result.MoveNext() {
+			return "", fmt.Errorf("xpath %s matches nothing", expression)
+		}
+		return strings.TrimSpace(result.Current().Value()), nil
+	case float64:
+		return strconv.FormatFloat(result, 'f', -1, 64), nil
+	default:
+		return fmt.Sprint(result), nil
+	}
+}
+
+func isHTML(resp *Response) bool {
+	if strings.Contains(resp.Headers.Get("Content-Type"), "html") {
+		return true
+	}
+	start := strings.ToLower(strings.TrimSpace(resp.Body))
+	return strings.HasPrefix(start, "<!doctype html") || strings.HasPrefix(start, "<html")
+}
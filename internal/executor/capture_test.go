@@ -1,6 +1,8 @@
 package executor
 
 import (
+	"net/http"
+	"strings"
 	"testing"
 
 	"github.com/cassielabs/hrun/internal/parser"
@@ -80,7 +82,7 @@ func TestApplyCaptureRules(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := applyCaptureRules(tt.body, tt.captures)
+			result, _ := applyCaptureRules(&Response{Body: tt.body}, tt.captures)
 
 			if len(result) != len(tt.expected) {
 				t.Errorf("Expected %d captured variables, got %d", len(tt.expected), len(result))
@@ -102,3 +104,84 @@ func TestApplyCaptureRules(t *testing.T) {
 		})
 	}
 }
+
+func TestApplyCaptureRules_Sources(t *testing.T) {
+	headers := http.Header{}
+	headers.Set("Location", "/users/42")
+	headers.Set("Content-Type", "text/html; charset=utf-8")
+	headers.Add("Set-Cookie", "session_id=s3cr3t; Path=/; HttpOnly")
+	headers.Add("Set-Cookie", "theme=dark")
+	html := `<!DOCTYPE html><html><body><form><input name="csrf" value="tok-1"><p class="user">Ada</p></form></body></html>`
+	resp := &Response{StatusCode: 201, Headers: headers, Body: html}
+
+	xmlResp := &Response{
+		StatusCode: 200,
+		Headers:    http.Header{"Content-Type": []string{"application/xml"}},
+		Body:       `<users><user id="7"><name>Grace</name></user><user id="8"/></users>`,
+	}
+	jsonResp := &Response{StatusCode: 200, Body: `{"status": "active", "header": {"x": 1}}`}
+
+	tests := []struct {
+		name     string
+		resp     *Response
+		path     string
+		expected string
+	}{
+		{name: "Status", resp: resp, path: "status", expected: "201"},
+		{name: "Header", resp: resp, path: "header.location", expected: "/users/42"},
+		{name: "Cookie", resp: resp, path: "cookie.session_id", expected: "s3cr3t"},
+		{name: "Regex group", resp: resp, path: `regex:name="csrf" value="([^"]+)"`, expected: "tok-1"},
+		{name: "Regex without group", resp: resp, path: `regex:tok-\d`, expected: "tok-1"},
+		{name: "XPath HTML", resp: resp, path: `xpath://p[@class="user"]`, expected: "Ada"},
+		{name: "XPath XML element", resp: xmlResp, path: "xpath://user/name", expected: "Grace"},
+		{name: "XPath XML attribute", resp: xmlResp, path: "xpath://user[2]/@id", expected: "8"},
+		{name: "XPath function", resp: xmlResp, path: "xpath:count(//user)", expected: "2"},
+		{name: "Body prefix", resp: jsonResp, path: "body.status", expected: "active"},
+		{name: "Body prefix for a header field", resp: jsonResp, path: "body.header.x", expected: "1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, warnings := applyCaptureRules(tt.resp, []parser.CaptureRule{{VariableName: "v", JSONPath: tt.path}})
+			if len(warnings) > 0 {
+				t.Fatalf("Unexpected warnings: %v", warnings)
+			}
+			if result["v"] != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result["v"])
+			}
+		})
+	}
+}
+
+func TestApplyCaptureRules_Warnings(t *testing.T) {
+	resp := &Response{StatusCode: 200, Headers: http.Header{}, Body: "plain text"}
+	captures := []parser.CaptureRule{
+		{VariableName: "token", JSONPath: "token"},
+		{VariableName: "loc", JSONPath: "header.Location"},
+		{VariableName: "sid", JSONPath: "cookie.sid"},
+		{VariableName: "csrf", JSONPath: "regex:csrf=(\\w+)"},
+		{VariableName: "bad", JSONPath: "regex:("},
+		{VariableName: "code", JSONPath: "status"},
+	}
+
+	result, warnings := applyCaptureRules(resp, captures)
+	if len(result) != 1 || result["code"] != "200" {
+		t.Errorf("Expected only the status to be captured, got %v", result)
+	}
+
+	expected := []string{
+		"token: body is not JSON",
+		"loc: no Location header",
+		"sid: no sid cookie",
+		"csrf: regex csrf=(\\w+) does not match",
+		"bad: invalid regex",
+	}
+	if len(warnings) != len(expected) {
+		t.Fatalf("Expected %d warnings, got %v", len(expected), warnings)
+	}
+	for i, prefix := range expected {
+		if !strings.HasPrefix(warnings[i], prefix) {
+			t.Errorf("Expected warning starting with %q, got %q", prefix, warnings[i])
+		}
+	}
+}
@@ -2,6 +2,7 @@ package executor
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -10,7 +11,6 @@ import (
 	"time"
 
 	"github.com/cassielabs/hrun/internal/parser"
-	"github.com/tidwall/gjson"
 )
 
 type Response struct {
@@ -21,30 +21,122 @@ type Response struct {
 	Duration         time.Duration
 	Error            error
 	CapturedVariables map[string]string
+	Attempts         []Attempt
+	// CaptureWarnings describes each @capture that found no value.
+	CaptureWarnings []string
+	// StartedAt is when Execute was called, and Proto the protocol of the
+	// response, such as "HTTP/1.1".
+	StartedAt time.Time
+	Proto     string
+	Timings   Timings
+	// RequestHeaders are the headers the last attempt was sent with, after
+	// middlewares and plugins changed them. They are nil when nothing was
+	// sent.
+	RequestHeaders http.Header
 }
 
+// ExecuteHook is called with the resolved request and its response each
+// time Execute finishes, including when the request failed. Hooks may be
+// called concurrently.
+type ExecuteHook func(req parser.HTTPRequest, resp *Response)
+
 type Executor struct {
-	client  *http.Client
-	timeout time.Duration
+	transport   RoundTripper
+	middlewares []Middleware
+	timeout     time.Duration
+	retries     int
+	parallel    int
+	hooks       []ExecuteHook
+
+	responses     *responseStore
+	runReferenced bool
+	prompts       *prompts
+}
+
+type Option func(*Executor)
+
+// WithTransport replaces the transport at the bottom of the middleware chain.
+func WithTransport(transport RoundTripper) Option {
+	return func(e *Executor) {
+		e.transport = transport
+	}
+}
+
+// WithMiddleware appends middlewares to the chain.
+func WithMiddleware(middlewares ...Middleware) Option {
+	return func(e *Executor) {
+		e.Use(middlewares...)
+	}
+}
+
+// WithExecuteHook registers a hook that sees every executed request.
+func WithExecuteHook(hook ExecuteHook) Option {
+	return func(e *Executor) {
+		e.hooks = append(e.hooks, hook)
+	}
 }
 
-func New(timeout time.Duration) *Executor {
-	return &Executor{
-		client: &http.Client{
-			Timeout: timeout,
-		},
-		timeout: timeout,
+func New(timeout time.Duration, opts ...Option) *Executor {
+	e := &Executor{
+		transport: http.DefaultTransport,
+		timeout:   timeout,
+		responses: &responseStore{byName: make(map[string]*Response)},
+	}
+	e.middlewares = []Middleware{e.retryMiddleware, contentTypeMiddleware, pluginMiddleware}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// Use appends middlewares to the chain. It must not be called while requests
+// are in flight.
+func (e *Executor) Use(middlewares ...Middleware) {
+	e.middlewares = append(e.middlewares, middlewares...)
+}
+
+// BeforeRequest registers a hook that runs before every request is sent.
+func (e *Executor) BeforeRequest(hook BeforeRequestHook) {
+	e.Use(BeforeRequestMiddleware(hook))
+}
+
+// AfterResponse registers a hook that runs on every response.
+func (e *Executor) AfterResponse(hook AfterResponseHook) {
+	e.Use(AfterResponseMiddleware(hook))
+}
+
+func (e *Executor) client() *http.Client {
+	return &http.Client{
+		Transport: chain(recordHeaders(e.transport), e.middlewares),
 	}
 }
 
 func (e *Executor) Execute(req parser.HTTPRequest) (*Response, error) {
+	return e.ExecuteContext(context.Background(), req)
+}
+
+// ExecuteContext is Execute with a context that cancels the request, and
+// its retries, when it is done.
+func (e *Executor) ExecuteContext(ctx context.Context, req parser.HTTPRequest) (*Response, error) {
+	resp, err := e.execute(ctx, req)
+	e.responses.record(req, resp)
+	for _, hook := range e.hooks {
+		hook(req, resp)
+	}
+	return resp, err
+}
+
+func (e *Executor) execute(ctx context.Context, req parser.HTTPRequest) (*Response, error) {
 	start := time.Now()
 	
-	httpReq, err := http.NewRequest(req.Method, req.URL, strings.NewReader(req.Body))
+	ctx, rc := withRequestContext(ctx, req)
+	ctx, trace := withTrace(ctx)
+	httpReq, err := http.NewRequestWithContext(ctx, req.Method, parser.EncodeURL(req.URL), strings.NewReader(req.Body))
 	if err != nil {
 		return &Response{
-			Error:    err,
-			Duration: time.Since(start),
+			Error:     err,
+			Duration:  time.Since(start),
+			StartedAt: start,
 		}, err
 	}
 
@@ -54,21 +146,14 @@ func (e *Executor) Execute(req parser.HTTPRequest) (*Response, error) {
 		}
 	}
 
-	if req.Body != "" && httpReq.Header.Get("Content-Type") == "" {
-		if strings.HasPrefix(strings.TrimSpace(req.Body), "{") || strings.HasPrefix(strings.TrimSpace(req.Body), "[") {
-			httpReq.Header.Set("Content-Type", "application/json")
-		} else if strings.HasPrefix(strings.TrimSpace(req.Body), "<") {
-			httpReq.Header.Set("Content-Type", "application/xml")
-		} else {
-			httpReq.Header.Set("Content-Type", "text/plain")
-		}
-	}
-
-	resp, err := e.client.Do(httpReq)
+	resp, err := e.client().Do(httpReq)
 	if err != nil {
 		return &Response{
-			Error:    err,
-			Duration: time.Since(start),
+			Error:     err,
+			Duration:  time.Since(start),
+			Attempts:  rc.attempts,
+			StartedAt: start,
+			RequestHeaders: rc.headers,
 		}, err
 	}
 	defer func() {
@@ -83,6 +168,11 @@ func (e *Executor) Execute(req parser.HTTPRequest) (*Response, error) {
 			Headers:    resp.Header,
 			Error:      err,
 			Duration:   time.Since(start),
+			Attempts:   rc.attempts,
+			StartedAt:  start,
+			Proto:      resp.Proto,
+			Timings:    trace.timings(time.Now()),
+			RequestHeaders: rc.headers,
 		}, err
 	}
 
@@ -93,20 +183,36 @@ func (e *Executor) Execute(req parser.HTTPRequest) (*Response, error) {
 		Body:             string(body),
 		Duration:         time.Since(start),
 		CapturedVariables: make(map[string]string),
+		Attempts:         rc.attempts,
+		StartedAt:        start,
+		Proto:            resp.Proto,
+		Timings:          trace.timings(time.Now()),
+		RequestHeaders:   rc.headers,
 	}
 
 	if len(req.Captures) > 0 {
-		response.CapturedVariables = applyCaptureRules(string(body), req.Captures)
+		response.CapturedVariables, response.CaptureWarnings = applyCaptureRules(response, req.Captures)
+	}
+	for varName, varValue := range rc.captured {
+		response.CapturedVariables[varName] = varValue
 	}
 
 	return response, nil
 }
 
 func (e *Executor) ExecuteAll(file *parser.HTTPFile) ([]*Response, error) {
+	if e.parallel > 1 {
+		return e.executeAllParallel(file)
+	}
+
 	responses := make([]*Response, 0, len(file.Requests))
 
 	for _, req := range file.Requests {
-		req.ApplyVariables(file.Variables)
+		req.Headers = req.Headers.Clone()
+		if err := e.ApplyVariables(file, &req, file.Variables); err != nil {
+			responses = append(responses, &Response{Error: err})
+			continue
+		}
 		resp, err := e.Execute(req)
 		if err != nil && resp == nil {
 			return responses, err
@@ -127,11 +233,16 @@ func FormatResponse(resp *Response) string {
 	if resp.Error != nil {
 		fmt.Fprintf(&buf, "Error: %v\n", resp.Error)
 		fmt.Fprintf(&buf, "Duration: %v\n", resp.Duration)
+		writeAttempts(&buf, resp.Attempts)
 		return buf.String()
 	}
 
 	fmt.Fprintf(&buf, "Status: %s\n", resp.Status)
 	fmt.Fprintf(&buf, "Duration: %v\n", resp.Duration)
+	writeAttempts(&buf, resp.Attempts)
+	for _, warning := range resp.CaptureWarnings {
+		fmt.Fprintf(&buf, "Capture warning: %s\n", warning)
+	}
 	fmt.Fprintln(&buf, "\nHeaders:")
 	for key, values := range resp.Headers {
 		for _, value := range values {
@@ -148,6 +259,20 @@ func FormatResponse(resp *Response) string {
 	return buf.String()
 }
 
+func writeAttempts(buf *bytes.Buffer, attempts []Attempt) {
+	if len(attempts) <= 1 {
+		return
+	}
+	fmt.Fprintf(buf, "Attempts: %d\n", len(attempts))
+	for _, attempt := range attempts {
+		outcome := fmt.Sprintf("status %d", attempt.StatusCode)
+		if attempt.Error != nil {
+			outcome = fmt.Sprintf("error: %v", attempt.Error)
+		}
+		fmt.Fprintf(buf, "  #%d %s (%v)\n", attempt.Number, outcome, attempt.Duration)
+	}
+}
+
 func formatBody(body string, contentType string) string {
 	if strings.Contains(contentType, "application/json") ||
 	   strings.HasPrefix(strings.TrimSpace(body), "{") ||
@@ -161,20 +286,3 @@ func formatBody(body string, contentType string) string {
 	}
 	return body
 }
-
-func applyCaptureRules(body string, captures []parser.CaptureRule) map[string]string {
-	capturedVars := make(map[string]string)
-
-	if !gjson.Valid(body) {
-		return capturedVars
-	}
-
-	for _, capture := range captures {
-		result := gjson.Get(body, capture.JSONPath)
-		if result.Exists() {
-			capturedVars[capture.VariableName] = result.String()
-		}
-	}
-
-	return capturedVars
-}
\ No newline at end of file
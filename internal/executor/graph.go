@@ -0,0 +1,256 @@
+package executor
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/cassielabs/hrun/internal/parser"
+)
+
+// CycleError reports requests that depend on each other.
+type CycleError struct {
+	Chain []string
+}
+
+func (e *CycleError) Error() string {
+	return fmt.Sprintf("dependency cycle: %s", strings.Join(e.Chain, " -> "))
+}
+
+// MissingDependencyError reports an @depends-on that names no request.
+type MissingDependencyError struct {
+	Request string
+	Name    string
+}
+
+func (e *MissingDependencyError) Error() string {
+	return fmt.Sprintf("%s depends on unknown request %q", e.Request, e.Name)
+}
+
+// UndefinedVariableError reports a variable that a request uses without a
+// fallback, which no request captures and nothing defines.
+type UndefinedVariableError struct {
+	Request string
+	Name    string
+}
+
+func (e *UndefinedVariableError) Error() string {
+	return fmt.Sprintf("%s uses {{%s}}, which is not defined or captured by any request", e.Request, e.Name)
+}
+
+func requestLabel(req parser.HTTPRequest, index int) string {
+	if req.Name != "" {
+		return req.Name
+	}
+	return fmt.Sprintf("#%d %s %s", index+1, req.Method, req.URL)
+}
+
+// buildDependencyGraph returns, for every request, the indexes of the
+// requests it must wait for. A request depends on the closest earlier
+// request capturing each variable it uses, directly or through a file
+// variable, or on a later one when the variable is not defined in the
+// file, on every request whose response it references, and on every
+// request named by @depends-on.
+//
+// A response reference to an unknown request is a *MissingDependencyError,
+// and a variable used without a fallback that no request captures and that
+// is not defined by the file, the request or the environment is an
+// *UndefinedVariableError, unless undefined variables are prompted for.
+func buildDependencyGraph(file *parser.HTTPFile, promptUndefined bool) ([][]int, error) {
+	producers := make(map[string][]int)
+	byName := make(map[string]int)
+	for i, req := range file.Requests {
+		for _, capture := range req.Captures {
+			producers[capture.VariableName] = append(producers[capture.VariableName], i)
+		}
+		if req.Name != "" {
+			if _, exists := byName[req.Name]; !exists {
+				byName[req.Name] = i
+			}
+		}
+	}
+
+	deps := make([][]int, len(file.Requests))
+	for i, req := range file.Requests {
+		seen := make(map[int]bool)
+		add := func(dep int) {
+			if dep != i && !seen[dep] {
+				seen[dep] = true
+				deps[i] = append(deps[i], dep)
+			}
+		}
+
+		required := requiredVariables(file.Variables, req.RequiredVariableReferences())
+		for _, name := range variableDependencies(file.Variables, req.VariableReferences()) {
+			if ref, ok := parser.ParseResponseReference(name); ok {
+				dep, ok := byName[ref.Request]
+				if !ok {
+					return nil, &MissingDependencyError{Request: requestLabel(req, i), Name: ref.Request}
+				}
+				add(dep)
+				continue
+			}
+			candidates := producers[name]
+			if len(candidates) == 0 {
+				if required[name] && !promptUndefined && !isDefined(file, &req, name) {
+					return nil, &UndefinedVariableError{Request: requestLabel(req, i), Name: name}
+				}
+				continue
+			}
+			producer := -1
+			for _, c := range candidates {
+				if c < i {
+					producer = c
+				}
+			}
+			if producer == -1 {
+				if _, defined := file.Variables[name]; defined {
+					continue
+				}
+				producer = candidates[0]
+			}
+			add(producer)
+		}
+
+		for _, name := range req.DependsOn {
+			dep, ok := byName[name]
+			if !ok {
+				return nil, &MissingDependencyError{Request: requestLabel(req, i), Name: name}
+			}
+			add(dep)
+		}
+	}
+
+	if err := detectCycle(file.Requests, deps); err != nil {
+		return nil, err
+	}
+	return deps, nil
+}
+
+// variableDependencies adds the variables that the variables in names
+// refer to, so a request using `{{apiUrl}}` with `@apiUrl = {{host}}/v2`
+// waits for whichever request captures host.
+func variableDependencies(variables map[string]string, names []string) []string {
+	seen := make(map[string]bool)
+	var all []string
+	for len(names) > 0 {
+		name := names[0]
+		names = names[1:]
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		all = append(all, name)
+		if value, ok := variables[name]; ok {
+			names = append(names, parser.VariableNames(value)...)
+		}
+	}
+	return all
+}
+
+// requiredVariables returns the variables in names, and those that the
+// values of defined variables among them refer to, that are used without a
+// fallback.
+func requiredVariables(variables map[string]string, names []string) map[string]bool {
+	required := make(map[string]bool)
+	for len(names) > 0 {
+		name := names[0]
+		names = names[1:]
+		if required[name] {
+			continue
+		}
+		required[name] = true
+		if value, ok := variables[name]; ok {
+			names = append(names, parser.RequiredVariableNames(value)...)
+		}
+	}
+	return required
+}
+
+// isDefined reports whether name has a value before any request runs.
+func isDefined(file *parser.HTTPFile, req *parser.HTTPRequest, name string) bool {
+	if _, ok := file.Variables[name]; ok {
+		return true
+	}
+	if _, ok := req.Variables[name]; ok {
+		return true
+	}
+	for _, prompt := range req.Prompts {
+		if prompt.Name == name {
+			return true
+		}
+	}
+	_, ok := os.LookupEnv(name)
+	return ok
+}
+
+func detectCycle(requests []parser.HTTPRequest, deps [][]int) error {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make([]int, len(deps))
+	var stack []int
+
+	var visit func(int) error
+	visit = func(i int) error {
+		state[i] = visiting
+		stack = append(stack, i)
+		for _, dep := range deps[i] {
+			switch state[dep] {
+			case visiting:
+				start := len(stack) - 1
+				for stack[start] != dep {
+					start--
+				}
+				var chain []string
+				for _, n := range stack[start:] {
+					chain = append(chain, requestLabel(requests[n], n))
+				}
+				chain = append(chain, requestLabel(requests[dep], dep))
+				return &CycleError{Chain: chain}
+			case unvisited:
+				if err := visit(dep); err != nil {
+					return err
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[i] = visited
+		return nil
+	}
+
+	for i := range deps {
+		if state[i] == unvisited {
+			if err := visit(i); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// transitiveDependencies returns every request i waits on, directly or not,
+// in file order.
+func transitiveDependencies(deps [][]int, i int) []int {
+	seen := make(map[int]bool)
+	var walk func(int)
+	walk = func(n int) {
+		for _, dep := range deps[n] {
+			if !seen[dep] {
+				seen[dep] = true
+				walk(dep)
+			}
+		}
+	}
+	walk(i)
+
+	result := make([]int, 0, len(seen))
+	for n := range deps {
+		if seen[n] {
+			result = append(result, n)
+		}
+	}
+	return result
+}
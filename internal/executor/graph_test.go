@@ -0,0 +1,302 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cassielabs/hrun/internal/parser"
+)
+
+func TestBuildDependencyGraph(t *testing.T) {
+	content := `@baseUrl = https://api.example.com
+
+### login
+# @capture token = token
+POST {{baseUrl}}/login
+
+### profile
+GET {{baseUrl}}/me
+Authorization: Bearer {{token}}
+
+### health
+GET {{baseUrl}}/health
+
+### audit
+# @depends-on health
+GET {{baseUrl}}/audit
+`
+	httpFile, err := parser.ParseString(content)
+	if err != nil {
+		t.Fatalf("ParseString failed: %v", err)
+	}
+
+	deps, err := buildDependencyGraph(httpFile, false)
+	if err != nil {
+		t.Fatalf("buildDependencyGraph failed: %v", err)
+	}
+
+	expected := [][]int{nil, {0}, nil, {2}}
+	if !reflect.DeepEqual(deps, expected) {
+		t.Errorf("Expected dependencies %v, got %v", expected, deps)
+	}
+}
+
+func TestBuildDependencyGraph_ClosestEarlierProducer(t *testing.T) {
+	content := `### first
+# @capture id = id
+GET https://api.example.com/a
+
+### second
+# @capture id = id
+GET https://api.example.com/b
+
+### third
+GET https://api.example.com/items/{{id}}
+`
+	httpFile, err := parser.ParseString(content)
+	if err != nil {
+		t.Fatalf("ParseString failed: %v", err)
+	}
+
+	deps, err := buildDependencyGraph(httpFile, false)
+	if err != nil {
+		t.Fatalf("buildDependencyGraph failed: %v", err)
+	}
+	if !reflect.DeepEqual(deps[2], []int{1}) {
+		t.Errorf("Expected third request to depend on second, got %v", deps[2])
+	}
+}
+
+func TestBuildDependencyGraph_ThroughFileVariable(t *testing.T) {
+	content := `@auth = Bearer {{token}}
+
+### login
+# @capture token = token
+POST https://api.example.com/login
+
+### profile
+GET https://api.example.com/me
+Authorization: {{auth}}
+`
+	httpFile, err := parser.ParseString(content)
+	if err != nil {
+		t.Fatalf("ParseString failed: %v", err)
+	}
+
+	deps, err := buildDependencyGraph(httpFile, false)
+	if err != nil {
+		t.Fatalf("buildDependencyGraph failed: %v", err)
+	}
+	if !reflect.DeepEqual(deps[1], []int{0}) {
+		t.Errorf("Expected profile to wait for the token behind {{auth}}, got %v", deps[1])
+	}
+}
+
+func TestBuildDependencyGraph_Cycle(t *testing.T) {
+	content := `### a
+# @capture x = x
+GET https://api.example.com/a?y={{y}}
+
+### b
+# @capture y = y
+GET https://api.example.com/b?x={{x}}
+`
+	httpFile, err := parser.ParseString(content)
+	if err != nil {
+		t.Fatalf("ParseString failed: %v", err)
+	}
+
+	_, err = buildDependencyGraph(httpFile, false)
+	var cycleErr *CycleError
+	if !errors.As(err, &cycleErr) {
+		t.Fatalf("Expected CycleError, got %v", err)
+	}
+	if got := strings.Join(cycleErr.Chain, " -> "); got != "a -> b -> a" {
+		t.Errorf("Expected chain 'a -> b -> a', got %q", got)
+	}
+}
+
+func TestBuildDependencyGraph_MissingDependency(t *testing.T) {
+	content := `### a
+# @depends-on nowhere
+GET https://api.example.com/a
+`
+	httpFile, err := parser.ParseString(content)
+	if err != nil {
+		t.Fatalf("ParseString failed: %v", err)
+	}
+
+	_, err = buildDependencyGraph(httpFile, false)
+	var missingErr *MissingDependencyError
+	if !errors.As(err, &missingErr) {
+		t.Fatalf("Expected MissingDependencyError, got %v", err)
+	}
+	if missingErr.Name != "nowhere" {
+		t.Errorf("Expected missing name 'nowhere', got %q", missingErr.Name)
+	}
+}
+
+func TestExecuteAll_Parallel(t *testing.T) {
+	var inFlight, maxInFlight int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+				break
+			}
+		}
+		time.Sleep(30 * time.Millisecond)
+		if r.URL.Path == "/login" {
+			_, _ = w.Write([]byte(`{"token":"secret"}`))
+			return
+		}
+		_, _ = fmt.Fprintf(w, "%s %s", r.URL.Path, r.Header.Get("Authorization"))
+	}))
+	defer server.Close()
+
+	content := fmt.Sprintf(`@baseUrl = %s
+
+### login
+# @capture token = token
+POST {{baseUrl}}/login
+
+### profile
+GET {{baseUrl}}/me
+Authorization: Bearer {{token}}
+
+### one
+GET {{baseUrl}}/one
+
+### two
+GET {{baseUrl}}/two
+`, server.URL)
+	httpFile, err := parser.ParseString(content)
+	if err != nil {
+		t.Fatalf("ParseString failed: %v", err)
+	}
+
+	exec := New(5*time.Second, WithParallel(4))
+	responses, err := exec.ExecuteAll(httpFile)
+	if err != nil {
+		t.Fatalf("ExecuteAll failed: %v", err)
+	}
+
+	expected := []string{`{"token":"secret"}`, "/me Bearer secret", "/one ", "/two "}
+	for i, resp := range responses {
+		if resp.Body != expected[i] {
+			t.Errorf("Response %d: expected %q, got %q", i, expected[i], resp.Body)
+		}
+	}
+	if maxInFlight < 2 {
+		t.Errorf("Expected independent requests to overlap, max in flight was %d", maxInFlight)
+	}
+	if httpFile.Variables["token"] != "secret" {
+		t.Errorf("Expected captured token in file variables, got %q", httpFile.Variables["token"])
+	}
+}
+
+func TestBuildDependencyGraph_ResponseReference(t *testing.T) {
+	content := `### login
+# @name login
+POST https://api.example.com/login
+
+### profile
+GET https://api.example.com/me
+Authorization: Bearer {{login.response.body.$.token}}
+`
+	httpFile, err := parser.ParseString(content)
+	if err != nil {
+		t.Fatalf("ParseString failed: %v", err)
+	}
+
+	deps, err := buildDependencyGraph(httpFile, false)
+	if err != nil {
+		t.Fatalf("buildDependencyGraph failed: %v", err)
+	}
+	if !reflect.DeepEqual(deps[1], []int{0}) {
+		t.Errorf("Expected profile to wait for the login response, got %v", deps[1])
+	}
+}
+
+func TestBuildDependencyGraph_Undefined(t *testing.T) {
+	t.Setenv("HRUN_GRAPH_ENV", "set")
+	tests := []struct {
+		name            string
+		content         string
+		promptUndefined bool
+		expected        string
+	}{
+		{
+			name: "undefined variable",
+			content: `### a
+GET https://api.example.com/{{nowhere}}
+`,
+			expected: "a uses {{nowhere}}, which is not defined or captured by any request",
+		},
+		{
+			name: "undefined through a file variable",
+			content: `@url = https://{{host}}/v1
+
+### a
+GET {{url}}/a
+`,
+			expected: "a uses {{host}}, which is not defined or captured by any request",
+		},
+		{
+			name: "unknown response reference",
+			content: `### a
+GET https://api.example.com/{{login.response.body.$.id}}
+`,
+			expected: `a depends on unknown request "login"`,
+		},
+		{
+			name: "fallback",
+			content: `### a
+GET https://api.example.com/{{page ?? "1"}}
+`,
+		},
+		{
+			name: "environment and prompt",
+			content: `### a
+# @prompt otp
+GET https://api.example.com/{{HRUN_GRAPH_ENV}}?otp={{otp}}
+`,
+		},
+		{
+			name: "prompted for",
+			content: `### a
+GET https://api.example.com/{{nowhere}}
+`,
+			promptUndefined: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			httpFile, err := parser.ParseString(tt.content)
+			if err != nil {
+				t.Fatalf("ParseString failed: %v", err)
+			}
+
+			_, err = buildDependencyGraph(httpFile, tt.promptUndefined)
+			if tt.expected == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.expected {
+				t.Errorf("Expected error %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
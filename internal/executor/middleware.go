@@ -0,0 +1,196 @@
+package executor
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/cassielabs/hrun/internal/parser"
+)
+
+// RoundTripper is the unit every middleware wraps. It is the same contract
+// as http.RoundTripper so any standard transport can sit at the bottom of
+// the chain.
+type RoundTripper = http.RoundTripper
+
+// RoundTripperFunc adapts a plain function to a RoundTripper.
+type RoundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Middleware wraps the next RoundTripper in the chain. Middlewares run in
+// the order they were registered: the first one sees the request first and
+// the response last.
+type Middleware func(next RoundTripper) RoundTripper
+
+// BeforeRequestHook runs before a request is sent. Returning an error aborts
+// the request.
+type BeforeRequestHook func(req *http.Request) error
+
+// AfterResponseHook runs once the response headers are received, before the
+// body is read. Returning an error fails the request.
+type AfterResponseHook func(req *http.Request, resp *http.Response) error
+
+// BeforeRequestMiddleware turns a BeforeRequestHook into a Middleware. The
+// hook gets a copy of the request, since a RoundTripper must not change the
+// one it is given, so it may set headers freely.
+func BeforeRequestMiddleware(hook BeforeRequestHook) Middleware {
+	return func(next RoundTripper) RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			req = req.Clone(req.Context())
+			if err := hook(req); err != nil {
+				return nil, err
+			}
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+// AfterResponseMiddleware turns an AfterResponseHook into a Middleware.
+func AfterResponseMiddleware(hook AfterResponseHook) Middleware {
+	return func(next RoundTripper) RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			resp, err := next.RoundTrip(req)
+			if err != nil {
+				return resp, err
+			}
+			if err := hook(req, resp); err != nil {
+				_ = resp.Body.Close()
+				return nil, err
+			}
+			return resp, nil
+		})
+	}
+}
+
+// HeaderMiddleware sets the given headers on every request that does not
+// already carry them.
+func HeaderMiddleware(headers http.Header) Middleware {
+	return BeforeRequestMiddleware(func(req *http.Request) error {
+		for key, values := range headers {
+			if req.Header.Get(key) != "" {
+				continue
+			}
+			for _, value := range values {
+				req.Header.Add(key, value)
+			}
+		}
+		return nil
+	})
+}
+
+// contentTypeMiddleware guesses a Content-Type for bodies sent without one,
+// on a copy of the request.
+func contentTypeMiddleware(next RoundTripper) RoundTripper {
+	return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Header.Get("Content-Type") == "" {
+			if body := requestBody(req); body != "" {
+				req = req.Clone(req.Context())
+				req.Header.Set("Content-Type", detectContentType(body))
+			}
+		}
+		return next.RoundTrip(req)
+	})
+}
+
+func detectContentType(body string) string {
+	trimmed := strings.TrimSpace(body)
+	if strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+		return "application/json"
+	}
+	if strings.HasPrefix(trimmed, "<") {
+		return "application/xml"
+	}
+	return "text/plain"
+}
+
+// requestBody returns a copy of the request body without consuming it.
+func requestBody(req *http.Request) string {
+	if req.Body == nil || req.GetBody == nil {
+		return ""
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return ""
+	}
+	defer func() {
+		_ = body.Close()
+	}()
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+func chain(transport RoundTripper, middlewares []Middleware) RoundTripper {
+	rt := transport
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		rt = middlewares[i](rt)
+	}
+	return rt
+}
+
+type requestContextKey struct{}
+
+type requestContext struct {
+	request  parser.HTTPRequest
+	mu       sync.Mutex
+	captured map[string]string
+	attempts []Attempt
+	headers  http.Header
+}
+
+func withRequestContext(ctx context.Context, req parser.HTTPRequest) (context.Context, *requestContext) {
+	rc := &requestContext{request: req, captured: make(map[string]string)}
+	return context.WithValue(ctx, requestContextKey{}, rc), rc
+}
+
+// SourceRequest returns the parsed request an outgoing *http.Request was
+// built from, so middlewares can read its directives.
+func SourceRequest(ctx context.Context) (parser.HTTPRequest, bool) {
+	rc, ok := ctx.Value(requestContextKey{}).(*requestContext)
+	if !ok {
+		return parser.HTTPRequest{}, false
+	}
+	return rc.request, true
+}
+
+// Capture records a variable from inside a middleware. It is merged into the
+// CapturedVariables of the resulting Response.
+func Capture(ctx context.Context, name, value string) {
+	rc, ok := ctx.Value(requestContextKey{}).(*requestContext)
+	if !ok {
+		return
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.captured[name] = value
+}
+
+func recordAttempt(ctx context.Context, attempt Attempt) {
+	rc, ok := ctx.Value(requestContextKey{}).(*requestContext)
+	if !ok {
+		return
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.attempts = append(rc.attempts, attempt)
+}
+
+// recordHeaders sits at the bottom of the chain and keeps the headers each
+// attempt is sent with, so the last one can be reported with the response.
+func recordHeaders(next RoundTripper) RoundTripper {
+	return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if rc, ok := req.Context().Value(requestContextKey{}).(*requestContext); ok {
+			rc.mu.Lock()
+			rc.headers = req.Header.Clone()
+			rc.mu.Unlock()
+		}
+		return next.RoundTrip(req)
+	})
+}
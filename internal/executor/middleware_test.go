@@ -0,0 +1,183 @@
+package executor
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cassielabs/hrun/internal/parser"
+)
+
+func echoHeadersServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Seen-Content-Type", r.Header.Get("Content-Type"))
+		w.Header().Set("X-Seen-Trace", r.Header.Get("X-Trace"))
+		w.Header().Set("X-Seen-Authorization", r.Header.Get("Authorization"))
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestMiddleware_Order(t *testing.T) {
+	server := echoHeadersServer(t)
+
+	var calls []string
+	record := func(name string) Middleware {
+		return func(next RoundTripper) RoundTripper {
+			return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				calls = append(calls, name+":before")
+				resp, err := next.RoundTrip(req)
+				calls = append(calls, name+":after")
+				return resp, err
+			})
+		}
+	}
+
+	exec := New(5*time.Second, WithMiddleware(record("first"), record("second")))
+	_, err := exec.Execute(parser.HTTPRequest{Method: "GET", URL: server.URL})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	expected := []string{"first:before", "second:before", "second:after", "first:after"}
+	if strings.Join(calls, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected call order %v, got %v", expected, calls)
+	}
+}
+
+func TestMiddleware_Hooks(t *testing.T) {
+	server := echoHeadersServer(t)
+
+	exec := New(5 * time.Second)
+	exec.BeforeRequest(func(req *http.Request) error {
+		req.Header.Set("X-Trace", "abc")
+		return nil
+	})
+	var seenStatus int
+	exec.AfterResponse(func(req *http.Request, resp *http.Response) error {
+		seenStatus = resp.StatusCode
+		return nil
+	})
+
+	resp, err := exec.Execute(parser.HTTPRequest{Method: "GET", URL: server.URL})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if got := resp.Headers.Get("X-Seen-Trace"); got != "abc" {
+		t.Errorf("Expected BeforeRequest header to reach server, got %q", got)
+	}
+	if seenStatus != http.StatusOK {
+		t.Errorf("Expected AfterResponse to see status 200, got %d", seenStatus)
+	}
+}
+
+func TestMiddleware_BeforeRequestError(t *testing.T) {
+	server := echoHeadersServer(t)
+
+	exec := New(5 * time.Second)
+	exec.BeforeRequest(func(req *http.Request) error {
+		return errors.New("signing failed")
+	})
+
+	resp, err := exec.Execute(parser.HTTPRequest{Method: "GET", URL: server.URL})
+	if err == nil {
+		t.Fatal("Expected error from BeforeRequest hook")
+	}
+	if !strings.Contains(err.Error(), "signing failed") {
+		t.Errorf("Expected hook error to be surfaced, got %v", err)
+	}
+	if resp == nil || resp.Error == nil {
+		t.Errorf("Expected response to carry the error")
+	}
+}
+
+func TestMiddleware_BuiltinContentType(t *testing.T) {
+	server := echoHeadersServer(t)
+
+	tests := []struct {
+		name     string
+		body     string
+		headers  http.Header
+		expected string
+	}{
+		{name: "JSON object", body: `{"a":1}`, expected: "application/json"},
+		{name: "JSON array", body: `[1,2]`, expected: "application/json"},
+		{name: "XML", body: `<a/>`, expected: "application/xml"},
+		{name: "Plain text", body: `hello`, expected: "text/plain"},
+		{name: "Empty body", body: ``, expected: ""},
+		{
+			name:     "Explicit header wins",
+			body:     `{"a":1}`,
+			headers:  http.Header{"Content-Type": []string{"text/csv"}},
+			expected: "text/csv",
+		},
+	}
+
+	exec := New(5 * time.Second)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := tt.headers
+			if headers == nil {
+				headers = http.Header{}
+			}
+			resp, err := exec.Execute(parser.HTTPRequest{Method: "POST", URL: server.URL, Body: tt.body, Headers: headers})
+			if err != nil {
+				t.Fatalf("Execute failed: %v", err)
+			}
+			if got := resp.Headers.Get("X-Seen-Content-Type"); got != tt.expected {
+				t.Errorf("Expected Content-Type %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestMiddleware_Header(t *testing.T) {
+	server := echoHeadersServer(t)
+
+	// outer checks that the request it passed on comes back unchanged.
+	var changed []string
+	outer := func(next RoundTripper) RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			before := req.Header.Clone()
+			resp, err := next.RoundTrip(req)
+			for _, key := range []string{"Authorization", "Content-Type"} {
+				if req.Header.Get(key) != before.Get(key) {
+					changed = append(changed, key)
+				}
+			}
+			return resp, err
+		})
+	}
+
+	exec := New(5*time.Second, WithMiddleware(outer, HeaderMiddleware(http.Header{"Authorization": {"Bearer tok"}})))
+	resp, err := exec.Execute(parser.HTTPRequest{Method: "POST", URL: server.URL, Headers: http.Header{}, Body: `{"a":1}`})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if got := resp.Headers.Get("X-Seen-Authorization"); got != "Bearer tok" {
+		t.Errorf("Expected bearer token, got %q", got)
+	}
+	if got := resp.Headers.Get("X-Seen-Content-Type"); got != "application/json" {
+		t.Errorf("Expected detected Content-Type, got %q", got)
+	}
+	if len(changed) > 0 {
+		t.Errorf("Expected middlewares to leave the request they were given alone, changed %v", changed)
+	}
+
+	resp, err = exec.Execute(parser.HTTPRequest{
+		Method:  "GET",
+		URL:     server.URL,
+		Headers: http.Header{"Authorization": []string{"Basic xyz"}},
+	})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if got := resp.Headers.Get("X-Seen-Authorization"); got != "Basic xyz" {
+		t.Errorf("Expected explicit Authorization to be kept, got %q", got)
+	}
+}
@@ -0,0 +1,75 @@
+package executor
+
+import (
+	"sync"
+
+	"github.com/cassielabs/hrun/internal/parser"
+)
+
+// WithParallel lets ExecuteAll run up to n independent requests at once.
+func WithParallel(n int) Option {
+	return func(e *Executor) {
+		e.parallel = n
+	}
+}
+
+// executeAllParallel runs requests as soon as the requests they depend on
+// have finished. Each request sees the file variables plus the variables
+// captured by its dependencies, applied in file order, so the result does
+// not depend on scheduling.
+func (e *Executor) executeAllParallel(file *parser.HTTPFile) ([]*Response, error) {
+	deps, err := buildDependencyGraph(file, e.prompts != nil && e.prompts.undefined)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]*Response, len(file.Requests))
+	done := make([]chan struct{}, len(file.Requests))
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+	sem := make(chan struct{}, e.parallel)
+
+	var wg sync.WaitGroup
+	for i := range file.Requests {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer close(done[i])
+
+			for _, dep := range deps[i] {
+				<-done[dep]
+			}
+
+			variables := make(map[string]string, len(file.Variables))
+			for k, v := range file.Variables {
+				variables[k] = v
+			}
+			for _, dep := range transitiveDependencies(deps, i) {
+				for k, v := range responses[dep].CapturedVariables {
+					variables[k] = v
+				}
+			}
+
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			req := file.Requests[i]
+			req.Headers = req.Headers.Clone()
+			if err := e.ApplyVariables(file, &req, variables); err != nil {
+				responses[i] = &Response{Error: err}
+				return
+			}
+			responses[i], _ = e.Execute(req)
+		}(i)
+	}
+	wg.Wait()
+
+	for _, resp := range responses {
+		for varName, varValue := range resp.CapturedVariables {
+			file.Variables[varName] = varValue
+		}
+	}
+
+	return responses, nil
+}
@@ -0,0 +1,122 @@
+package executor
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/cassielabs/hrun/internal/plugin"
+)
+
+// pluginMiddleware runs the external plugins enabled on a request with
+// `# @plugin name args...`. Pre-request output replaces the fields it sets
+// on a copy of the outgoing request; post-response output is merged into
+// the captured variables.
+func pluginMiddleware(next RoundTripper) RoundTripper {
+	return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		source, ok := SourceRequest(req.Context())
+		if !ok || len(source.Plugins) == 0 {
+			return next.RoundTrip(req)
+		}
+
+		for _, p := range source.Plugins {
+			output, err := plugin.Run(req.Context(), p.Name, plugin.Input{
+				Hook:    plugin.HookPreRequest,
+				Args:    p.Args,
+				Request: pluginRequest(req),
+			})
+			if err != nil {
+				return nil, err
+			}
+			if output.Request != nil {
+				if req, err = applyPluginRequest(req, output.Request); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		resp, err := next.RoundTrip(req)
+		if err != nil {
+			return resp, err
+		}
+
+		body, err := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+
+		for _, p := range source.Plugins {
+			output, err := plugin.Run(req.Context(), p.Name, plugin.Input{
+				Hook:    plugin.HookPostResponse,
+				Args:    p.Args,
+				Request: pluginRequest(req),
+				Response: &plugin.Response{
+					StatusCode: resp.StatusCode,
+					Headers:    resp.Header,
+					Body:       string(body),
+				},
+			})
+			if err != nil {
+				return nil, err
+			}
+			for name, value := range output.Variables {
+				Capture(req.Context(), name, value)
+			}
+		}
+
+		return resp, nil
+	})
+}
+
+func pluginRequest(req *http.Request) plugin.Request {
+	body := requestBody(req)
+	return plugin.Request{
+		Method:  req.Method,
+		URL:     req.URL.String(),
+		Headers: req.Header.Clone(),
+		Body:    &body,
+	}
+}
+
+// applyPluginRequest returns a copy of req with the fields a plugin set.
+// Header names are canonicalized, since plugins may write them in any case.
+func applyPluginRequest(req *http.Request, modified *plugin.Request) (*http.Request, error) {
+	req = req.Clone(req.Context())
+	if modified.Method != "" {
+		req.Method = strings.ToUpper(modified.Method)
+	}
+	if modified.URL != "" {
+		u, err := url.Parse(modified.URL)
+		if err != nil {
+			return nil, err
+		}
+		req.URL = u
+		req.Host = u.Host
+	}
+	if modified.Headers != nil {
+		req.Header = make(http.Header, len(modified.Headers))
+		for key, values := range modified.Headers {
+			canonical := http.CanonicalHeaderKey(key)
+			req.Header[canonical] = append(req.Header[canonical], values...)
+		}
+	}
+	if modified.Body != nil {
+		body := *modified.Body
+		req.Body = http.NoBody
+		if body != "" {
+			req.Body = io.NopCloser(strings.NewReader(body))
+		}
+		req.GetBody = func() (io.ReadCloser, error) {
+			if body == "" {
+				return http.NoBody, nil
+			}
+			return io.NopCloser(strings.NewReader(body)), nil
+		}
+		req.ContentLength = int64(len(body))
+	}
+	return req, nil
+}
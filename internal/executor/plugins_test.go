@@ -0,0 +1,122 @@
+package executor
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cassielabs/hrun/internal/parser"
+	"github.com/cassielabs/hrun/internal/plugin"
+)
+
+func TestPluginMiddleware(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell plugins are not supported on windows")
+	}
+
+	dir := t.TempDir()
+	script := `#!/bin/sh
+input=$(cat)
+case "$input" in
+  *'"hook":"pre-request"'*) echo '{"request":{"headers":{"X-Signature":["signed"]},"body":"rewritten"}}' ;;
+  *) echo '{"variables":{"signatureChecked":"true"}}' ;;
+esac
+`
+	if err := os.WriteFile(filepath.Join(dir, plugin.ExecutablePrefix+"sign"), []byte(script), 0o755); err != nil {
+		t.Fatalf("Failed to write plugin: %v", err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	var gotSignature, gotBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotSignature = r.Header.Get("X-Signature")
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	}))
+	defer server.Close()
+
+	exec := New(5 * time.Second)
+	resp, err := exec.Execute(parser.HTTPRequest{
+		Method:  "POST",
+		URL:     server.URL,
+		Headers: http.Header{},
+		Body:    "original",
+		Plugins: []parser.PluginDirective{{Name: "sign"}},
+	})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	if gotSignature != "signed" {
+		t.Errorf("Expected plugin header to reach server, got %q", gotSignature)
+	}
+	if gotBody != "rewritten" {
+		t.Errorf("Expected plugin body to reach server, got %q", gotBody)
+	}
+	if resp.Body != `{"ok":true}` {
+		t.Errorf("Expected response body to survive post-response hook, got %q", resp.Body)
+	}
+	if resp.CapturedVariables["signatureChecked"] != "true" {
+		t.Errorf("Expected plugin variable to be captured, got %v", resp.CapturedVariables)
+	}
+}
+
+func TestPluginMiddleware_MissingPlugin(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Request should not be sent when a plugin is missing")
+	}))
+	defer server.Close()
+
+	exec := New(5 * time.Second)
+	_, err := exec.Execute(parser.HTTPRequest{
+		Method:  "GET",
+		URL:     server.URL,
+		Plugins: []parser.PluginDirective{{Name: "missing"}},
+	})
+	if err == nil {
+		t.Error("Expected error for missing plugin")
+	}
+}
+
+func TestApplyPluginRequest(t *testing.T) {
+	original, err := http.NewRequest("POST", "https://api.example.com/orders", strings.NewReader("original"))
+	if err != nil {
+		t.Fatalf("NewRequest failed: %v", err)
+	}
+	original.Header.Set("Content-Type", "text/plain")
+
+	empty := ""
+	req, err := applyPluginRequest(original, &plugin.Request{
+		Headers: http.Header{"x-signature": {"signed"}, "content-type": {"text/plain"}},
+		Body:    &empty,
+	})
+	if err != nil {
+		t.Fatalf("applyPluginRequest failed: %v", err)
+	}
+	if got := req.Header["X-Signature"]; len(got) != 1 || got[0] != "signed" {
+		t.Errorf("Expected canonical X-Signature header, got %v", req.Header)
+	}
+	if body := requestBody(req); body != "" || req.ContentLength != 0 {
+		t.Errorf("Expected an empty body to clear it, got %q with length %d", body, req.ContentLength)
+	}
+	if original.Header.Get("X-Signature") != "" || requestBody(original) != "original" {
+		t.Errorf("Expected the original request to be left alone, got %v %q", original.Header, requestBody(original))
+	}
+
+	req, err = applyPluginRequest(original, &plugin.Request{Method: "put"})
+	if err != nil {
+		t.Fatalf("applyPluginRequest failed: %v", err)
+	}
+	if req.Method != "PUT" || requestBody(req) != "original" {
+		t.Errorf("Expected only the method to change, got %s %q", req.Method, requestBody(req))
+	}
+}
@@ -0,0 +1,75 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/cassielabs/hrun/internal/parser"
+)
+
+// Prompter asks the user for the value of a variable.
+type Prompter func(prompt parser.Prompt) (string, error)
+
+// prompts asks for variables through a Prompter, one question at a time.
+type prompts struct {
+	mu        sync.Mutex
+	ask       Prompter
+	undefined bool
+	// answers holds the values given for undefined variables, which are
+	// asked for once.
+	answers map[string]string
+}
+
+// WithPrompter makes ApplyVariables ask for a request's `# @prompt`
+// variables each time it runs. With undefined, it also asks once for any
+// other variable that is not defined.
+func WithPrompter(ask Prompter, undefined bool) Option {
+	return func(e *Executor) {
+		e.prompts = &prompts{ask: ask, undefined: undefined, answers: make(map[string]string)}
+	}
+}
+
+// request asks for the request's @prompt variables.
+func (p *prompts) request(req *parser.HTTPRequest) (map[string]string, error) {
+	values := make(map[string]string)
+	if p == nil {
+		return values, nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, prompt := range req.Prompts {
+		value, err := p.ask(prompt)
+		if err != nil {
+			return nil, fmt.Errorf("{{%s}}: %w", prompt.Name, err)
+		}
+		values[prompt.Name] = value
+	}
+	return values, nil
+}
+
+// missing asks for the variables an unresolved error names, or reports
+// false when undefined variables are not prompted for.
+func (p *prompts) missing(err error) (map[string]string, bool, error) {
+	var unresolved *parser.UnresolvedError
+	if p == nil || !p.undefined || !errors.As(err, &unresolved) {
+		return nil, false, nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	values := make(map[string]string)
+	for _, name := range unresolved.Names {
+		if _, ok := parser.ParseResponseReference(name); ok {
+			return nil, false, nil
+		}
+		value, ok := p.answers[name]
+		if !ok {
+			if value, err = p.ask(parser.Prompt{Name: name}); err != nil {
+				return nil, false, fmt.Errorf("{{%s}}: %w", name, err)
+			}
+			p.answers[name] = value
+		}
+		values[name] = value
+	}
+	return values, true, nil
+}
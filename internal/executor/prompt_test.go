@@ -0,0 +1,63 @@
+package executor
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cassielabs/hrun/internal/parser"
+)
+
+func TestApplyVariables_Prompts(t *testing.T) {
+	httpFile, err := parser.ParseString(`@baseUrl = https://api.example.com
+
+### Verify
+# @prompt otp "Enter your 2FA code"
+POST {{baseUrl}}/verify?tenant={{tenant}}
+X-Otp: {{otp}}
+`)
+	if err != nil {
+		t.Fatalf("ParseString failed: %v", err)
+	}
+
+	var asked []string
+	answers := map[string]string{"otp": "123456", "tenant": "acme"}
+	ask := func(prompt parser.Prompt) (string, error) {
+		asked = append(asked, prompt.Name+":"+prompt.Description)
+		return answers[prompt.Name], nil
+	}
+
+	req := httpFile.Requests[0]
+	req.Headers = req.Headers.Clone()
+	err = New(time.Second, WithPrompter(ask, false)).ApplyVariables(httpFile, &req, httpFile.Variables)
+	var unresolved *parser.UnresolvedError
+	if !errors.As(err, &unresolved) || strings.Join(unresolved.Names, ",") != "tenant" {
+		t.Errorf("Expected {{tenant}} to stay unresolved, got %v", err)
+	}
+
+	asked = nil
+	exec := New(time.Second, WithPrompter(ask, true))
+	for i := 0; i < 2; i++ {
+		req := httpFile.Requests[0]
+		req.Headers = req.Headers.Clone()
+		answers["otp"] = strings.Repeat("7", i+1)
+		if err := exec.ApplyVariables(httpFile, &req, httpFile.Variables); err != nil {
+			t.Fatalf("ApplyVariables failed: %v", err)
+		}
+		if req.URL != "https://api.example.com/verify?tenant=acme" || req.Headers.Get("X-Otp") != answers["otp"] {
+			t.Errorf("Expected the answers to be applied, got %s with X-Otp %q", req.URL, req.Headers.Get("X-Otp"))
+		}
+	}
+	if strings.Join(asked, ",") != "otp:Enter your 2FA code,tenant:,otp:Enter your 2FA code" {
+		t.Errorf("Expected @prompt on every run and undefined variables once, got %v", asked)
+	}
+
+	failing := func(parser.Prompt) (string, error) { return "", errors.New("no input") }
+	req = httpFile.Requests[0]
+	req.Headers = req.Headers.Clone()
+	err = New(time.Second, WithPrompter(failing, false)).ApplyVariables(httpFile, &req, httpFile.Variables)
+	if err == nil || err.Error() != "{{otp}}: no input" {
+		t.Errorf("Expected the prompt error, got %v", err)
+	}
+}
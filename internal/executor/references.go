@@ -0,0 +1,179 @@
+package executor
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/cassielabs/hrun/internal/parser"
+)
+
+// responseStore keeps the last successful response of each named request,
+// for `{{name.response...}}` references.
+type responseStore struct {
+	mu     sync.Mutex
+	byName map[string]*Response
+	// running serialises requests run on demand for a reference.
+	running sync.Mutex
+}
+
+func (s *responseStore) get(name string) *Response {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.byName[name]
+}
+
+func (s *responseStore) record(req parser.HTTPRequest, resp *Response) {
+	if req.Name == "" || resp == nil || resp.Error != nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.byName[req.Name] = resp
+}
+
+// WithRunReferenced makes ApplyVariables run a referenced request that has
+// not run yet, instead of failing.
+func WithRunReferenced(run bool) Option {
+	return func(e *Executor) {
+		e.runReferenced = run
+	}
+}
+
+// LastResponse returns the last successful response of the named request,
+// or nil if it has not run.
+func (e *Executor) LastResponse(name string) *Response {
+	return e.responses.get(name)
+}
+
+// ApplyVariables expands req's variables from variables, like
+// parser.HTTPRequest.ApplyVariables, and fills `{{name.response...}}`
+// references, directly or through file variables, from the responses
+// this executor has seen. With WithRunReferenced, a referenced request in
+// file that has not run is executed first, and its captures are added to
+// variables. The request's own Variables, and with WithPrompter its @prompt
+// variables, override variables.
+func (e *Executor) ApplyVariables(file *parser.HTTPFile, req *parser.HTTPRequest, variables map[string]string) error {
+	if !e.runReferenced {
+		return e.applyVariables(file, req, variables, nil)
+	}
+	e.responses.running.Lock()
+	defer e.responses.running.Unlock()
+	return e.applyVariables(file, req, variables, nil)
+}
+
+// applyVariables is ApplyVariables with the chain of requests being run
+// for references, to report requests that refer to each other.
+func (e *Executor) applyVariables(file *parser.HTTPFile, req *parser.HTTPRequest, variables map[string]string, chain []string) error {
+	defined := mergeVariables(variables, req.Variables)
+	values := make(map[string]string)
+	for _, name := range variableDependencies(defined, req.VariableReferences()) {
+		ref, ok := parser.ParseResponseReference(name)
+		if !ok {
+			continue
+		}
+		if _, defined := defined[name]; defined {
+			continue
+		}
+
+		resp := e.responses.get(ref.Request)
+		if resp == nil && e.runReferenced {
+			var err error
+			if resp, err = e.runReference(file, ref.Request, variables, chain); err != nil {
+				return err
+			}
+		}
+		if resp == nil {
+			return fmt.Errorf("{{%s}}: request %s has not run yet", name, ref.Request)
+		}
+
+		value, err := referenceValue(resp, ref)
+		if err != nil {
+			return fmt.Errorf("{{%s}}: %v", name, err)
+		}
+		values[name] = value
+	}
+
+	prompted, err := e.prompts.request(req)
+	if err != nil {
+		return err
+	}
+	for k, v := range prompted {
+		values[k] = v
+	}
+	variables = mergeVariables(mergeVariables(variables, req.Variables), values)
+
+	original := *req
+	original.Headers = req.Headers.Clone()
+	err = req.ApplyVariables(variables)
+	answers, asked, promptErr := e.prompts.missing(err)
+	if promptErr != nil {
+		return promptErr
+	}
+	if !asked {
+		return err
+	}
+	*req = original
+	return req.ApplyVariables(mergeVariables(variables, answers))
+}
+
+// mergeVariables returns variables with values added, leaving variables
+// unchanged.
+func mergeVariables(variables, values map[string]string) map[string]string {
+	if len(values) == 0 {
+		return variables
+	}
+	merged := make(map[string]string, len(variables)+len(values))
+	for k, v := range variables {
+		merged[k] = v
+	}
+	for k, v := range values {
+		merged[k] = v
+	}
+	return merged
+}
+
+// runReference executes the request named name so that its response can be
+// referenced.
+func (e *Executor) runReference(file *parser.HTTPFile, name string, variables map[string]string, chain []string) (*Response, error) {
+	for i, pending := range chain {
+		if pending == name {
+			return nil, fmt.Errorf("requests refer to each other: %s", strings.Join(append(chain[i:], name), " -> "))
+		}
+	}
+
+	var dep *parser.HTTPRequest
+	if file != nil {
+		for i := range file.Requests {
+			if file.Requests[i].Name == name {
+				dep = &file.Requests[i]
+				break
+			}
+		}
+	}
+	if dep == nil {
+		return nil, fmt.Errorf("no request named %s", name)
+	}
+
+	req := *dep
+	req.Headers = req.Headers.Clone()
+	if err := e.applyVariables(file, &req, variables, append(chain, name)); err != nil {
+		return nil, fmt.Errorf("running %s: %w", name, err)
+	}
+	resp, err := e.Execute(req)
+	if err != nil {
+		return nil, fmt.Errorf("running %s: %w", name, err)
+	}
+	for k, v := range resp.CapturedVariables {
+		variables[k] = v
+	}
+	return resp, nil
+}
+
+func referenceValue(resp *Response, ref parser.ResponseReference) (string, error) {
+	capture, ok := ref.Capture()
+	if !ok {
+		return resp.Body, nil
+	}
+	return captureValue(resp, capture)
+}
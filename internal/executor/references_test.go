@@ -0,0 +1,97 @@
+package executor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cassielabs/hrun/internal/parser"
+)
+
+func TestApplyVariables_ResponseReferences(t *testing.T) {
+	var logins atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			logins.Add(1)
+			w.Header().Set("X-Request-Id", "req-1")
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"token": "tok-1", "roles": [{"name": "admin"}]}`))
+		default:
+			_, _ = w.Write([]byte(r.Header.Get("Authorization") + " " + r.Header.Get("X-Trace") + " " + r.URL.Query().Get("role")))
+		}
+	}))
+	defer server.Close()
+
+	content := `@baseUrl = ` + server.URL + `
+@auth = Bearer {{login.response.body.$.token}}
+
+### Log in
+# @name login
+POST {{baseUrl}}/login
+
+### Create order
+# @name createOrder
+POST {{baseUrl}}/orders?role={{login.response.body.$.roles[0]['name']}}
+Authorization: {{auth}}
+X-Trace: {{login.response.headers.X-Request-Id}}
+`
+	httpFile, err := parser.ParseString(content)
+	if err != nil {
+		t.Fatalf("ParseString failed: %v", err)
+	}
+	order := httpFile.Requests[1]
+
+	exec := New(5 * time.Second)
+	req := order
+	req.Headers = req.Headers.Clone()
+	err = exec.ApplyVariables(httpFile, &req, httpFile.Variables)
+	if err == nil || !strings.Contains(err.Error(), "request login has not run yet") {
+		t.Errorf("Expected an error for a request that has not run, got %v", err)
+	}
+
+	exec = New(5*time.Second, WithRunReferenced(true))
+	for i := 0; i < 2; i++ {
+		req := order
+		req.Headers = req.Headers.Clone()
+		if err := exec.ApplyVariables(httpFile, &req, httpFile.Variables); err != nil {
+			t.Fatalf("ApplyVariables failed: %v", err)
+		}
+		resp, err := exec.Execute(req)
+		if err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		if resp.Body != "Bearer tok-1 req-1 admin" {
+			t.Errorf("Expected references to be filled from the login response, got %q", resp.Body)
+		}
+	}
+	if logins.Load() != 1 {
+		t.Errorf("Expected login to run once and be reused, ran %d times", logins.Load())
+	}
+	if exec.LastResponse("createOrder") == nil {
+		t.Error("Expected named responses to be kept")
+	}
+}
+
+func TestApplyVariables_ReferenceCycle(t *testing.T) {
+	httpFile, err := parser.ParseString(`### a
+# @name a
+GET http://127.0.0.1:1/{{b.response.body.id}}
+
+### b
+# @name b
+GET http://127.0.0.1:1/{{a.response.body.id}}
+`)
+	if err != nil {
+		t.Fatalf("ParseString failed: %v", err)
+	}
+
+	req := httpFile.Requests[0]
+	err = New(time.Second, WithRunReferenced(true)).ApplyVariables(httpFile, &req, httpFile.Variables)
+	if err == nil || !strings.Contains(err.Error(), "b -> a -> b") {
+		t.Errorf("Expected the reference cycle to be reported, got %v", err)
+	}
+}
@@ -0,0 +1,210 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cassielabs/hrun/internal/parser"
+)
+
+const defaultRetryDelay = 250 * time.Millisecond
+
+var idempotentMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodOptions: true,
+	http.MethodTrace:   true,
+	http.MethodPut:     true,
+	http.MethodDelete:  true,
+}
+
+// Attempt records the outcome of one try of a request.
+type Attempt struct {
+	Number     int
+	StatusCode int
+	Duration   time.Duration
+	Error      error
+}
+
+// WithRetries sets the retry count used for requests without an @retry
+// directive.
+func WithRetries(retries int) Option {
+	return func(e *Executor) {
+		e.retries = retries
+	}
+}
+
+func (e *Executor) retryPolicy(req parser.HTTPRequest) parser.RetryPolicy {
+	policy := parser.RetryPolicy{MaxRetries: e.retries}
+	if req.Retry != nil {
+		policy = *req.Retry
+	}
+	if policy.Backoff == "" {
+		policy.Backoff = parser.BackoffExponential
+	}
+	if policy.Delay == 0 {
+		policy.Delay = defaultRetryDelay
+	}
+	if len(policy.On) == 0 {
+		policy.On = []string{parser.RetryOn5xx, parser.RetryOnTimeout, parser.RetryOnNetwork}
+	}
+	return policy
+}
+
+// retryMiddleware is the outermost built-in middleware. Every attempt runs
+// the rest of the chain again with its own timeout, so signing plugins and
+// hooks see each retry.
+func (e *Executor) retryMiddleware(next RoundTripper) RoundTripper {
+	return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		source, _ := SourceRequest(req.Context())
+		policy := e.retryPolicy(source)
+
+		maxAttempts := 1
+		if methodRetryable(policy, req.Method) {
+			maxAttempts += policy.MaxRetries
+		}
+
+		for attempt := 1; ; attempt++ {
+			attemptReq, cancel, err := e.newAttempt(req, e.requestTimeout(source))
+			if err != nil {
+				return nil, err
+			}
+
+			start := time.Now()
+			resp, err := next.RoundTrip(attemptReq)
+			record := Attempt{Number: attempt, Duration: time.Since(start), Error: err}
+			if resp != nil {
+				record.StatusCode = resp.StatusCode
+			}
+			recordAttempt(req.Context(), record)
+
+			if attempt >= maxAttempts || !shouldRetry(policy, resp, err) {
+				if err != nil {
+					cancel()
+					if attempt > 1 {
+						return nil, fmt.Errorf("%w (after %d attempts)", err, attempt)
+					}
+					return nil, err
+				}
+				resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+				return resp, nil
+			}
+
+			if resp != nil {
+				_, _ = io.Copy(io.Discard, resp.Body)
+				_ = resp.Body.Close()
+			}
+			cancel()
+
+			select {
+			case <-time.After(backoffDelay(policy, attempt)):
+			case <-req.Context().Done():
+				return nil, req.Context().Err()
+			}
+		}
+	})
+}
+
+// requestTimeout is the request's @timeout, or the executor's default.
+func (e *Executor) requestTimeout(req parser.HTTPRequest) time.Duration {
+	if req.Timeout > 0 {
+		return req.Timeout
+	}
+	return e.timeout
+}
+
+func (e *Executor) newAttempt(req *http.Request, timeout time.Duration) (*http.Request, context.CancelFunc, error) {
+	ctx, cancel := req.Context(), context.CancelFunc(func() {})
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	}
+	attemptReq := req.Clone(ctx)
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			cancel()
+			return nil, nil, err
+		}
+		attemptReq.Body = body
+	}
+	return attemptReq, cancel, nil
+}
+
+func methodRetryable(policy parser.RetryPolicy, method string) bool {
+	if len(policy.Methods) == 0 {
+		return idempotentMethods[method]
+	}
+	for _, m := range policy.Methods {
+		if m == "*" || m == method {
+			return true
+		}
+	}
+	return false
+}
+
+func shouldRetry(policy parser.RetryPolicy, resp *http.Response, err error) bool {
+	for _, cond := range policy.On {
+		switch cond {
+		case parser.RetryOnTimeout:
+			if err != nil && isTimeout(err) {
+				return true
+			}
+		case parser.RetryOnNetwork:
+			var netErr net.Error
+			if err != nil && !isTimeout(err) && errors.As(err, &netErr) {
+				return true
+			}
+		case parser.RetryOn5xx:
+			if err == nil && resp.StatusCode >= 500 && resp.StatusCode < 600 {
+				return true
+			}
+		case parser.RetryOn4xx:
+			if err == nil && resp.StatusCode >= 400 && resp.StatusCode < 500 {
+				return true
+			}
+		default:
+			if code, convErr := strconv.Atoi(cond); convErr == nil && err == nil && resp.StatusCode == code {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+func backoffDelay(policy parser.RetryPolicy, attempt int) time.Duration {
+	switch policy.Backoff {
+	case parser.BackoffNone:
+		return 0
+	case parser.BackoffConstant:
+		return policy.Delay
+	case parser.BackoffLinear:
+		return policy.Delay * time.Duration(attempt)
+	default:
+		return policy.Delay * time.Duration(1<<(attempt-1))
+	}
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
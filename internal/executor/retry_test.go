@@ -0,0 +1,192 @@
+package executor
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cassielabs/hrun/internal/parser"
+)
+
+func flakyServer(t *testing.T, failures int32, status int) (*httptest.Server, *int32) {
+	t.Helper()
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		body, _ := io.ReadAll(r.Body)
+		if n <= failures {
+			w.WriteHeader(status)
+			return
+		}
+		_, _ = w.Write(body)
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func TestRetry_RecoversFrom5xx(t *testing.T) {
+	server, calls := flakyServer(t, 2, http.StatusServiceUnavailable)
+
+	exec := New(5 * time.Second)
+	resp, err := exec.Execute(parser.HTTPRequest{
+		Method: "GET",
+		URL:    server.URL,
+		Retry:  &parser.RetryPolicy{MaxRetries: 3, Delay: time.Millisecond},
+	})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected final status 200, got %d", resp.StatusCode)
+	}
+	if *calls != 3 {
+		t.Errorf("Expected 3 calls, got %d", *calls)
+	}
+	if len(resp.Attempts) != 3 {
+		t.Fatalf("Expected 3 recorded attempts, got %d", len(resp.Attempts))
+	}
+	if resp.Attempts[0].StatusCode != http.StatusServiceUnavailable || resp.Attempts[2].StatusCode != http.StatusOK {
+		t.Errorf("Unexpected attempt outcomes: %+v", resp.Attempts)
+	}
+}
+
+func TestRetry_GivesUpAfterMaxRetries(t *testing.T) {
+	server, calls := flakyServer(t, 10, http.StatusBadGateway)
+
+	exec := New(5 * time.Second)
+	resp, err := exec.Execute(parser.HTTPRequest{
+		Method: "GET",
+		URL:    server.URL,
+		Retry:  &parser.RetryPolicy{MaxRetries: 2, Backoff: parser.BackoffNone},
+	})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusBadGateway {
+		t.Errorf("Expected last status 502, got %d", resp.StatusCode)
+	}
+	if *calls != 3 {
+		t.Errorf("Expected 3 calls, got %d", *calls)
+	}
+}
+
+func TestRetry_SkipsNonIdempotentMethods(t *testing.T) {
+	server, calls := flakyServer(t, 1, http.StatusServiceUnavailable)
+
+	exec := New(5*time.Second, WithRetries(3))
+	resp, err := exec.Execute(parser.HTTPRequest{Method: "POST", URL: server.URL, Body: "payload"})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected POST not to be retried, got status %d", resp.StatusCode)
+	}
+	if *calls != 1 {
+		t.Errorf("Expected 1 call, got %d", *calls)
+	}
+}
+
+func TestRetry_MethodOverrideResendsBody(t *testing.T) {
+	server, calls := flakyServer(t, 1, http.StatusServiceUnavailable)
+
+	exec := New(5 * time.Second)
+	resp, err := exec.Execute(parser.HTTPRequest{
+		Method: "POST",
+		URL:    server.URL,
+		Body:   "payload",
+		Retry:  &parser.RetryPolicy{MaxRetries: 1, Backoff: parser.BackoffNone, Methods: []string{"POST"}},
+	})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	if *calls != 2 {
+		t.Errorf("Expected 2 calls, got %d", *calls)
+	}
+	if resp.Body != "payload" {
+		t.Errorf("Expected body to be resent on retry, got %q", resp.Body)
+	}
+}
+
+func TestRetry_OnlyConfiguredConditions(t *testing.T) {
+	server, calls := flakyServer(t, 1, http.StatusTooManyRequests)
+
+	exec := New(5 * time.Second)
+	_, err := exec.Execute(parser.HTTPRequest{
+		Method: "GET",
+		URL:    server.URL,
+		Retry:  &parser.RetryPolicy{MaxRetries: 2, Backoff: parser.BackoffNone, On: []string{parser.RetryOn5xx}},
+	})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if *calls != 1 {
+		t.Errorf("Expected 429 not to be retried on 5xx policy, got %d calls", *calls)
+	}
+
+	_, err = exec.Execute(parser.HTTPRequest{
+		Method: "GET",
+		URL:    server.URL,
+		Retry:  &parser.RetryPolicy{MaxRetries: 2, Backoff: parser.BackoffNone, On: []string{"429"}},
+	})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if *calls != 2 {
+		t.Errorf("Expected explicit 429 condition to pass after one call, got %d calls", *calls)
+	}
+}
+
+func TestRetry_Timeout(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			time.Sleep(200 * time.Millisecond)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	exec := New(50 * time.Millisecond)
+	resp, err := exec.Execute(parser.HTTPRequest{
+		Method: "GET",
+		URL:    server.URL,
+		Retry:  &parser.RetryPolicy{MaxRetries: 1, Backoff: parser.BackoffNone, On: []string{parser.RetryOnTimeout}},
+	})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected second attempt to succeed, got %d", resp.StatusCode)
+	}
+	if len(resp.Attempts) != 2 || resp.Attempts[0].Error == nil {
+		t.Errorf("Expected first attempt to record a timeout, got %+v", resp.Attempts)
+	}
+}
+
+func TestBackoffDelay(t *testing.T) {
+	tests := []struct {
+		backoff  string
+		attempt  int
+		expected time.Duration
+	}{
+		{parser.BackoffNone, 3, 0},
+		{parser.BackoffConstant, 3, 100 * time.Millisecond},
+		{parser.BackoffLinear, 3, 300 * time.Millisecond},
+		{parser.BackoffExponential, 1, 100 * time.Millisecond},
+		{parser.BackoffExponential, 3, 400 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		policy := parser.RetryPolicy{Backoff: tt.backoff, Delay: 100 * time.Millisecond}
+		if got := backoffDelay(policy, tt.attempt); got != tt.expected {
+			t.Errorf("%s attempt %d: expected %v, got %v", tt.backoff, tt.attempt, tt.expected, got)
+		}
+	}
+}
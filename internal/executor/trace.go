@@ -0,0 +1,99 @@
+package executor
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Timings breaks the final attempt of a request down into the phases
+// browsers report. Phases that did not happen, such as DNS and connect on a
+// reused connection, are zero. Responses served without a network
+// connection, for example from a cassette, have no timings at all.
+type Timings struct {
+	Blocked time.Duration
+	DNS     time.Duration
+	Connect time.Duration
+	TLS     time.Duration
+	Send    time.Duration
+	Wait    time.Duration
+	Receive time.Duration
+}
+
+// IsZero reports whether no phase was measured.
+func (t Timings) IsZero() bool {
+	return t == Timings{}
+}
+
+// tracer collects connection events for the latest attempt of a request.
+type tracer struct {
+	mu                        sync.Mutex
+	getConn, gotConn          time.Time
+	dnsStart, dnsDone         time.Time
+	connectStart, connectDone time.Time
+	tlsStart, tlsDone         time.Time
+	wroteRequest, firstByte   time.Time
+}
+
+func withTrace(ctx context.Context) (context.Context, *tracer) {
+	t := &tracer{}
+	at := func(field *time.Time) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		*field = time.Now()
+	}
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GetConn: func(string) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			// Each retry starts over, so only the last attempt is reported.
+			t.gotConn, t.dnsStart, t.dnsDone = time.Time{}, time.Time{}, time.Time{}
+			t.connectStart, t.connectDone = time.Time{}, time.Time{}
+			t.tlsStart, t.tlsDone = time.Time{}, time.Time{}
+			t.wroteRequest, t.firstByte = time.Time{}, time.Time{}
+			t.getConn = time.Now()
+		},
+		GotConn:              func(httptrace.GotConnInfo) { at(&t.gotConn) },
+		DNSStart:             func(httptrace.DNSStartInfo) { at(&t.dnsStart) },
+		DNSDone:              func(httptrace.DNSDoneInfo) { at(&t.dnsDone) },
+		ConnectStart:         func(string, string) { at(&t.connectStart) },
+		ConnectDone:          func(string, string, error) { at(&t.connectDone) },
+		TLSHandshakeStart:    func() { at(&t.tlsStart) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { at(&t.tlsDone) },
+		WroteRequest:         func(httptrace.WroteRequestInfo) { at(&t.wroteRequest) },
+		GotFirstResponseByte: func() { at(&t.firstByte) },
+	}), t
+}
+
+// timings returns the phases measured so far, with Receive running up to
+// bodyRead.
+func (t *tracer) timings(bodyRead time.Time) Timings {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.getConn.IsZero() || t.firstByte.IsZero() {
+		return Timings{}
+	}
+
+	timings := Timings{
+		DNS:     between(t.dnsStart, t.dnsDone),
+		Connect: between(t.connectStart, t.connectDone),
+		TLS:     between(t.tlsStart, t.tlsDone),
+		Send:    between(t.gotConn, t.wroteRequest),
+		Wait:    between(t.wroteRequest, t.firstByte),
+		Receive: between(t.firstByte, bodyRead),
+	}
+	timings.Blocked = between(t.getConn, t.gotConn) - timings.DNS - timings.Connect - timings.TLS
+	if timings.Blocked < 0 {
+		timings.Blocked = 0
+	}
+	return timings
+}
+
+func between(start, end time.Time) time.Duration {
+	if start.IsZero() || end.Before(start) {
+		return 0
+	}
+	return end.Sub(start)
+}
@@ -0,0 +1,45 @@
+package executor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cassielabs/hrun/internal/parser"
+)
+
+func TestExecute_TimingsAndHook(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	var seen []string
+	exec := New(5*time.Second, WithExecuteHook(func(req parser.HTTPRequest, resp *Response) {
+		seen = append(seen, req.URL)
+		if resp.Error != nil {
+			seen = append(seen, "error")
+		}
+	}))
+
+	resp, err := exec.Execute(parser.HTTPRequest{Method: "GET", URL: server.URL})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if resp.Timings.Wait < 20*time.Millisecond {
+		t.Errorf("Expected wait to include server delay, got %v", resp.Timings.Wait)
+	}
+	if resp.Timings.Connect == 0 {
+		t.Errorf("Expected connect time on a new connection, got %+v", resp.Timings)
+	}
+	if resp.StartedAt.IsZero() || resp.Proto != "HTTP/1.1" {
+		t.Errorf("Expected start time and protocol, got %v %q", resp.StartedAt, resp.Proto)
+	}
+
+	_, _ = exec.Execute(parser.HTTPRequest{Method: "GET", URL: "http://127.0.0.1:1"})
+	if len(seen) != 3 || seen[0] != server.URL || seen[2] != "error" {
+		t.Errorf("Expected hook for each request including failures, got %v", seen)
+	}
+}
@@ -0,0 +1,218 @@
+package export
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/cassielabs/hrun/internal/parser"
+)
+
+const (
+	FormatCurl   = "curl"
+	FormatHTTPie = "httpie"
+	FormatWget   = "wget"
+)
+
+var Formats = []string{FormatCurl, FormatHTTPie, FormatWget}
+
+var (
+	envReferenceRegex = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+	referenceRegex    = regexp.MustCompile(`\{\{.+?\}\}`)
+	shellSafeRegex    = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+)
+
+// Command renders a request as a shell command in the given format.
+// Variables still present as {{name}} become ${name} environment references.
+func Command(format string, req parser.HTTPRequest) (string, error) {
+	switch format {
+	case FormatCurl:
+		return Curl(req), nil
+	case FormatHTTPie:
+		return HTTPie(req), nil
+	case FormatWget:
+		return Wget(req), nil
+	}
+	return "", fmt.Errorf("unknown export format %q (expected one of %s)", format, strings.Join(Formats, ", "))
+}
+
+// ApplyVariables resolves the request's variables where it can. Variables
+// that are not defined, such as ones captured from an earlier response, are
+// left as {{name}} so the exported command reads them from the environment.
+func ApplyVariables(req *parser.HTTPRequest, variables map[string]string) error {
+	var unresolved *parser.UnresolvedError
+	if err := req.ApplyVariables(variables); err != nil {
+		if !errors.As(err, &unresolved) {
+			return err
+		}
+		if reference := expressionReference(req); reference != "" {
+			return fmt.Errorf("%s cannot be resolved or read from the environment", reference)
+		}
+	}
+	return nil
+}
+
+// expressionReference returns the first {{...}} reference left in the
+// request that is not a plain {{name}}.
+func expressionReference(req *parser.HTTPRequest) string {
+	texts := []string{req.URL, req.Body}
+	for _, line := range headerLines(*req, ": ") {
+		texts = append(texts, line)
+	}
+	for _, text := range texts {
+		for _, reference := range referenceRegex.FindAllString(text, -1) {
+			if !envReferenceRegex.MatchString(reference) {
+				return reference
+			}
+		}
+	}
+	return ""
+}
+
+// ApplyEnvRefs resolves the fallbacks and filters in the request and leaves
+// plain {{name}} references to become environment references. Expressions
+// that need a variable that is not defined, such as a response reference,
+// cannot be written as a shell command and are an error.
+func ApplyEnvRefs(req *parser.HTTPRequest, variables map[string]string) error {
+	if err := req.ApplyExpressions(variables); err != nil {
+		return fmt.Errorf("%w: only plain {{name}} references can be read from the environment", err)
+	}
+	return nil
+}
+
+func Curl(req parser.HTTPRequest) string {
+	command := "curl"
+	switch req.Method {
+	case "GET", "":
+	case "HEAD":
+		command += " --head"
+	default:
+		command += " -X " + req.Method
+	}
+	command += " " + Quote(encodeURL(req.URL))
+
+	var options []string
+	for _, header := range headerLines(req, ": ") {
+		options = append(options, "-H "+Quote(header))
+	}
+	if body := requestBody(req); body != "" {
+		options = append(options, "--data-raw "+Quote(body))
+	}
+	return joinLines(command, options)
+}
+
+func HTTPie(req parser.HTTPRequest) string {
+	method := req.Method
+	if method == "" {
+		method = "GET"
+	}
+	command := "http"
+	if body := requestBody(req); body != "" {
+		command += " --raw " + Quote(body)
+	}
+	command += " " + method + " " + Quote(encodeURL(req.URL))
+
+	var options []string
+	for _, header := range headerLines(req, ":") {
+		options = append(options, Quote(header))
+	}
+	return joinLines(command, options)
+}
+
+func Wget(req parser.HTTPRequest) string {
+	command := "wget -qO-"
+	if req.Method != "" && req.Method != "GET" {
+		command += " --method=" + req.Method
+	}
+	command += " " + Quote(encodeURL(req.URL))
+
+	var options []string
+	for _, header := range headerLines(req, ": ") {
+		options = append(options, "--header="+Quote(header))
+	}
+	if body := requestBody(req); body != "" {
+		options = append(options, "--body-data="+Quote(body))
+	}
+	return joinLines(command, options)
+}
+
+// encodeURL percent-encodes the URL the way it is sent, with
+// parser.EncodeURL, keeping the {{name}} references left in it as written.
+func encodeURL(rawURL string) string {
+	references := referenceRegex.FindAllString(rawURL, -1)
+	if len(references) == 0 {
+		return parser.EncodeURL(rawURL)
+	}
+
+	marker := "hrun"
+	for strings.Contains(rawURL, marker) {
+		marker += "_"
+	}
+	i := 0
+	replaced := referenceRegex.ReplaceAllStringFunc(rawURL, func(string) string {
+		i++
+		return fmt.Sprintf("%s%d%s", marker, i-1, marker)
+	})
+	encoded := parser.EncodeURL(replaced)
+	for i, reference := range references {
+		encoded = strings.Replace(encoded, fmt.Sprintf("%s%d%s", marker, i, marker), reference, 1)
+	}
+	return encoded
+}
+
+// Quote makes text safe to paste into a POSIX shell. {{name}} references
+// are left outside the quotes as "${name}" so the shell expands them.
+func Quote(text string) string {
+	var parts []string
+	last := 0
+	for _, loc := range envReferenceRegex.FindAllStringSubmatchIndex(text, -1) {
+		if loc[0] > last {
+			parts = append(parts, quoteLiteral(text[last:loc[0]]))
+		}
+		parts = append(parts, `"${`+text[loc[2]:loc[3]]+`}"`)
+		last = loc[1]
+	}
+	if last < len(text) || len(parts) == 0 {
+		parts = append(parts, quoteLiteral(text[last:]))
+	}
+	return strings.Join(parts, "")
+}
+
+func quoteLiteral(text string) string {
+	if shellSafeRegex.MatchString(text) {
+		return text
+	}
+	return "'" + strings.ReplaceAll(text, "'", `'\''`) + "'"
+}
+
+// requestBody drops the blank lines that separate a body from the next
+// request in the .http file.
+func requestBody(req parser.HTTPRequest) string {
+	return strings.TrimRight(req.Body, "\r\n")
+}
+
+func headerLines(req parser.HTTPRequest, separator string) []string {
+	names := make([]string, 0, len(req.Headers))
+	for name := range req.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var lines []string
+	for _, name := range names {
+		for _, value := range req.Headers[name] {
+			lines = append(lines, name+separator+value)
+		}
+	}
+	return lines
+}
+
+// joinLines puts every option on its own continuation line.
+func joinLines(command string, options []string) string {
+	for _, option := range options {
+		command += " \\\n  " + option
+	}
+	return command
+}
@@ -0,0 +1,186 @@
+package export
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/cassielabs/hrun/internal/importer"
+	"github.com/cassielabs/hrun/internal/parser"
+)
+
+func TestQuote(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{input: "https://api.example.com/users", expected: "https://api.example.com/users"},
+		{input: "", expected: "''"},
+		{input: "a b", expected: "'a b'"},
+		{input: "it's", expected: `'it'\''s'`},
+		{input: "$HOME `id`", expected: "'$HOME `id`'"},
+		{input: "{{baseUrl}}/users?q=a b", expected: `"${baseUrl}"'/users?q=a b'`},
+		{input: "Bearer {{ token }}", expected: `'Bearer '"${token}"`},
+		{input: "{{X-Tenant}}", expected: "'{{X-Tenant}}'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := Quote(tt.input); got != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestCommand(t *testing.T) {
+	req := parser.HTTPRequest{
+		Method: "POST",
+		URL:    "https://api.example.com/users",
+		Headers: http.Header{
+			"Content-Type":  []string{"application/json"},
+			"Authorization": []string{"Bearer {{token}}"},
+		},
+		Body: `{"name": "O'Brien"}`,
+	}
+
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{
+			format: FormatCurl,
+			expected: "curl -X POST https://api.example.com/users \\\n" +
+				"  -H 'Authorization: Bearer '\"${token}\" \\\n" +
+				"  -H 'Content-Type: application/json' \\\n" +
+				"  --data-raw '{\"name\": \"O'\\''Brien\"}'",
+		},
+		{
+			format: FormatHTTPie,
+			expected: "http --raw '{\"name\": \"O'\\''Brien\"}' POST https://api.example.com/users \\\n" +
+				"  'Authorization:Bearer '\"${token}\" \\\n" +
+				"  Content-Type:application/json",
+		},
+		{
+			format: FormatWget,
+			expected: "wget -qO- --method=POST https://api.example.com/users \\\n" +
+				"  --header='Authorization: Bearer '\"${token}\" \\\n" +
+				"  --header='Content-Type: application/json' \\\n" +
+				"  --body-data='{\"name\": \"O'\\''Brien\"}'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			got, err := Command(tt.format, req)
+			if err != nil {
+				t.Fatalf("Command failed: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("Expected:\n%s\ngot:\n%s", tt.expected, got)
+			}
+		})
+	}
+
+	if _, err := Command("powershell", req); err == nil {
+		t.Error("Expected error for unknown format")
+	}
+}
+
+func TestCurl_RoundTrip(t *testing.T) {
+	req := parser.HTTPRequest{
+		Method:  "PUT",
+		URL:     "https://api.example.com/items/1?tag=a&b=c",
+		Headers: http.Header{"Content-Type": []string{"application/json"}},
+		Body:    "{\n  \"note\": \"it's $5\"\n}",
+	}
+
+	file, err := importer.Curl(Curl(req))
+	if err != nil {
+		t.Fatalf("Exported command does not import: %v", err)
+	}
+	got := file.Requests[0]
+	if got.Method != req.Method || got.URL != req.URL || got.Body != req.Body || got.Headers.Get("Content-Type") != "application/json" {
+		t.Errorf("Expected %+v, got %+v", req, got)
+	}
+}
+
+func TestApplyVariables_Captured(t *testing.T) {
+	httpFile, err := parser.ParseString(`@baseUrl = https://api.example.com
+
+### login
+# @capture token = token
+POST {{baseUrl}}/login
+
+### profile
+GET {{baseUrl}}/me
+Authorization: Bearer {{token}}
+`)
+	if err != nil {
+		t.Fatalf("ParseString failed: %v", err)
+	}
+
+	req := httpFile.Requests[1]
+	req.Headers = req.Headers.Clone()
+	if err := ApplyVariables(&req, httpFile.Variables); err != nil {
+		t.Fatalf("ApplyVariables failed: %v", err)
+	}
+
+	expected := "curl https://api.example.com/me \\\n" +
+		"  -H 'Authorization: Bearer '\"${token}\""
+	if got := Curl(req); got != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, got)
+	}
+
+	reference := parser.HTTPRequest{Method: "GET", URL: "https://api.example.com/{{login.response.body.$.id}}"}
+	if err := ApplyVariables(&reference, nil); err == nil {
+		t.Error("Expected a response reference to fail")
+	}
+
+	invalid := parser.HTTPRequest{Method: "GET", URL: "https://api.example.com/{{a | nope}}"}
+	if err := ApplyVariables(&invalid, nil); err == nil {
+		t.Error("Expected an invalid reference to fail")
+	}
+}
+
+func TestApplyEnvRefs(t *testing.T) {
+	req := parser.HTTPRequest{Method: "GET", URL: `{{baseUrl}}/me?q={{q ?? "rock & roll"}}`}
+	if err := ApplyEnvRefs(&req, map[string]string{"baseUrl": "https://api.example.com"}); err != nil {
+		t.Fatalf("ApplyEnvRefs failed: %v", err)
+	}
+	if got, expected := Curl(req), `curl "${baseUrl}"'/me?q=rock+%26+roll'`; got != expected {
+		t.Errorf("Expected %s, got %s", expected, got)
+	}
+
+	req = parser.HTTPRequest{Method: "GET", URL: "https://api.example.com/{{login.response.body.$.id}}"}
+	err := ApplyEnvRefs(&req, nil)
+	if err == nil || !strings.Contains(err.Error(), "only plain {{name}} references") {
+		t.Errorf("Expected a response reference to be rejected, got %v", err)
+	}
+}
+
+func TestCommand_EncodesURL(t *testing.T) {
+	tests := []struct {
+		url      string
+		expected string
+	}{
+		{url: "https://x.io/search?q=rock roll&city=Zürich", expected: "'https://x.io/search?q=rock%20roll&city=Z%C3%BCrich'"},
+		{url: "{{baseUrl}}/search?q=a b&id={{ id }}", expected: `"${baseUrl}"'/search?q=a%20b&id='"${id}"`},
+		{url: "https://x.io/a%20b?tag=a&b=c", expected: "'https://x.io/a%20b?tag=a&b=c'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			req := parser.HTTPRequest{Method: "GET", URL: tt.url}
+			for _, format := range Formats {
+				got, err := Command(format, req)
+				if err != nil {
+					t.Fatalf("Command failed: %v", err)
+				}
+				if !strings.Contains(got, " "+tt.expected) {
+					t.Errorf("Expected %s in %s command, got %s", tt.expected, format, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package har
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cassielabs/hrun/internal/executor"
+	"github.com/cassielabs/hrun/internal/parser"
+)
+
+func TestRecorder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"id":1}`))
+	}))
+	defer server.Close()
+
+	recorder := NewRecorder()
+	exec := executor.New(5*time.Second, executor.WithExecuteHook(recorder.Hook()))
+
+	headers := make(http.Header)
+	headers.Set("Content-Type", "application/json")
+	if _, err := exec.Execute(parser.HTTPRequest{Method: "POST", URL: server.URL + "/users?tag=a%20b", Headers: headers, Body: `{"name":"Ada"}`}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	_, _ = exec.Execute(parser.HTTPRequest{Method: "GET", URL: "http://127.0.0.1:1/down"})
+
+	path := filepath.Join(t.TempDir(), "out.har")
+	if err := recorder.Save(path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	h, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if h.Log.Version != "1.2" || len(h.Log.Entries) != 2 {
+		t.Fatalf("Expected a 1.2 log with 2 entries, got %q with %d", h.Log.Version, len(h.Log.Entries))
+	}
+
+	entry := h.Log.Entries[0]
+	if entry.Request.Method != "POST" || entry.Request.PostData == nil || entry.Request.PostData.Text != `{"name":"Ada"}` {
+		t.Errorf("Unexpected request %+v", entry.Request)
+	}
+	if len(entry.Request.QueryString) != 1 || entry.Request.QueryString[0].Value != "a b" {
+		t.Errorf("Expected decoded query string, got %+v", entry.Request.QueryString)
+	}
+	if entry.Response.Status != 201 || entry.Response.StatusText != "Created" || entry.Response.Content.Text != `{"id":1}` {
+		t.Errorf("Unexpected response %+v", entry.Response)
+	}
+	if entry.Response.Content.MimeType != "application/json" || entry.Response.HTTPVersion != "HTTP/1.1" {
+		t.Errorf("Unexpected content type or version: %+v", entry.Response)
+	}
+	if entry.Time <= 0 || entry.Timings.Connect <= 0 || entry.Timings.SSL != -1 {
+		t.Errorf("Expected measured timings, got %v %+v", entry.Time, entry.Timings)
+	}
+	if _, err := time.Parse(time.RFC3339, entry.StartedDateTime); err != nil {
+		t.Errorf("Expected ISO 8601 start time, got %q", entry.StartedDateTime)
+	}
+
+	failed := h.Log.Entries[1]
+	if failed.Response.Status != 0 || failed.Response.Error == "" {
+		t.Errorf("Expected failed request to be recorded with its error, got %+v", failed.Response)
+	}
+}
+
+func TestRecorder_SentHeaders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	recorder := NewRecorder()
+	exec := executor.New(5*time.Second, executor.WithExecuteHook(recorder.Hook()), executor.WithMiddleware(executor.HeaderMiddleware(http.Header{"X-Client": {"hrun"}})))
+	req := parser.HTTPRequest{Method: "POST", URL: server.URL + "/users", Headers: make(http.Header), Body: `{"name":"Ada"}`}
+	if _, err := exec.Execute(req); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	entry := recorder.har.Log.Entries[0]
+	headers := make(map[string]string)
+	for _, header := range entry.Request.Headers {
+		headers[header.Name] = header.Value
+	}
+	if headers["Content-Type"] != "application/json" || headers["X-Client"] != "hrun" {
+		t.Errorf("Expected the headers added while sending, got %+v", entry.Request.Headers)
+	}
+	if entry.Request.PostData == nil || entry.Request.PostData.MimeType != "application/json" {
+		t.Errorf("Expected the detected content type on the post data, got %+v", entry.Request.PostData)
+	}
+	if len(req.Headers) != 0 {
+		t.Errorf("Expected the parsed headers to be left alone, got %v", req.Headers)
+	}
+}
+
+func TestNewEntry_WithoutTimings(t *testing.T) {
+	resp := &executor.Response{StatusCode: 200, Status: "200 OK", Duration: 1500 * time.Microsecond, StartedAt: time.Now()}
+	entry := NewEntry(parser.HTTPRequest{Method: "GET", URL: "http://example.com"}, resp)
+
+	if entry.Time != 1.5 || entry.Timings.Wait != 1.5 || entry.Timings.Blocked != -1 {
+		t.Errorf("Expected replayed responses to report all time as wait, got %v %+v", entry.Time, entry.Timings)
+	}
+}
+
+func TestRecorder_MaskWith(t *testing.T) {
+	recorder := NewRecorder()
+	recorder.MaskWith(func(s string) string { return strings.ReplaceAll(s, "s3cr3t", "••••") })
+
+	headers := http.Header{"Authorization": {"Bearer s3cr3t"}}
+	resp := &executor.Response{StatusCode: 200, Status: "200 OK", Body: `{"token":"s3cr3t"}`, StartedAt: time.Now()}
+	recorder.Hook()(parser.HTTPRequest{Method: "POST", URL: "http://example.com/?key=s3cr3t", Headers: headers, Body: "s3cr3t"}, resp)
+
+	path := filepath.Join(t.TempDir(), "out.har")
+	if err := recorder.Save(path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	if strings.Contains(string(data), "s3cr3t") {
+		t.Errorf("Expected the secret to be masked everywhere, got %s", data)
+	}
+	if strings.Count(string(data), "••••") != 5 {
+		t.Errorf("Expected URL, query, header, body and content to be masked, got %s", data)
+	}
+}
@@ -0,0 +1,227 @@
+package har
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/cassielabs/hrun/internal/executor"
+	"github.com/cassielabs/hrun/internal/parser"
+)
+
+// timeFormat is ISO 8601 with milliseconds, fixed-width so that entries
+// sort as strings.
+const timeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// Recorder collects every executed request into a HAR log.
+type Recorder struct {
+	mu   sync.Mutex
+	har  HAR
+	mask func(string) string
+}
+
+func NewRecorder() *Recorder {
+	return &Recorder{har: HAR{Log: Log{
+		Version: "1.2",
+		Creator: Creator{Name: "hrun", Version: "dev"},
+		Entries: []Entry{},
+	}}}
+}
+
+// Hook returns an executor hook that records each request and response.
+func (r *Recorder) Hook() executor.ExecuteHook {
+	return func(req parser.HTTPRequest, resp *executor.Response) {
+		entry := NewEntry(req, resp)
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.har.Log.Entries = append(r.har.Log.Entries, entry)
+	}
+}
+
+// MaskWith sets a function applied to URLs, headers, cookies, bodies and
+// errors when saving, such as one hiding secret values.
+func (r *Recorder) MaskWith(mask func(string) string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.mask = mask
+}
+
+// Save writes the entries recorded so far, in the order they started.
+func (r *Recorder) Save(path string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sort.SliceStable(r.har.Log.Entries, func(i, j int) bool {
+		return r.har.Log.Entries[i].StartedDateTime < r.har.Log.Entries[j].StartedDateTime
+	})
+	if r.mask == nil {
+		return r.har.Save(path)
+	}
+
+	masked := r.har
+	masked.Log.Entries = make([]Entry, len(r.har.Log.Entries))
+	for i, entry := range r.har.Log.Entries {
+		masked.Log.Entries[i] = maskEntry(entry, r.mask)
+	}
+	return masked.Save(path)
+}
+
+// maskEntry returns a copy of entry with mask applied to every field that
+// can carry a variable's value. Base64-encoded content is left as is.
+func maskEntry(entry Entry, mask func(string) string) Entry {
+	maskPairs := func(pairs []NameValue) []NameValue {
+		masked := make([]NameValue, len(pairs))
+		for i, pair := range pairs {
+			masked[i] = NameValue{Name: pair.Name, Value: mask(pair.Value)}
+		}
+		return masked
+	}
+	maskCookies := func(cookies []Cookie) []Cookie {
+		masked := make([]Cookie, len(cookies))
+		for i, cookie := range cookies {
+			masked[i] = Cookie{Name: cookie.Name, Value: mask(cookie.Value)}
+		}
+		return masked
+	}
+
+	entry.Request.URL = mask(entry.Request.URL)
+	entry.Request.Headers = maskPairs(entry.Request.Headers)
+	entry.Request.Cookies = maskCookies(entry.Request.Cookies)
+	entry.Request.QueryString = maskPairs(entry.Request.QueryString)
+	if entry.Request.PostData != nil {
+		postData := *entry.Request.PostData
+		postData.Text = mask(postData.Text)
+		postData.Params = make([]PostParam, len(entry.Request.PostData.Params))
+		for i, param := range entry.Request.PostData.Params {
+			param.Value = mask(param.Value)
+			postData.Params[i] = param
+		}
+		entry.Request.PostData = &postData
+	}
+
+	entry.Response.Headers = maskPairs(entry.Response.Headers)
+	entry.Response.Cookies = maskCookies(entry.Response.Cookies)
+	entry.Response.RedirectURL = mask(entry.Response.RedirectURL)
+	entry.Response.Error = mask(entry.Response.Error)
+	if entry.Response.Content.Encoding == "" {
+		entry.Response.Content.Text = mask(entry.Response.Content.Text)
+	}
+	return entry
+}
+
+// NewEntry converts an executed request into a HAR entry. Its headers are
+// the ones the request was sent with, falling back to the parsed ones when
+// it was never sent. When the response has no connection timings, the
+// whole duration is reported as waiting.
+func NewEntry(req parser.HTTPRequest, resp *executor.Response) Entry {
+	headers := resp.RequestHeaders
+	if headers == nil {
+		headers = req.Headers
+	}
+	entry := Entry{
+		StartedDateTime: resp.StartedAt.UTC().Format(timeFormat),
+		Time:            milliseconds(resp.Duration),
+		Request: Request{
+			Method:      req.Method,
+			URL:         parser.EncodeURL(req.URL),
+			HTTPVersion: "HTTP/1.1",
+			Cookies:     []Cookie{},
+			Headers:     nameValues(headers),
+			QueryString: []NameValue{},
+			HeadersSize: -1,
+			BodySize:    len(req.Body),
+		},
+		Response: Response{
+			Status:      resp.StatusCode,
+			HTTPVersion: resp.Proto,
+			Cookies:     []Cookie{},
+			Headers:     nameValues(resp.Headers),
+			Content: Content{
+				Size:     len(resp.Body),
+				MimeType: resp.Headers.Get("Content-Type"),
+			},
+			RedirectURL: resp.Headers.Get("Location"),
+			HeadersSize: -1,
+			BodySize:    len(resp.Body),
+		},
+		Timings: Timings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1},
+	}
+
+	if u, err := url.Parse(entry.Request.URL); err == nil {
+		for _, pair := range strings.Split(u.RawQuery, "&") {
+			if pair == "" {
+				continue
+			}
+			key, value, _ := strings.Cut(pair, "=")
+			if unescaped, err := url.QueryUnescape(key); err == nil {
+				key = unescaped
+			}
+			if unescaped, err := url.QueryUnescape(value); err == nil {
+				value = unescaped
+			}
+			entry.Request.QueryString = append(entry.Request.QueryString, NameValue{Name: key, Value: value})
+		}
+	}
+	if req.Body != "" {
+		entry.Request.PostData = &PostData{MimeType: headers.Get("Content-Type"), Text: req.Body}
+	}
+
+	if _, text, ok := strings.Cut(resp.Status, " "); ok {
+		entry.Response.StatusText = text
+	}
+	if entry.Response.HTTPVersion == "" {
+		entry.Response.HTTPVersion = "HTTP/1.1"
+	}
+	if utf8.ValidString(resp.Body) {
+		entry.Response.Content.Text = resp.Body
+	} else {
+		entry.Response.Content.Text = base64.StdEncoding.EncodeToString([]byte(resp.Body))
+		entry.Response.Content.Encoding = "base64"
+	}
+	if resp.Error != nil {
+		entry.Response.Error = resp.Error.Error()
+	}
+
+	t := resp.Timings
+	if t.IsZero() {
+		entry.Timings.Wait = entry.Time
+		return entry
+	}
+	entry.Timings.Blocked = milliseconds(t.Blocked)
+	if t.DNS > 0 {
+		entry.Timings.DNS = milliseconds(t.DNS)
+	}
+	if t.Connect > 0 {
+		entry.Timings.Connect = milliseconds(t.Connect + t.TLS)
+	}
+	if t.TLS > 0 {
+		entry.Timings.SSL = milliseconds(t.TLS)
+	}
+	entry.Timings.Send = milliseconds(t.Send)
+	entry.Timings.Wait = milliseconds(t.Wait)
+	entry.Timings.Receive = milliseconds(t.Receive)
+	return entry
+}
+
+func nameValues(headers http.Header) []NameValue {
+	keys := make([]string, 0, len(headers))
+	for key := range headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	values := []NameValue{}
+	for _, key := range keys {
+		for _, value := range headers[key] {
+			values = append(values, NameValue{Name: key, Value: value})
+		}
+	}
+	return values
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
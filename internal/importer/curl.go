@@ -0,0 +1,460 @@
+package importer
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/cassielabs/hrun/internal/parser"
+)
+
+// formBoundary is fixed so that imports are reproducible.
+const formBoundary = "hrun-form-boundary"
+
+// curlValueFlags are the options that take an argument but do not affect
+// the request. They are skipped together with their value.
+var curlValueFlags = map[string]bool{
+	"-o": true, "--output": true, "-m": true, "--max-time": true, "--connect-timeout": true,
+	"--retry": true, "--retry-delay": true, "-w": true, "--write-out": true, "-x": true,
+	"--proxy": true, "--cacert": true, "--capath": true, "-E": true, "--cert": true,
+	"--key": true, "-c": true, "--cookie-jar": true, "--resolve": true, "--interface": true,
+	"-r": true, "--range": true, "--limit-rate": true, "-D": true, "--dump-header": true,
+}
+
+// Curl converts one or more curl command lines into requests. Commands are
+// separated by newlines; backslash line continuations are joined first.
+func Curl(input string) (*parser.HTTPFile, error) {
+	commands, err := splitShell(input)
+	if err != nil {
+		return nil, err
+	}
+
+	file := &parser.HTTPFile{Variables: make(map[string]string)}
+	for _, args := range commands {
+		if filepath.Base(args[0]) != "curl" {
+			return nil, fmt.Errorf("expected a curl command, got %q", args[0])
+		}
+		req, err := curlRequest(args[1:])
+		if err != nil {
+			return nil, err
+		}
+		file.Requests = append(file.Requests, req)
+	}
+	if len(file.Requests) == 0 {
+		return nil, fmt.Errorf("no curl commands found")
+	}
+	return file, nil
+}
+
+func curlRequest(args []string) (parser.HTTPRequest, error) {
+	req := parser.HTTPRequest{Headers: make(http.Header)}
+	var (
+		rawURL     string
+		data       []string
+		form       []formField
+		getData    bool
+		head       bool
+		compressed bool
+		insecure   bool
+	)
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			rawURL = arg
+			continue
+		}
+
+		name, value, hasValue := arg, "", false
+		if !strings.HasPrefix(arg, "--") && len(arg) > 2 {
+			// Short options can be combined (-sSL) and carry their value
+			// (-XPOST, -sXPOST).
+			flags := arg[1:]
+			name = ""
+			for j, flag := range flags {
+				short := "-" + string(flag)
+				if curlTakesValue(short) {
+					name = short
+					if rest := flags[j+1:]; rest != "" {
+						value, hasValue = rest, true
+					}
+					break
+				}
+				switch flag {
+				case 'I':
+					head = true
+				case 'G':
+					getData = true
+				case 'k':
+					insecure = true
+				}
+			}
+			if name == "" {
+				continue
+			}
+		}
+		if !hasValue && curlTakesValue(name) {
+			if i+1 >= len(args) {
+				return req, fmt.Errorf("option %s needs a value", name)
+			}
+			i++
+			value = args[i]
+		}
+
+		switch name {
+		case "-X", "--request":
+			req.Method = strings.ToUpper(value)
+		case "-H", "--header":
+			key, headerValue, ok := strings.Cut(value, ":")
+			if !ok {
+				return req, fmt.Errorf("invalid header %q", value)
+			}
+			req.Headers.Add(strings.TrimSpace(key), strings.TrimSpace(headerValue))
+		case "-d", "--data", "--data-ascii", "--data-binary":
+			if strings.HasPrefix(value, "@") {
+				content, err := readCurlFile(value[1:])
+				if err != nil {
+					return req, err
+				}
+				if name != "--data-binary" {
+					content = strings.NewReplacer("\r", "", "\n", "").Replace(content)
+				}
+				value = content
+			}
+			data = append(data, value)
+		case "--data-raw":
+			data = append(data, value)
+		case "--data-urlencode":
+			encoded, err := curlURLEncode(value)
+			if err != nil {
+				return req, err
+			}
+			data = append(data, encoded)
+		case "--json":
+			data = append(data, value)
+			setDefaultHeader(req.Headers, "Content-Type", "application/json")
+			setDefaultHeader(req.Headers, "Accept", "application/json")
+		case "-F", "--form", "--form-string":
+			form = append(form, formField{value: value, literal: name == "--form-string"})
+		case "-u", "--user":
+			req.Headers.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(value)))
+		case "-A", "--user-agent":
+			req.Headers.Set("User-Agent", value)
+		case "-e", "--referer":
+			req.Headers.Set("Referer", value)
+		case "-b", "--cookie":
+			req.Headers.Add("Cookie", value)
+		case "--url":
+			rawURL = value
+		case "-G", "--get":
+			getData = true
+		case "-I", "--head":
+			head = true
+		case "--compressed":
+			compressed = true
+		case "-k", "--insecure":
+			insecure = true
+		}
+	}
+
+	if rawURL == "" {
+		return req, fmt.Errorf("curl command has no URL")
+	}
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "http://" + rawURL
+	}
+	req.URL = rawURL
+
+	switch {
+	case len(form) > 0:
+		body, err := multipartBody(form)
+		if err != nil {
+			return req, err
+		}
+		req.Body = body
+		req.Headers.Set("Content-Type", "multipart/form-data; boundary="+formBoundary)
+	case len(data) > 0 && getData:
+		separator := "?"
+		if strings.Contains(req.URL, "?") {
+			separator = "&"
+		}
+		req.URL += separator + strings.Join(data, "&")
+	case len(data) > 0:
+		req.Body = strings.Join(data, "&")
+		setDefaultHeader(req.Headers, "Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	// hrun negotiates and decodes compression itself; sending the header
+	// explicitly would hand back the raw compressed body.
+	if compressed {
+		req.Headers.Del("Accept-Encoding")
+	}
+
+	// hrun always verifies certificates, so say so rather than losing the
+	// option without a trace.
+	if insecure {
+		req.Description = insecureNote
+	}
+
+	if req.Method == "" {
+		switch {
+		case head:
+			req.Method = "HEAD"
+		case req.Body != "":
+			req.Method = "POST"
+		default:
+			req.Method = "GET"
+		}
+	}
+	return req, nil
+}
+
+// insecureNote describes a request imported from a command run with -k.
+const insecureNote = "insecure: TLS verification disabled in the original command"
+
+func curlTakesValue(name string) bool {
+	switch name {
+	case "-X", "--request", "-H", "--header", "-d", "--data", "--data-ascii", "--data-binary",
+		"--data-raw", "--data-urlencode", "--json", "-F", "--form", "--form-string", "-u", "--user",
+		"-A", "--user-agent", "-e", "--referer", "-b", "--cookie", "--url":
+		return true
+	}
+	return curlValueFlags[name]
+}
+
+func setDefaultHeader(headers http.Header, key, value string) {
+	if headers.Get(key) == "" {
+		headers.Set(key, value)
+	}
+}
+
+func readCurlFile(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return string(content), nil
+}
+
+// curlURLEncode follows --data-urlencode: "name=value" encodes the value,
+// "name@file" encodes the file's content and a bare value is encoded whole.
+func curlURLEncode(value string) (string, error) {
+	if name, content, ok := strings.Cut(value, "="); ok {
+		if name == "" {
+			return url.QueryEscape(content), nil
+		}
+		return name + "=" + url.QueryEscape(content), nil
+	}
+	if name, path, ok := strings.Cut(value, "@"); ok {
+		content, err := readCurlFile(path)
+		if err != nil {
+			return "", err
+		}
+		if name == "" {
+			return url.QueryEscape(content), nil
+		}
+		return name + "=" + url.QueryEscape(content), nil
+	}
+	return url.QueryEscape(value), nil
+}
+
+type formField struct {
+	value string
+	// literal fields come from --form-string and never read files.
+	literal bool
+}
+
+// multipartBody builds a multipart/form-data body from -F fields. Files
+// given as name=@path are embedded; name=<path sends a file as a plain value.
+func multipartBody(fields []formField) (string, error) {
+	var b strings.Builder
+	for _, field := range fields {
+		name, value, ok := strings.Cut(field.value, "=")
+		if !ok {
+			return "", fmt.Errorf("invalid form field %q", field.value)
+		}
+
+		var contentType, filename string
+		if !field.literal && (strings.HasPrefix(value, "@") || strings.HasPrefix(value, "<")) {
+			parts := strings.Split(value[1:], ";")
+			path := parts[0]
+			for _, option := range parts[1:] {
+				key, optionValue, _ := strings.Cut(option, "=")
+				switch strings.TrimSpace(key) {
+				case "type":
+					contentType = optionValue
+				case "filename":
+					filename = optionValue
+				}
+			}
+			content, err := readCurlFile(path)
+			if err != nil {
+				return "", err
+			}
+			if value[0] == '@' {
+				if filename == "" {
+					filename = filepath.Base(path)
+				}
+				if contentType == "" {
+					contentType = "application/octet-stream"
+				}
+			}
+			value = content
+		}
+
+		b.WriteString("--" + formBoundary + "\n")
+		disposition := "Content-Disposition: form-data; name=" + strconv.Quote(name)
+		if filename != "" {
+			disposition += "; filename=" + strconv.Quote(filename)
+		}
+		b.WriteString(disposition + "\n")
+		if contentType != "" {
+			b.WriteString("Content-Type: " + contentType + "\n")
+		}
+		b.WriteString("\n" + value + "\n")
+	}
+	b.WriteString("--" + formBoundary + "--")
+	return b.String(), nil
+}
+
+// splitShell splits text into commands of words using POSIX shell quoting,
+// plus the $'...' strings browsers emit when copying as cURL.
+func splitShell(input string) ([][]string, error) {
+	var (
+		commands [][]string
+		words    []string
+		word     strings.Builder
+		inWord   bool
+	)
+	endWord := func() {
+		if inWord {
+			words = append(words, word.String())
+			word.Reset()
+			inWord = false
+		}
+	}
+	endCommand := func() {
+		endWord()
+		if len(words) > 0 {
+			commands = append(commands, words)
+			words = nil
+		}
+	}
+
+	runes := []rune(input)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '\\':
+			if i+1 < len(runes) && runes[i+1] == '\r' {
+				i++
+			}
+			if i+1 < len(runes) && runes[i+1] == '\n' {
+				i++
+				continue
+			}
+			if i+1 < len(runes) {
+				i++
+				word.WriteRune(runes[i])
+				inWord = true
+			}
+		case r == '\'':
+			end := indexRune(runes, i+1, '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated single quote")
+			}
+			word.WriteString(string(runes[i+1 : end]))
+			inWord = true
+			i = end
+		case r == '$' && i+1 < len(runes) && runes[i+1] == '\'':
+			next, err := ansiCString(runes, i+2, &word)
+			if err != nil {
+				return nil, err
+			}
+			inWord = true
+			i = next
+		case r == '"':
+			i++
+			for ; i < len(runes) && runes[i] != '"'; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) && strings.ContainsRune("$`\"\\\n", runes[i+1]) {
+					i++
+					if runes[i] == '\n' {
+						continue
+					}
+				}
+				word.WriteRune(runes[i])
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("unterminated double quote")
+			}
+			inWord = true
+		case r == '\n' || r == ';':
+			endCommand()
+		case r == ' ' || r == '\t' || r == '\r':
+			endWord()
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	endCommand()
+	return commands, nil
+}
+
+func indexRune(runes []rune, from int, target rune) int {
+	for i := from; i < len(runes); i++ {
+		if runes[i] == target {
+			return i
+		}
+	}
+	return -1
+}
+
+// ansiCString decodes a $'...' string starting after the opening quote and
+// returns the index of the closing quote.
+func ansiCString(runes []rune, start int, word *strings.Builder) (int, error) {
+	for i := start; i < len(runes); i++ {
+		r := runes[i]
+		if r == '\'' {
+			return i, nil
+		}
+		if r != '\\' || i+1 >= len(runes) {
+			word.WriteRune(r)
+			continue
+		}
+		i++
+		switch runes[i] {
+		case 'n':
+			word.WriteByte('\n')
+		case 't':
+			word.WriteByte('\t')
+		case 'r':
+			word.WriteByte('\r')
+		case 'x', 'u', 'U':
+			size := map[rune]int{'x': 2, 'u': 4, 'U': 8}[runes[i]]
+			end := i + 1
+			for end < len(runes) && end-i-1 < size && strings.ContainsRune("0123456789abcdefABCDEF", runes[end]) {
+				end++
+			}
+			code, err := strconv.ParseUint(string(runes[i+1:end]), 16, 32)
+			if err != nil {
+				return 0, fmt.Errorf("invalid escape in $'...' string")
+			}
+			if runes[i] == 'x' {
+				word.WriteByte(byte(code))
+			} else if utf8.ValidRune(rune(code)) {
+				word.WriteRune(rune(code))
+			}
+			i = end - 1
+		default:
+			word.WriteRune(runes[i])
+		}
+	}
+	return 0, fmt.Errorf("unterminated $'...' string")
+}
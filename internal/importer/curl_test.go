@@ -0,0 +1,161 @@
+package importer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCurl(t *testing.T) {
+	dir := t.TempDir()
+	payload := filepath.Join(dir, "payload.json")
+	if err := os.WriteFile(payload, []byte("{\"a\": 1}\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write payload: %v", err)
+	}
+	avatar := filepath.Join(dir, "avatar.png")
+	if err := os.WriteFile(avatar, []byte("PNG"), 0o644); err != nil {
+		t.Fatalf("Failed to write avatar: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		input    string
+		method   string
+		url      string
+		headers  map[string]string
+		body     string
+		contains []string
+		// description is the request's description comment.
+		description string
+	}{
+		{
+			name:   "Plain GET",
+			input:  `curl https://api.example.com/users`,
+			method: "GET",
+			url:    "https://api.example.com/users",
+		},
+		{
+			name: "Devtools copy with continuations",
+			input: "curl 'https://api.example.com/users' \\\n" +
+				"  -H 'accept: application/json' \\\n" +
+				"  -H 'accept-encoding: gzip, deflate, br' \\\n" +
+				"  --data-raw $'{\"name\":\"O\\'Brien\\\\n\"}' \\\n" +
+				"  --compressed",
+			method:  "POST",
+			url:     "https://api.example.com/users",
+			headers: map[string]string{"Accept": "application/json", "Accept-Encoding": "", "Content-Type": "application/x-www-form-urlencoded"},
+			body:    `{"name":"O'Brien\n"}`,
+		},
+		{
+			name:        "Method, basic auth and insecure",
+			input:       `curl -k -X DELETE -u admin:secret "http://localhost:8080/items/1"`,
+			method:      "DELETE",
+			url:         "http://localhost:8080/items/1",
+			headers:     map[string]string{"Authorization": "Basic YWRtaW46c2VjcmV0"},
+			description: insecureNote,
+		},
+		{
+			name:        "Insecure long option",
+			input:       `curl --insecure https://x.io/a`,
+			method:      "GET",
+			url:         "https://x.io/a",
+			description: insecureNote,
+		},
+		{
+			name:        "Insecure combined short options",
+			input:       `curl -skL https://x.io/a`,
+			method:      "GET",
+			url:         "https://x.io/a",
+			description: insecureNote,
+		},
+		{
+			name:    "Combined short options",
+			input:   `curl -sSLXPUT -H"Content-Type: application/json" -d '{"a":2}' example.com/x`,
+			method:  "PUT",
+			url:     "http://example.com/x",
+			headers: map[string]string{"Content-Type": "application/json"},
+			body:    `{"a":2}`,
+		},
+		{
+			name:   "Data from file",
+			input:  `curl --data-binary @` + payload + ` https://api.example.com/upload`,
+			method: "POST",
+			url:    "https://api.example.com/upload",
+			body:   "{\"a\": 1}\n",
+		},
+		{
+			name:   "Get with data",
+			input:  `curl -G -d q=go --data-urlencode 'tag=a b' https://api.example.com/search`,
+			method: "GET",
+			url:    "https://api.example.com/search?q=go&tag=a+b",
+		},
+		{
+			name:    "Multipart form",
+			input:   `curl -F title=Hello -F 'avatar=@` + avatar + `;type=image/png' https://api.example.com/profile`,
+			method:  "POST",
+			url:     "https://api.example.com/profile",
+			headers: map[string]string{"Content-Type": "multipart/form-data; boundary=" + formBoundary},
+			contains: []string{
+				`Content-Disposition: form-data; name="title"` + "\n\nHello",
+				`Content-Disposition: form-data; name="avatar"; filename="avatar.png"` + "\nContent-Type: image/png\n\nPNG",
+				"--" + formBoundary + "--",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, err := Curl(tt.input)
+			if err != nil {
+				t.Fatalf("Curl failed: %v", err)
+			}
+			if len(file.Requests) != 1 {
+				t.Fatalf("Expected 1 request, got %d", len(file.Requests))
+			}
+			req := file.Requests[0]
+			if req.Method != tt.method || req.URL != tt.url {
+				t.Errorf("Expected %s %s, got %s %s", tt.method, tt.url, req.Method, req.URL)
+			}
+			for key, expected := range tt.headers {
+				if got := req.Headers.Get(key); got != expected {
+					t.Errorf("Expected header %s=%q, got %q", key, expected, got)
+				}
+			}
+			if tt.body != "" && req.Body != tt.body {
+				t.Errorf("Expected body %q, got %q", tt.body, req.Body)
+			}
+			if req.Description != tt.description {
+				t.Errorf("Expected description %q, got %q", tt.description, req.Description)
+			}
+			for _, expected := range tt.contains {
+				if !strings.Contains(req.Body, expected) {
+					t.Errorf("Expected body to contain %q, got:\n%s", expected, req.Body)
+				}
+			}
+		})
+	}
+}
+
+func TestCurl_MultipleCommands(t *testing.T) {
+	file, err := Curl("curl https://a.example.com\n\ncurl -X POST https://b.example.com \\\n  -d x=1\n")
+	if err != nil {
+		t.Fatalf("Curl failed: %v", err)
+	}
+	if len(file.Requests) != 2 || file.Requests[1].Method != "POST" || file.Requests[1].Body != "x=1" {
+		t.Errorf("Expected two requests, got %+v", file.Requests)
+	}
+}
+
+func TestCurl_Errors(t *testing.T) {
+	for _, input := range []string{
+		"",
+		"wget https://example.com",
+		"curl -H 'unterminated https://example.com",
+		"curl -X POST",
+	} {
+		if _, err := Curl(input); err == nil {
+			t.Errorf("Expected error for %q", input)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package importer
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/cassielabs/hrun/internal/har"
+	"github.com/cassielabs/hrun/internal/openapi"
+	"github.com/cassielabs/hrun/internal/parser"
+)
+
+// harSkipHeaders are set by the client or the browser and would be wrong,
+// or break decoding, if replayed as-is.
+var harSkipHeaders = map[string]bool{
+	"Host": true, "Content-Length": true, "Connection": true, "Accept-Encoding": true,
+	"Transfer-Encoding": true, "Upgrade": true, "Keep-Alive": true,
+}
+
+// HARFilter limits which entries are imported. Empty lists match
+// everything.
+type HARFilter struct {
+	// Domains match the request host or any of its subdomains.
+	Domains []string
+	// ContentTypes match part of the response MIME type, so "json"
+	// selects application/json and application/problem+json.
+	ContentTypes []string
+}
+
+func (f HARFilter) matches(entry har.Entry, u *url.URL) bool {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return false
+	}
+	// CORS preflights are sent by the browser, not by the page.
+	if entry.Request.Method == http.MethodOptions && harHeader(entry.Request.Headers, "Access-Control-Request-Method") != "" {
+		return false
+	}
+
+	if len(f.Domains) > 0 {
+		host := strings.ToLower(u.Hostname())
+		found := false
+		for _, domain := range f.Domains {
+			domain = strings.ToLower(strings.TrimPrefix(domain, "."))
+			if host == domain || strings.HasSuffix(host, "."+domain) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if len(f.ContentTypes) > 0 {
+		mimeType := strings.ToLower(entry.Response.Content.MimeType)
+		for _, contentType := range f.ContentTypes {
+			if contentType != "" && strings.Contains(mimeType, strings.ToLower(contentType)) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// HAR converts recorded traffic into requests, in the order they were
+// made. Requests are named after their method and path, numbered when the
+// same endpoint was called more than once. When every request goes to the
+// same origin, it is declared once as {{baseUrl}}.
+func HAR(h *har.HAR, filter HARFilter) *parser.HTTPFile {
+	file := &parser.HTTPFile{Variables: make(map[string]string)}
+	origins := make(map[string]bool)
+	names := make(map[string]int)
+
+	for _, entry := range h.Log.Entries {
+		u, err := url.Parse(entry.Request.URL)
+		if err != nil || !filter.matches(entry, u) {
+			continue
+		}
+		origins[u.Scheme+"://"+u.Host] = true
+
+		req := parser.HTTPRequest{
+			Method:  strings.ToUpper(entry.Request.Method),
+			URL:     entry.Request.URL,
+			Headers: make(http.Header),
+		}
+		req.Name = operationName(openapi.Operation{Method: req.Method, Path: u.Path})
+		names[req.Name]++
+		if names[req.Name] > 1 {
+			req.Name += strconv.Itoa(names[req.Name])
+		}
+		if status := entry.Response.Status; status > 0 {
+			text := entry.Response.StatusText
+			if text == "" {
+				text = http.StatusText(status)
+			}
+			req.Description = strings.TrimSpace(fmt.Sprintf("Recorded response: %d %s", status, text))
+		}
+
+		for _, header := range entry.Request.Headers {
+			name := http.CanonicalHeaderKey(header.Name)
+			if strings.HasPrefix(header.Name, ":") || harSkipHeaders[name] {
+				continue
+			}
+			req.Headers.Add(name, header.Value)
+		}
+
+		if postData := entry.Request.PostData; postData != nil {
+			req.Body = postData.Text
+			if req.Body == "" && len(postData.Params) > 0 {
+				fields := make([]string, 0, len(postData.Params))
+				for _, param := range postData.Params {
+					fields = append(fields, url.QueryEscape(param.Name)+"="+url.QueryEscape(param.Value))
+				}
+				req.Body = strings.Join(fields, "&")
+			}
+			if req.Body != "" && req.Headers.Get("Content-Type") == "" && postData.MimeType != "" {
+				req.Headers.Set("Content-Type", postData.MimeType)
+			}
+		}
+
+		file.Requests = append(file.Requests, req)
+	}
+
+	if len(origins) == 1 {
+		for origin := range origins {
+			file.Variables["baseUrl"] = origin
+			for i := range file.Requests {
+				file.Requests[i].URL = "{{baseUrl}}" + strings.TrimPrefix(file.Requests[i].URL, origin)
+			}
+		}
+	}
+	return file
+}
+
+func harHeader(headers []har.NameValue, name string) string {
+	for _, header := range headers {
+		if strings.EqualFold(header.Name, name) {
+			return header.Value
+		}
+	}
+	return ""
+}
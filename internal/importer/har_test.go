@@ -0,0 +1,142 @@
+package importer
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/cassielabs/hrun/internal/har"
+)
+
+const sessionHAR = `{
+  "log": {
+    "version": "1.2",
+    "creator": {"name": "WebInspector", "version": "537.36"},
+    "entries": [
+      {
+        "request": {
+          "method": "GET",
+          "url": "https://app.example.com/index.html",
+          "headers": [{"name": "Accept", "value": "text/html"}]
+        },
+        "response": {"status": 200, "statusText": "OK", "content": {"mimeType": "text/html"}}
+      },
+      {
+        "request": {
+          "method": "OPTIONS",
+          "url": "https://api.example.com/users",
+          "headers": [{"name": "Access-Control-Request-Method", "value": "POST"}]
+        },
+        "response": {"status": 204, "content": {"mimeType": ""}}
+      },
+      {
+        "request": {
+          "method": "POST",
+          "url": "https://api.example.com/users?invite=true",
+          "headers": [
+            {"name": ":authority", "value": "api.example.com"},
+            {"name": "content-type", "value": "application/json"},
+            {"name": "accept-encoding", "value": "gzip, br"},
+            {"name": "content-length", "value": "16"},
+            {"name": "authorization", "value": "Bearer abc"}
+          ],
+          "postData": {"mimeType": "application/json", "text": "{\"name\":\"Ada\"}"}
+        },
+        "response": {"status": 201, "statusText": "", "content": {"mimeType": "application/json; charset=utf-8"}}
+      },
+      {
+        "request": {
+          "method": "POST",
+          "url": "https://api.example.com/users",
+          "headers": [],
+          "postData": {"mimeType": "application/x-www-form-urlencoded", "params": [{"name": "name", "value": "Grace H"}]}
+        },
+        "response": {"status": 400, "statusText": "Bad Request", "content": {"mimeType": "application/problem+json"}}
+      },
+      {
+        "request": {"method": "GET", "url": "https://cdn.example.net/app.js", "headers": []},
+        "response": {"status": 200, "content": {"mimeType": "application/javascript"}}
+      },
+      {
+        "request": {"method": "GET", "url": "data:image/png;base64,AAAA", "headers": []},
+        "response": {"status": 200, "content": {"mimeType": "image/png"}}
+      }
+    ]
+  }
+}`
+
+func TestHAR(t *testing.T) {
+	var h har.HAR
+	if err := json.Unmarshal([]byte(sessionHAR), &h); err != nil {
+		t.Fatalf("Failed to parse HAR: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		filter   HARFilter
+		expected []string
+	}{
+		{
+			name:     "No filter",
+			expected: []string{"getIndexHtml", "postUsers", "postUsers2", "getAppJs"},
+		},
+		{
+			name:     "Domain and subdomains",
+			filter:   HARFilter{Domains: []string{"example.com"}},
+			expected: []string{"getIndexHtml", "postUsers", "postUsers2"},
+		},
+		{
+			name:     "Content type",
+			filter:   HARFilter{ContentTypes: []string{"json"}},
+			expected: []string{"postUsers", "postUsers2"},
+		},
+		{
+			name:     "Domain and content type",
+			filter:   HARFilter{Domains: []string{"cdn.example.net"}, ContentTypes: []string{"json"}},
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := HAR(&h, tt.filter)
+			var names []string
+			for _, req := range file.Requests {
+				names = append(names, req.Name)
+			}
+			if strings.Join(names, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("Expected requests %v, got %v", tt.expected, names)
+			}
+		})
+	}
+
+	file := HAR(&h, HARFilter{ContentTypes: []string{"json"}})
+	if file.Variables["baseUrl"] != "https://api.example.com" {
+		t.Errorf("Expected a single origin to become baseUrl, got %v", file.Variables)
+	}
+
+	create := file.Requests[0]
+	if create.Method != "POST" || create.URL != "{{baseUrl}}/users?invite=true" {
+		t.Errorf("Unexpected request line %s %s", create.Method, create.URL)
+	}
+	if create.Headers.Get("Authorization") != "Bearer abc" || create.Headers.Get("Content-Type") != "application/json" {
+		t.Errorf("Expected recorded headers to be kept, got %v", create.Headers)
+	}
+	for _, skipped := range []string{":authority", "Accept-Encoding", "Content-Length"} {
+		if create.Headers.Get(skipped) != "" {
+			t.Errorf("Expected %s to be dropped", skipped)
+		}
+	}
+	if create.Body != `{"name":"Ada"}` || create.Description != "Recorded response: 201 Created" {
+		t.Errorf("Unexpected body %q or description %q", create.Body, create.Description)
+	}
+
+	form := file.Requests[1]
+	if form.Body != "name=Grace+H" || form.Headers.Get("Content-Type") != "application/x-www-form-urlencoded" {
+		t.Errorf("Expected form params to be encoded, got %q (%q)", form.Body, form.Headers.Get("Content-Type"))
+	}
+
+	if all := HAR(&h, HARFilter{}); all.Variables["baseUrl"] != "" || all.Requests[0].URL != "https://app.example.com/index.html" {
+		t.Errorf("Expected absolute URLs across origins, got %v %q", all.Variables, all.Requests[0].URL)
+	}
+}
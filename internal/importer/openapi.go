@@ -0,0 +1,271 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/cassielabs/hrun/internal/openapi"
+	"github.com/cassielabs/hrun/internal/parser"
+)
+
+var pathParamRegex = regexp.MustCompile(`\{([^}]+)\}`)
+
+// OpenAPI generates one request per operation. Path, query and header
+// parameters become {{variables}}, declared with their example or default
+// value, and bodies are taken from examples or generated from schemas.
+func OpenAPI(spec *openapi.Spec) *parser.HTTPFile {
+	file := &parser.HTTPFile{
+		Variables: map[string]string{"baseUrl": baseURL(spec)},
+	}
+
+	for _, op := range spec.Operations() {
+		req := parser.HTTPRequest{
+			Name:        operationName(op),
+			Description: op.Summary,
+			Method:      op.Method,
+			Headers:     make(http.Header),
+		}
+		if req.Description == "" {
+			req.Description, _, _ = strings.Cut(strings.TrimSpace(op.Description), "\n")
+		}
+
+		var query []string
+		for _, param := range op.Parameters {
+			switch param.In {
+			case "query":
+				query = append(query, url.QueryEscape(param.Name)+"={{"+param.Name+"}}")
+			case "header":
+				req.Headers.Set(param.Name, "{{"+param.Name+"}}")
+			case "path":
+			default:
+				continue
+			}
+			if _, ok := file.Variables[param.Name]; !ok {
+				file.Variables[param.Name] = parameterValue(spec, param)
+			}
+		}
+
+		req.URL = "{{baseUrl}}" + pathParamRegex.ReplaceAllString(op.Path, "{{$1}}")
+		if len(query) > 0 {
+			req.URL += "?" + strings.Join(query, "&")
+		}
+
+		if op.RequestBody != nil {
+			if contentType, body, ok := requestBody(spec, op.RequestBody.Content); ok {
+				req.Headers.Set("Content-Type", contentType)
+				req.Body = body
+			}
+		}
+
+		file.Requests = append(file.Requests, req)
+	}
+	return file
+}
+
+// baseURL is the first server with its variables set to their defaults.
+func baseURL(spec *openapi.Spec) string {
+	if len(spec.Servers) == 0 {
+		return "http://localhost"
+	}
+	server := spec.Servers[0]
+	base := pathParamRegex.ReplaceAllStringFunc(server.URL, func(match string) string {
+		return server.Variables[match[1:len(match)-1]].Default
+	})
+	if strings.HasPrefix(base, "/") {
+		base = "http://localhost" + base
+	}
+	return strings.TrimSuffix(base, "/")
+}
+
+// operationName is the operationId, or a camelCase name built from the
+// method and path: GET /users/{id} becomes getUsersId.
+func operationName(op openapi.Operation) string {
+	if op.OperationID != "" {
+		return op.OperationID
+	}
+	name := strings.ToLower(op.Method)
+	for _, word := range strings.FieldsFunc(op.Path, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		name += strings.ToUpper(word[:1]) + word[1:]
+	}
+	return name
+}
+
+func parameterValue(spec *openapi.Spec, param openapi.Parameter) string {
+	value := param.Example
+	if value == nil {
+		value = exampleValue(spec, param.Schema, 0)
+	}
+	switch v := value.(type) {
+	case string:
+		return v
+	case []any:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = fmt.Sprint(item)
+		}
+		return strings.Join(items, ",")
+	case nil:
+		return ""
+	}
+	return fmt.Sprint(value)
+}
+
+func requestBody(spec *openapi.Spec, content map[string]openapi.MediaType) (string, string, bool) {
+	if len(content) == 0 {
+		return "", "", false
+	}
+	names := make([]string, 0, len(content))
+	for name := range content {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	contentType := names[0]
+	for _, name := range names {
+		if name == "application/json" || strings.HasSuffix(name, "+json") {
+			contentType = name
+			break
+		}
+	}
+
+	mediaType := content[contentType]
+	value := mediaType.Example
+	if value == nil && len(mediaType.Examples) > 0 {
+		keys := make([]string, 0, len(mediaType.Examples))
+		for key := range mediaType.Examples {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		value = mediaType.Examples[keys[0]].Value
+	}
+	if value == nil {
+		value = exampleValue(spec, mediaType.Schema, 0)
+	}
+	if value == nil {
+		return contentType, "", true
+	}
+
+	switch {
+	case contentType == "application/x-www-form-urlencoded":
+		if object, ok := value.(map[string]any); ok {
+			form := url.Values{}
+			for key, item := range object {
+				form.Set(key, fmt.Sprint(item))
+			}
+			return contentType, form.Encode(), true
+		}
+	case strings.Contains(contentType, "json"):
+		if data, err := json.MarshalIndent(value, "", "  "); err == nil {
+			return contentType, string(data), true
+		}
+	}
+	if text, ok := value.(string); ok {
+		return contentType, text, true
+	}
+	return contentType, fmt.Sprint(value), true
+}
+
+// exampleValue builds a value for a schema from its example, default or
+// enum, falling back to a placeholder for its type. Recursive schemas stop
+// after a few levels.
+func exampleValue(spec *openapi.Spec, schema map[string]any, depth int) any {
+	schema = spec.ResolveSchema(schema)
+	if schema == nil || depth > 8 {
+		return nil
+	}
+	for _, key := range []string{"example", "default"} {
+		if value, ok := schema[key]; ok {
+			return value
+		}
+	}
+	if values, ok := schema["examples"].([]any); ok && len(values) > 0 {
+		return values[0]
+	}
+	if values, ok := schema["enum"].([]any); ok && len(values) > 0 {
+		return values[0]
+	}
+	if value, ok := schema["const"]; ok {
+		return value
+	}
+
+	if parts, ok := schema["allOf"].([]any); ok {
+		merged := make(map[string]any)
+		for _, part := range parts {
+			partSchema, _ := part.(map[string]any)
+			if object, ok := exampleValue(spec, partSchema, depth+1).(map[string]any); ok {
+				for key, value := range object {
+					merged[key] = value
+				}
+			}
+		}
+		return merged
+	}
+	for _, key := range []string{"oneOf", "anyOf"} {
+		if parts, ok := schema[key].([]any); ok && len(parts) > 0 {
+			partSchema, _ := parts[0].(map[string]any)
+			return exampleValue(spec, partSchema, depth+1)
+		}
+	}
+
+	switch schemaType(schema) {
+	case "object":
+		object := make(map[string]any)
+		properties, _ := schema["properties"].(map[string]any)
+		for name, property := range properties {
+			propertySchema, _ := property.(map[string]any)
+			if value := exampleValue(spec, propertySchema, depth+1); value != nil {
+				object[name] = value
+			}
+		}
+		return object
+	case "array":
+		items, _ := schema["items"].(map[string]any)
+		if item := exampleValue(spec, items, depth+1); item != nil {
+			return []any{item}
+		}
+		return []any{}
+	case "integer", "number":
+		return 0
+	case "boolean":
+		return false
+	case "string":
+		switch schema["format"] {
+		case "date-time":
+			return "2024-01-01T00:00:00Z"
+		case "date":
+			return "2024-01-01"
+		case "email":
+			return "user@example.com"
+		case "uuid":
+			return "00000000-0000-0000-0000-000000000000"
+		case "uri", "url":
+			return "https://example.com"
+		}
+		return "string"
+	}
+	return nil
+}
+
+func schemaType(schema map[string]any) string {
+	switch t := schema["type"].(type) {
+	case string:
+		return t
+	case []any:
+		for _, item := range t {
+			if name, ok := item.(string); ok && name != "null" {
+				return name
+			}
+		}
+	}
+	if _, ok := schema["properties"]; ok {
+		return "object"
+	}
+	return ""
+}
@@ -0,0 +1,116 @@
+package importer
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cassielabs/hrun/internal/openapi"
+	"github.com/cassielabs/hrun/internal/parser"
+)
+
+const ordersSpec = `{
+  "openapi": "3.1.0",
+  "info": {"title": "Orders", "version": "1"},
+  "servers": [{"url": "https://{region}.example.com/api/", "variables": {"region": {"default": "eu"}}}],
+  "paths": {
+    "/orders/{orderId}": {
+      "get": {
+        "operationId": "getOrder",
+        "summary": "Fetch one order",
+        "parameters": [
+          {"name": "orderId", "in": "path", "required": true, "schema": {"type": "string", "format": "uuid"}},
+          {"name": "expand", "in": "query", "schema": {"type": "array", "items": {"type": "string"}}, "example": ["items", "customer"]},
+          {"name": "X-Tenant", "in": "header", "schema": {"type": "string", "default": "acme"}}
+        ],
+        "responses": {"200": {"description": "OK"}}
+      }
+    },
+    "/orders": {
+      "post": {
+        "description": "Creates an order.\nMore details here.",
+        "requestBody": {
+          "content": {
+            "application/json": {"schema": {"$ref": "#/components/schemas/NewOrder"}}
+          }
+        },
+        "responses": {"201": {"description": "Created"}}
+      }
+    }
+  },
+  "components": {
+    "schemas": {
+      "NewOrder": {
+        "type": "object",
+        "properties": {
+          "sku": {"type": "string", "example": "SKU-1"},
+          "quantity": {"type": "integer", "minimum": 1},
+          "gift": {"type": "boolean"},
+          "placedAt": {"type": "string", "format": "date-time"}
+        }
+      }
+    }
+  }
+}`
+
+func TestOpenAPI(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "orders.json")
+	if err := os.WriteFile(path, []byte(ordersSpec), 0o644); err != nil {
+		t.Fatalf("Failed to write spec: %v", err)
+	}
+	spec, err := openapi.Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	file, err := parser.ParseString(parser.Format(OpenAPI(spec)))
+	if err != nil {
+		t.Fatalf("Generated file does not parse: %v", err)
+	}
+
+	expectedVariables := map[string]string{
+		"baseUrl":  "https://eu.example.com/api",
+		"orderId":  "00000000-0000-0000-0000-000000000000",
+		"expand":   "items,customer",
+		"X-Tenant": "acme",
+	}
+	for name, expected := range expectedVariables {
+		if got := file.Variables[name]; got != expected {
+			t.Errorf("Expected variable %s=%q, got %q", name, expected, got)
+		}
+	}
+
+	if len(file.Requests) != 2 {
+		t.Fatalf("Expected 2 requests, got %d", len(file.Requests))
+	}
+
+	create := file.Requests[0]
+	if create.Name != "postOrders" || create.Method != "POST" || create.URL != "{{baseUrl}}/orders" {
+		t.Errorf("Unexpected create request: %s %s %s", create.Name, create.Method, create.URL)
+	}
+	if create.Description != "Creates an order." {
+		t.Errorf("Expected first description line, got %q", create.Description)
+	}
+	if create.Headers.Get("Content-Type") != "application/json" {
+		t.Errorf("Expected JSON content type, got %q", create.Headers.Get("Content-Type"))
+	}
+	var body map[string]any
+	if err := json.Unmarshal([]byte(create.Body), &body); err != nil {
+		t.Fatalf("Expected JSON body, got %q: %v", create.Body, err)
+	}
+	if body["sku"] != "SKU-1" || body["quantity"] != float64(0) || body["gift"] != false || body["placedAt"] != "2024-01-01T00:00:00Z" {
+		t.Errorf("Unexpected generated body: %v", body)
+	}
+
+	get := file.Requests[1]
+	if get.Name != "getOrder" || get.Description != "Fetch one order" {
+		t.Errorf("Expected operationId and summary, got %q and %q", get.Name, get.Description)
+	}
+	if get.URL != "{{baseUrl}}/orders/{{orderId}}?expand={{expand}}" {
+		t.Errorf("Unexpected URL %q", get.URL)
+	}
+	if get.Headers.Get("X-Tenant") != "{{X-Tenant}}" {
+		t.Errorf("Expected header parameter variable, got %q", get.Headers.Get("X-Tenant"))
+	}
+}
@@ -0,0 +1,206 @@
+package mock
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cassielabs/hrun/internal/parser"
+)
+
+var (
+	variableSegmentRegex = regexp.MustCompile(`\{\{\s*([^}]+?)\s*\}\}`)
+	leadingVariableRegex = regexp.MustCompile(`^\{\{[^}]+\}\}`)
+)
+
+type Options struct {
+	// Latency is added before every response, plus a random amount up to
+	// Jitter.
+	Latency time.Duration
+	Jitter  time.Duration
+	// Log receives one line per handled request; nil disables logging.
+	Log io.Writer
+}
+
+type route struct {
+	method    string
+	pattern   string
+	regex     *regexp.Regexp
+	params    []string
+	wildcards int
+	request   parser.HTTPRequest
+}
+
+// Server answers requests with the example responses declared in a .http
+// file. Each request becomes a route; `{{var}}` path segments match any
+// value and are available as variables in the example response.
+type Server struct {
+	routes    []route
+	variables map[string]string
+	opts      Options
+}
+
+func New(file *parser.HTTPFile, opts Options) (*Server, error) {
+	s := &Server{variables: file.Variables, opts: opts}
+	for _, req := range file.Requests {
+		r, err := newRoute(req, file.Variables)
+		if err != nil {
+			return nil, err
+		}
+		s.routes = append(s.routes, r)
+	}
+	sort.SliceStable(s.routes, func(i, j int) bool {
+		return s.routes[i].wildcards < s.routes[j].wildcards
+	})
+	return s, nil
+}
+
+func newRoute(req parser.HTTPRequest, variables map[string]string) (route, error) {
+	pattern := routePath(req.URL, variables)
+
+	var expr strings.Builder
+	var params []string
+	wildcards := 0
+	expr.WriteString("^")
+	for i, segment := range strings.Split(pattern, "/") {
+		if i > 0 {
+			expr.WriteString("/")
+		}
+		last := 0
+		for _, loc := range variableSegmentRegex.FindAllStringSubmatchIndex(segment, -1) {
+			expr.WriteString(regexp.QuoteMeta(segment[last:loc[0]]))
+			expr.WriteString("([^/]+)")
+			params = append(params, segment[loc[2]:loc[3]])
+			wildcards++
+			last = loc[1]
+		}
+		expr.WriteString(regexp.QuoteMeta(segment[last:]))
+	}
+	expr.WriteString("/?$")
+
+	regex, err := regexp.Compile(expr.String())
+	if err != nil {
+		return route{}, fmt.Errorf("invalid route %s %s: %w", req.Method, pattern, err)
+	}
+	return route{
+		method:    req.Method,
+		pattern:   pattern,
+		regex:     regex,
+		params:    params,
+		wildcards: wildcards,
+		request:   req,
+	}, nil
+}
+
+// routePath resolves the file variables in a request URL and returns its
+// path, so `{{baseUrl}}/users` with `@baseUrl = http://localhost:8080/api`
+// routes /api/users. Variables left in the path stay as wildcards; a
+// leading variable the file does not define is dropped.
+func routePath(rawURL string, variables map[string]string) string {
+	path := strings.TrimSpace(parser.ReplaceVariables(rawURL, variables))
+	if i := strings.IndexAny(path, "?#"); i >= 0 {
+		path = path[:i]
+	}
+	if loc := leadingVariableRegex.FindStringIndex(path); loc != nil {
+		path = path[loc[1]:]
+	} else if u, err := url.Parse(path); err == nil && u.Host != "" {
+		path = u.Path
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return path
+}
+
+// Routes lists the routes in matching order.
+func (s *Server) Routes() []string {
+	routes := make([]string, len(s.routes))
+	for i, r := range s.routes {
+		status := "no example"
+		if r.request.ExampleResponse != nil {
+			status = r.request.ExampleResponse.Status
+		}
+		routes[i] = fmt.Sprintf("%-7s %s -> %s", r.method, r.pattern, status)
+	}
+	return routes
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	status := s.serve(w, r)
+	if s.opts.Log != nil {
+		fmt.Fprintf(s.opts.Log, "%s %s %s -> %d (%v)\n", start.Format("15:04:05"), r.Method, r.URL.RequestURI(), status, time.Since(start).Round(time.Millisecond))
+	}
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) int {
+	if delay := s.delay(); delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return 499
+		}
+	}
+
+	matched, params := s.match(r.Method, r.URL.Path)
+	if matched == nil {
+		http.Error(w, fmt.Sprintf("no mock route for %s %s", r.Method, r.URL.Path), http.StatusNotFound)
+		return http.StatusNotFound
+	}
+
+	example := matched.request.ExampleResponse
+	if example == nil {
+		http.Error(w, fmt.Sprintf("no example response declared for %s %s", matched.method, matched.pattern), http.StatusNotImplemented)
+		return http.StatusNotImplemented
+	}
+
+	variables := make(map[string]string, len(s.variables)+len(params))
+	for k, v := range s.variables {
+		variables[k] = v
+	}
+	for k, v := range params {
+		variables[k] = v
+	}
+
+	for key, values := range example.Headers {
+		for _, value := range values {
+			w.Header().Add(key, parser.ReplaceVariables(value, variables))
+		}
+	}
+	w.WriteHeader(example.StatusCode)
+	_, _ = io.WriteString(w, parser.ReplaceVariables(example.Body, variables))
+	return example.StatusCode
+}
+
+func (s *Server) match(method, path string) (*route, map[string]string) {
+	for i := range s.routes {
+		r := &s.routes[i]
+		if r.method != method {
+			continue
+		}
+		matches := r.regex.FindStringSubmatch(path)
+		if matches == nil {
+			continue
+		}
+		params := make(map[string]string, len(r.params))
+		for j, name := range r.params {
+			params[name] = matches[j+1]
+		}
+		return r, params
+	}
+	return nil, nil
+}
+
+func (s *Server) delay() time.Duration {
+	delay := s.opts.Latency
+	if s.opts.Jitter > 0 {
+		delay += time.Duration(rand.Int63n(int64(s.opts.Jitter)))
+	}
+	return delay
+}
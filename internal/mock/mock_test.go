@@ -0,0 +1,141 @@
+package mock
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cassielabs/hrun/internal/parser"
+)
+
+const mockFile = `@baseUrl = http://localhost:8080
+
+### Get Current User
+GET {{baseUrl}}/users/me
+
+HTTP/1.1 200 OK
+Content-Type: application/json
+
+{"id": "me"}
+
+### Get User
+GET {{baseUrl}}/users/{{userId}}
+
+HTTP/1.1 200 OK
+Content-Type: application/json
+X-User: {{userId}}
+
+{"id": "{{userId}}"}
+
+### Create User
+POST https://api.example.com/users?notify=true
+
+HTTP/1.1 201 Created
+Location: /users/42
+
+### Delete User
+DELETE {{baseUrl}}/users/{{userId}}
+`
+
+func newTestServer(t *testing.T, opts Options) *httptest.Server {
+	t.Helper()
+	httpFile, err := parser.ParseString(mockFile)
+	if err != nil {
+		t.Fatalf("ParseString failed: %v", err)
+	}
+	server, err := New(httpFile, opts)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	ts := httptest.NewServer(server)
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func do(t *testing.T, method, url string) (*http.Response, string) {
+	t.Helper()
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		t.Fatalf("NewRequest failed: %v", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	body, _ := io.ReadAll(resp.Body)
+	return resp, string(body)
+}
+
+func TestServer_Routes(t *testing.T) {
+	var log bytes.Buffer
+	ts := newTestServer(t, Options{Log: &log})
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		status int
+		body   string
+	}{
+		{name: "Literal route wins over wildcard", method: "GET", path: "/users/me", status: 200, body: `{"id": "me"}`},
+		{name: "Wildcard fills variables", method: "GET", path: "/users/7", status: 200, body: `{"id": "7"}`},
+		{name: "Absolute URL and query ignored", method: "POST", path: "/users?x=1", status: 201, body: ""},
+		{name: "Route without example", method: "DELETE", path: "/users/7", status: 501},
+		{name: "Unknown path", method: "GET", path: "/orders", status: 404},
+		{name: "Unknown method", method: "PATCH", path: "/users/7", status: 404},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, body := do(t, tt.method, ts.URL+tt.path)
+			if resp.StatusCode != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, resp.StatusCode)
+			}
+			if tt.status < 300 && body != tt.body {
+				t.Errorf("Expected body %q, got %q", tt.body, body)
+			}
+		})
+	}
+
+	resp, _ := do(t, "GET", ts.URL+"/users/7")
+	if resp.Header.Get("X-User") != "7" {
+		t.Errorf("Expected header variables to be replaced, got %q", resp.Header.Get("X-User"))
+	}
+	if !strings.Contains(log.String(), "GET /users/7 -> 200") {
+		t.Errorf("Expected request log, got:\n%s", log.String())
+	}
+}
+
+func TestServer_Latency(t *testing.T) {
+	ts := newTestServer(t, Options{Latency: 50 * time.Millisecond})
+
+	start := time.Now()
+	do(t, "GET", ts.URL+"/users/me")
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("Expected at least 50ms latency, got %v", elapsed)
+	}
+}
+
+func TestRoutePath(t *testing.T) {
+	variables := map[string]string{"baseUrl": "http://localhost:8080/api", "version": "v2"}
+	tests := map[string]string{
+		"{{baseUrl}}/users/{{id}}":           "/api/users/{{id}}",
+		"{{baseUrl}}/{{version}}/users":      "/api/v2/users",
+		"{{apiRoot}}/users/{{id}}":           "/users/{{id}}",
+		"https://api.example.com/v1/users?a": "/v1/users",
+		"http://localhost:8080":              "/",
+		"/health":                            "/health",
+	}
+	for input, expected := range tests {
+		if got := routePath(input, variables); got != expected {
+			t.Errorf("routePath(%q): expected %q, got %q", input, expected, got)
+		}
+	}
+}
@@ -0,0 +1,411 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cassielabs/hrun/internal/executor"
+	"github.com/cassielabs/hrun/internal/parser"
+	"github.com/cassielabs/hrun/internal/schema"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+var (
+	templateParamRegex = regexp.MustCompile(`\{([^}]+)\}`)
+	serverOriginRegex  = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]*://[^/]*`)
+)
+
+// Violation is one way a request or response breaks the contract.
+type Violation struct {
+	// Location is what was checked, e.g. `query parameter "limit"` or
+	// "response body".
+	Location string
+	// Pointer is the JSON pointer inside a body, if any.
+	Pointer string
+	Message string
+}
+
+func (v Violation) String() string {
+	if v.Pointer != "" {
+		return fmt.Sprintf("%s at %s: %s", v.Location, v.Pointer, v.Message)
+	}
+	return fmt.Sprintf("%s: %s", v.Location, v.Message)
+}
+
+// Contract matches executed requests to operations, validates them against
+// the spec and keeps track of coverage.
+type Contract struct {
+	spec     *Spec
+	url      string
+	compiler *jsonschema.Compiler
+	schemas  map[string]*jsonschema.Schema
+	routes   []route
+	seen     map[*Operation]map[string]bool
+}
+
+type route struct {
+	operation *Operation
+	pattern   *regexp.Regexp
+	params    []string
+	literal   int
+}
+
+func NewContract(spec *Spec) (*Contract, error) {
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(spec.source))
+	if err != nil {
+		return nil, err
+	}
+	compiler := jsonschema.NewCompiler()
+	if strings.HasPrefix(spec.OpenAPI, "3.0") {
+		compiler.DefaultDraft(jsonschema.Draft4)
+		rewriteNullable(doc)
+	} else {
+		compiler.DefaultDraft(jsonschema.Draft2020)
+	}
+
+	abs, err := filepath.Abs(spec.path)
+	if err != nil {
+		return nil, err
+	}
+	if err := compiler.AddResource(abs, doc); err != nil {
+		return nil, err
+	}
+
+	c := &Contract{
+		spec:     spec,
+		url:      abs,
+		compiler: compiler,
+		schemas:  make(map[string]*jsonschema.Schema),
+		seen:     make(map[*Operation]map[string]bool),
+	}
+
+	bases := []string{""}
+	for _, server := range spec.Servers {
+		base := strings.TrimSuffix(serverOriginRegex.ReplaceAllString(server.URL, ""), "/")
+		if base != "" {
+			bases = append(bases, templatePattern(base, `[^/]+`))
+		}
+	}
+	prefix := "^(?:" + strings.Join(bases, "|") + ")"
+
+	for i := range spec.operations {
+		op := &spec.operations[i]
+		r := route{operation: op}
+		for _, match := range templateParamRegex.FindAllStringSubmatch(op.Path, -1) {
+			r.params = append(r.params, match[1])
+		}
+		r.literal = len(templateParamRegex.ReplaceAllString(op.Path, ""))
+		r.pattern, err = regexp.Compile(prefix + templatePattern(op.Path, `([^/]+)`) + "/?$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid path %s: %w", op.Path, err)
+		}
+		c.routes = append(c.routes, r)
+	}
+	// Concrete paths win over templated ones: /users/me before /users/{id}.
+	sort.SliceStable(c.routes, func(i, j int) bool {
+		if len(c.routes[i].params) != len(c.routes[j].params) {
+			return len(c.routes[i].params) < len(c.routes[j].params)
+		}
+		return c.routes[i].literal > c.routes[j].literal
+	})
+	return c, nil
+}
+
+func templatePattern(template, param string) string {
+	var b strings.Builder
+	last := 0
+	for _, loc := range templateParamRegex.FindAllStringIndex(template, -1) {
+		b.WriteString(regexp.QuoteMeta(template[last:loc[0]]))
+		b.WriteString(param)
+		last = loc[1]
+	}
+	b.WriteString(regexp.QuoteMeta(template[last:]))
+	return b.String()
+}
+
+// Match finds the operation for a method and URL, along with the path
+// parameters taken from the URL.
+func (c *Contract) Match(method, rawURL string) (*Operation, map[string]string) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, nil
+	}
+	for _, r := range c.routes {
+		if r.operation.Method != strings.ToUpper(method) {
+			continue
+		}
+		matches := r.pattern.FindStringSubmatch(u.Path)
+		if matches == nil {
+			continue
+		}
+		params := make(map[string]string, len(r.params))
+		for i, name := range r.params {
+			params[name] = matches[i+1]
+		}
+		return r.operation, params
+	}
+	return nil, nil
+}
+
+// Check validates an executed request and its response. It returns a nil
+// operation when the request matches nothing in the spec.
+func (c *Contract) Check(req parser.HTTPRequest, resp *executor.Response) (*Operation, []Violation, error) {
+	op, pathParams := c.Match(req.Method, req.URL)
+	if op == nil {
+		return nil, nil, nil
+	}
+	u, err := url.Parse(req.URL)
+	if err != nil {
+		return op, nil, err
+	}
+
+	var violations []Violation
+	add := func(found []Violation, err error) error {
+		violations = append(violations, found...)
+		return err
+	}
+
+	if err := add(c.checkParameters(op, pathParams, u.Query(), req)); err != nil {
+		return op, violations, err
+	}
+	if err := add(c.checkRequestBody(op, req)); err != nil {
+		return op, violations, err
+	}
+	if err := add(c.checkResponse(op, resp)); err != nil {
+		return op, violations, err
+	}
+	return op, violations, nil
+}
+
+func (c *Contract) checkParameters(op *Operation, pathParams map[string]string, query url.Values, req parser.HTTPRequest) ([]Violation, error) {
+	var violations []Violation
+	for _, param := range op.Parameters {
+		var values []string
+		switch param.In {
+		case "path":
+			if value, ok := pathParams[param.Name]; ok {
+				values = []string{value}
+			}
+		case "query":
+			values = query[param.Name]
+		case "header":
+			values = req.Headers.Values(param.Name)
+		default:
+			continue
+		}
+
+		location := fmt.Sprintf("%s parameter %q", param.In, param.Name)
+		if len(values) == 0 {
+			if param.Required {
+				violations = append(violations, Violation{Location: location, Message: "is required but missing"})
+			}
+			continue
+		}
+		if param.Schema == nil {
+			continue
+		}
+
+		found, err := c.validate(param.Pointer+"/schema", c.coerce(param.Schema, values))
+		if err != nil {
+			return violations, err
+		}
+		for _, v := range found {
+			violations = append(violations, Violation{Location: location, Pointer: v.Pointer, Message: v.Message})
+		}
+	}
+	return violations, nil
+}
+
+func (c *Contract) checkRequestBody(op *Operation, req parser.HTTPRequest) ([]Violation, error) {
+	if op.RequestBody == nil {
+		return nil, nil
+	}
+	if strings.TrimSpace(req.Body) == "" {
+		if op.RequestBody.Required {
+			return []Violation{{Location: "request body", Message: "is required but missing"}}, nil
+		}
+		return nil, nil
+	}
+	return c.checkBody("request body", op.RequestBody.Content, req.Headers.Get("Content-Type"), req.Body)
+}
+
+func (c *Contract) checkResponse(op *Operation, resp *executor.Response) ([]Violation, error) {
+	code := strconv.Itoa(resp.StatusCode)
+	key := responseKey(op.Responses, resp.StatusCode)
+	if c.seen[op] == nil {
+		c.seen[op] = make(map[string]bool)
+	}
+	if key == "" {
+		return []Violation{{Location: "response", Message: fmt.Sprintf("status %s is not documented", code)}}, nil
+	}
+	c.seen[op][key] = true
+
+	response := op.Responses[key]
+	if len(response.Content) == 0 || strings.TrimSpace(resp.Body) == "" {
+		return nil, nil
+	}
+	return c.checkBody(fmt.Sprintf("response %s body", code), response.Content, resp.Headers.Get("Content-Type"), resp.Body)
+}
+
+func (c *Contract) checkBody(location string, content map[string]MediaType, contentType, body string) ([]Violation, error) {
+	name, mediaType, ok := selectMediaType(content, contentType)
+	if !ok {
+		return []Violation{{Location: location, Message: fmt.Sprintf("content type %s is not documented", contentType)}}, nil
+	}
+	if mediaType.Schema == nil || !isJSON(name) {
+		return nil, nil
+	}
+
+	instance, err := jsonschema.UnmarshalJSON(strings.NewReader(body))
+	if err != nil {
+		return []Violation{{Location: location, Message: "is not valid JSON"}}, nil
+	}
+	found, err := c.validate(mediaType.Pointer+"/schema", instance)
+	if err != nil {
+		return nil, err
+	}
+	violations := make([]Violation, len(found))
+	for i, v := range found {
+		violations[i] = Violation{Location: location, Pointer: v.Pointer, Message: v.Message}
+	}
+	return violations, nil
+}
+
+func (c *Contract) validate(pointer string, instance any) ([]schema.Violation, error) {
+	sch, ok := c.schemas[pointer]
+	if !ok {
+		var err error
+		sch, err = c.compiler.Compile(c.url + pointer)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile schema %s: %w", pointer, err)
+		}
+		c.schemas[pointer] = sch
+	}
+	return schema.Violations(sch.Validate(instance))
+}
+
+// coerce converts parameter strings to the type their schema expects, so
+// that ?limit=10 validates against an integer schema.
+func (c *Contract) coerce(paramSchema map[string]any, values []string) any {
+	resolved := c.spec.ResolveSchema(paramSchema)
+	switch schemaType(resolved) {
+	case "array":
+		if len(values) == 1 {
+			values = strings.Split(values[0], ",")
+		}
+		items, _ := resolved["items"].(map[string]any)
+		out := make([]any, len(values))
+		for i, value := range values {
+			out[i] = c.coerce(items, []string{value})
+		}
+		return out
+	case "integer", "number":
+		if _, err := strconv.ParseFloat(values[0], 64); err == nil {
+			return json.Number(values[0])
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(values[0]); err == nil {
+			return b
+		}
+	}
+	return values[0]
+}
+
+func schemaType(s map[string]any) string {
+	switch t := s["type"].(type) {
+	case string:
+		return t
+	case []any:
+		for _, item := range t {
+			if name, ok := item.(string); ok && name != "null" {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// responseKey finds the documented response for a status: the exact code,
+// then a range such as 2XX, then default.
+func responseKey(responses map[string]Response, status int) string {
+	code := strconv.Itoa(status)
+	if _, ok := responses[code]; ok {
+		return code
+	}
+	for key := range responses {
+		if strings.EqualFold(key, code[:1]+"XX") {
+			return key
+		}
+	}
+	if _, ok := responses["default"]; ok {
+		return "default"
+	}
+	return ""
+}
+
+func selectMediaType(content map[string]MediaType, contentType string) (string, MediaType, bool) {
+	names := make([]string, 0, len(content))
+	for name := range content {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	actual, _, err := mime.ParseMediaType(contentType)
+	if err != nil || actual == "" {
+		for _, name := range names {
+			if isJSON(name) {
+				return name, content[name], true
+			}
+		}
+		if len(names) > 0 {
+			return names[0], content[names[0]], true
+		}
+		return "", MediaType{}, false
+	}
+
+	major, _, _ := strings.Cut(actual, "/")
+	for _, candidate := range []string{actual, major + "/*", "*/*"} {
+		for _, name := range names {
+			if strings.EqualFold(strings.TrimSpace(strings.Split(name, ";")[0]), candidate) {
+				if candidate != actual && isJSON(actual) {
+					return actual, content[name], true
+				}
+				return name, content[name], true
+			}
+		}
+	}
+	return "", MediaType{}, false
+}
+
+func isJSON(mediaType string) bool {
+	mediaType = strings.ToLower(strings.TrimSpace(strings.Split(mediaType, ";")[0]))
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// rewriteNullable turns OpenAPI 3.0's nullable: true into a JSON Schema
+// type union, which draft 4 understands.
+func rewriteNullable(value any) {
+	switch v := value.(type) {
+	case map[string]any:
+		if nullable, _ := v["nullable"].(bool); nullable {
+			if t, ok := v["type"].(string); ok {
+				v["type"] = []any{t, "null"}
+			}
+		}
+		for _, item := range v {
+			rewriteNullable(item)
+		}
+	case []any:
+		for _, item := range v {
+			rewriteNullable(item)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package openapi
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Coverage reports which operations and documented responses were
+// exercised by the checked requests.
+type Coverage struct {
+	Operations []OperationCoverage
+}
+
+type OperationCoverage struct {
+	Method      string
+	Path        string
+	OperationID string
+	Exercised   bool
+	// Responses lists the documented response codes; Missing the ones
+	// that were never returned.
+	Responses []string
+	Missing   []string
+}
+
+func (c *Contract) Coverage() Coverage {
+	var coverage Coverage
+	for i := range c.spec.operations {
+		op := &c.spec.operations[i]
+		seen, exercised := c.seen[op]
+		entry := OperationCoverage{
+			Method:      op.Method,
+			Path:        op.Path,
+			OperationID: op.OperationID,
+			Exercised:   exercised,
+		}
+		for code := range op.Responses {
+			entry.Responses = append(entry.Responses, code)
+			if !seen[code] {
+				entry.Missing = append(entry.Missing, code)
+			}
+		}
+		sort.Strings(entry.Responses)
+		sort.Strings(entry.Missing)
+		coverage.Operations = append(coverage.Operations, entry)
+	}
+	return coverage
+}
+
+func (c Coverage) String() string {
+	var b strings.Builder
+	var exercised, responses, seen int
+	var notExercised, missing []string
+	for _, op := range c.Operations {
+		label := op.Method + " " + op.Path
+		responses += len(op.Responses)
+		seen += len(op.Responses) - len(op.Missing)
+		if !op.Exercised {
+			if op.OperationID != "" {
+				label += " (" + op.OperationID + ")"
+			}
+			notExercised = append(notExercised, label)
+			continue
+		}
+		exercised++
+		if len(op.Missing) > 0 {
+			missing = append(missing, label+": "+strings.Join(op.Missing, ", "))
+		}
+	}
+
+	fmt.Fprintf(&b, "OpenAPI coverage: %d/%d operations, %d/%d responses\n", exercised, len(c.Operations), seen, responses)
+	if len(notExercised) > 0 {
+		b.WriteString("  Operations never exercised:\n")
+		for _, line := range notExercised {
+			fmt.Fprintf(&b, "    %s\n", line)
+		}
+	}
+	if len(missing) > 0 {
+		b.WriteString("  Responses never seen:\n")
+		for _, line := range missing {
+			fmt.Fprintf(&b, "    %s\n", line)
+		}
+	}
+	return b.String()
+}
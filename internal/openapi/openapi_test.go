@@ -0,0 +1,295 @@
+package openapi
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cassielabs/hrun/internal/executor"
+	"github.com/cassielabs/hrun/internal/parser"
+)
+
+const petstore = `openapi: 3.0.3
+info:
+  title: Users
+  version: "1.0"
+servers:
+  - url: https://api.example.com/v1
+paths:
+  /users:
+    get:
+      operationId: listUsers
+      summary: List users
+      parameters:
+        - name: limit
+          in: query
+          schema:
+            type: integer
+            maximum: 100
+      responses:
+        200:
+          description: OK
+          content:
+            application/json:
+              schema:
+                type: array
+                items:
+                  $ref: '#/components/schemas/User'
+    post:
+      operationId: createUser
+      requestBody:
+        $ref: '#/components/requestBodies/NewUser'
+      responses:
+        "201":
+          description: Created
+        4XX:
+          description: Invalid
+  /users/{id}:
+    parameters:
+      - $ref: '#/components/parameters/UserID'
+    get:
+      operationId: getUser
+      responses:
+        "200":
+          description: OK
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/User'
+        "404":
+          description: Not found
+  /users/me:
+    get:
+      operationId: getMe
+      responses:
+        "200":
+          description: OK
+components:
+  parameters:
+    UserID:
+      name: id
+      in: path
+      required: true
+      schema:
+        type: integer
+  requestBodies:
+    NewUser:
+      required: true
+      content:
+        application/json:
+          schema:
+            type: object
+            required: [name]
+            properties:
+              name:
+                type: string
+  schemas:
+    User:
+      type: object
+      required: [id, name]
+      properties:
+        id:
+          type: integer
+        name:
+          type: string
+        nickname:
+          type: string
+          nullable: true
+`
+
+func loadSpec(t *testing.T) *Spec {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "spec.yaml")
+	if err := os.WriteFile(path, []byte(petstore), 0o644); err != nil {
+		t.Fatalf("Failed to write spec: %v", err)
+	}
+	spec, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	return spec
+}
+
+func TestLoad_Operations(t *testing.T) {
+	spec := loadSpec(t)
+
+	var labels []string
+	for _, op := range spec.Operations() {
+		labels = append(labels, op.Method+" "+op.Path)
+	}
+	expected := "GET /users,POST /users,GET /users/me,GET /users/{id}"
+	if strings.Join(labels, ",") != expected {
+		t.Errorf("Expected operations %s, got %s", expected, strings.Join(labels, ","))
+	}
+
+	getUser := spec.Operations()[3]
+	if len(getUser.Parameters) != 1 || getUser.Parameters[0].Name != "id" || !getUser.Parameters[0].Required {
+		t.Errorf("Expected shared path parameter to be resolved, got %+v", getUser.Parameters)
+	}
+	if _, ok := spec.Operations()[0].Responses["200"]; !ok {
+		t.Errorf("Expected unquoted response code to be loaded, got %v", spec.Operations()[0].Responses)
+	}
+	if body := spec.Operations()[1].RequestBody; body == nil || !body.Required {
+		t.Errorf("Expected request body reference to be resolved, got %+v", body)
+	}
+}
+
+func TestContract_Match(t *testing.T) {
+	contract, err := NewContract(loadSpec(t))
+	if err != nil {
+		t.Fatalf("NewContract failed: %v", err)
+	}
+
+	tests := []struct {
+		method     string
+		url        string
+		expected   string
+		pathParams map[string]string
+	}{
+		{method: "GET", url: "https://api.example.com/v1/users?limit=5", expected: "listUsers"},
+		{method: "GET", url: "http://localhost:8080/users", expected: "listUsers"},
+		{method: "GET", url: "https://api.example.com/v1/users/42", expected: "getUser", pathParams: map[string]string{"id": "42"}},
+		{method: "GET", url: "https://api.example.com/v1/users/me", expected: "getMe"},
+		{method: "DELETE", url: "https://api.example.com/v1/users/42"},
+		{method: "GET", url: "https://api.example.com/v1/orders"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.url, func(t *testing.T) {
+			op, params := contract.Match(tt.method, tt.url)
+			if tt.expected == "" {
+				if op != nil {
+					t.Errorf("Expected no match, got %s", op.OperationID)
+				}
+				return
+			}
+			if op == nil || op.OperationID != tt.expected {
+				t.Fatalf("Expected %s, got %+v", tt.expected, op)
+			}
+			for name, value := range tt.pathParams {
+				if params[name] != value {
+					t.Errorf("Expected path parameter %s=%s, got %q", name, value, params[name])
+				}
+			}
+		})
+	}
+}
+
+func TestContract_Check(t *testing.T) {
+	jsonHeaders := http.Header{"Content-Type": []string{"application/json"}}
+
+	tests := []struct {
+		name     string
+		req      parser.HTTPRequest
+		resp     executor.Response
+		expected []string
+	}{
+		{
+			name: "Valid list",
+			req:  parser.HTTPRequest{Method: "GET", URL: "https://api.example.com/v1/users?limit=10"},
+			resp: executor.Response{StatusCode: 200, Headers: jsonHeaders, Body: `[{"id": 1, "name": "a", "nickname": null}]`},
+		},
+		{
+			name:     "Query parameter out of range",
+			req:      parser.HTTPRequest{Method: "GET", URL: "https://api.example.com/v1/users?limit=500"},
+			resp:     executor.Response{StatusCode: 200, Headers: jsonHeaders, Body: `[]`},
+			expected: []string{`query parameter "limit"`},
+		},
+		{
+			name:     "Path parameter type",
+			req:      parser.HTTPRequest{Method: "GET", URL: "https://api.example.com/v1/users/abc"},
+			resp:     executor.Response{StatusCode: 404},
+			expected: []string{`path parameter "id"`},
+		},
+		{
+			name:     "Response body",
+			req:      parser.HTTPRequest{Method: "GET", URL: "https://api.example.com/v1/users/1"},
+			resp:     executor.Response{StatusCode: 200, Headers: jsonHeaders, Body: `{"id": "1"}`},
+			expected: []string{"response 200 body: missing property", "response 200 body at /id"},
+		},
+		{
+			name:     "Single response body error",
+			req:      parser.HTTPRequest{Method: "GET", URL: "https://api.example.com/v1/users/1"},
+			resp:     executor.Response{StatusCode: 200, Headers: jsonHeaders, Body: `{"id": "1", "name": "a"}`},
+			expected: []string{"response 200 body at /id: got string, want integer"},
+		},
+		{
+			name:     "Undocumented status",
+			req:      parser.HTTPRequest{Method: "GET", URL: "https://api.example.com/v1/users/1"},
+			resp:     executor.Response{StatusCode: 500},
+			expected: []string{"response: status 500 is not documented"},
+		},
+		{
+			name:     "Missing request body",
+			req:      parser.HTTPRequest{Method: "POST", URL: "https://api.example.com/v1/users"},
+			resp:     executor.Response{StatusCode: 422},
+			expected: []string{"request body: is required but missing"},
+		},
+		{
+			name:     "Invalid request body",
+			req:      parser.HTTPRequest{Method: "POST", URL: "https://api.example.com/v1/users", Headers: jsonHeaders, Body: `{"name": 5}`},
+			resp:     executor.Response{StatusCode: 201},
+			expected: []string{"request body at /name"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			contract, err := NewContract(loadSpec(t))
+			if err != nil {
+				t.Fatalf("NewContract failed: %v", err)
+			}
+			op, violations, err := contract.Check(tt.req, &tt.resp)
+			if err != nil {
+				t.Fatalf("Check failed: %v", err)
+			}
+			if op == nil {
+				t.Fatal("Expected request to match an operation")
+			}
+			if len(violations) != len(tt.expected) {
+				t.Fatalf("Expected %d violations, got %v", len(tt.expected), violations)
+			}
+			for i, v := range violations {
+				if !strings.HasPrefix(v.String(), tt.expected[i]) {
+					t.Errorf("Expected violation starting with %q, got %q", tt.expected[i], v.String())
+				}
+			}
+		})
+	}
+}
+
+func TestContract_Coverage(t *testing.T) {
+	contract, err := NewContract(loadSpec(t))
+	if err != nil {
+		t.Fatalf("NewContract failed: %v", err)
+	}
+
+	requests := []struct {
+		method string
+		url    string
+		status int
+	}{
+		{method: "GET", url: "https://api.example.com/v1/users/1", status: 200},
+		{method: "POST", url: "https://api.example.com/v1/users", status: 400},
+		{method: "GET", url: "https://api.example.com/v1/users/me", status: 200},
+	}
+	for _, r := range requests {
+		if _, _, err := contract.Check(parser.HTTPRequest{Method: r.method, URL: r.url}, &executor.Response{StatusCode: r.status}); err != nil {
+			t.Fatalf("Check failed: %v", err)
+		}
+	}
+
+	report := contract.Coverage().String()
+	for _, expected := range []string{
+		"OpenAPI coverage: 3/4 operations, 3/6 responses",
+		"GET /users (listUsers)",
+		"POST /users: 201",
+		"GET /users/{id}: 404",
+	} {
+		if !strings.Contains(report, expected) {
+			t.Errorf("Expected coverage report to contain %q, got:\n%s", expected, report)
+		}
+	}
+}
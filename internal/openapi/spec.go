@@ -0,0 +1,347 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"gopkg.in/yaml.v3"
+)
+
+var methods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// Spec is an OpenAPI 3.x document, loaded from YAML or JSON.
+type Spec struct {
+	OpenAPI string   `json:"openapi"`
+	Info    Info     `json:"info"`
+	Servers []Server `json:"servers"`
+
+	path       string
+	source     []byte
+	doc        map[string]any
+	operations []Operation
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description"`
+}
+
+type Server struct {
+	URL         string                    `json:"url"`
+	Description string                    `json:"description"`
+	Variables   map[string]ServerVariable `json:"variables"`
+}
+
+type ServerVariable struct {
+	Default string   `json:"default"`
+	Enum    []string `json:"enum"`
+}
+
+// Operation is one method on one path, with every $ref resolved.
+type Operation struct {
+	Method      string   `json:"-"`
+	Path        string   `json:"-"`
+	OperationID string   `json:"operationId"`
+	Summary     string   `json:"summary"`
+	Description string   `json:"description"`
+	Tags        []string `json:"tags"`
+
+	Parameters  []Parameter         `json:"-"`
+	RequestBody *RequestBody        `json:"-"`
+	Responses   map[string]Response `json:"-"`
+}
+
+type Parameter struct {
+	Name        string         `json:"name"`
+	In          string         `json:"in"`
+	Required    bool           `json:"required"`
+	Description string         `json:"description"`
+	Schema      map[string]any `json:"schema"`
+	Example     any            `json:"example"`
+
+	// Pointer locates the parameter object in the document.
+	Pointer string `json:"-"`
+}
+
+type RequestBody struct {
+	Description string               `json:"description"`
+	Required    bool                 `json:"required"`
+	Content     map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content"`
+}
+
+type MediaType struct {
+	Schema   map[string]any     `json:"schema"`
+	Example  any                `json:"example"`
+	Examples map[string]Example `json:"examples"`
+
+	// Pointer locates the media type object in the document.
+	Pointer string `json:"-"`
+}
+
+type Example struct {
+	Summary string `json:"summary"`
+	Value   any    `json:"value"`
+}
+
+// Load reads an OpenAPI 3.x document. JSON is accepted too, since it is
+// valid YAML.
+func Load(path string) (*Spec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw any
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	source, err := json.Marshal(normalize(raw))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(source))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	root, ok := doc.(map[string]any)
+	if !ok || root["openapi"] == nil {
+		return nil, fmt.Errorf("%s is not an OpenAPI 3 document", path)
+	}
+
+	spec := &Spec{path: path, source: source, doc: root}
+	if err := json.Unmarshal(source, spec); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if !strings.HasPrefix(spec.OpenAPI, "3.") {
+		return nil, fmt.Errorf("%s: unsupported OpenAPI version %q", path, spec.OpenAPI)
+	}
+	if spec.operations, err = spec.buildOperations(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return spec, nil
+}
+
+// Operations returns every operation, sorted by path and then method.
+func (s *Spec) Operations() []Operation {
+	return s.operations
+}
+
+// ResolveSchema follows $ref until it reaches a schema object. Unresolvable
+// references return the schema unchanged.
+func (s *Spec) ResolveSchema(schema map[string]any) map[string]any {
+	for depth := 0; depth < 32 && schema != nil; depth++ {
+		ref, ok := schema["$ref"].(string)
+		if !ok {
+			return schema
+		}
+		target, ok := s.lookup(ref).(map[string]any)
+		if !ok {
+			return schema
+		}
+		schema = target
+	}
+	return schema
+}
+
+func (s *Spec) buildOperations() ([]Operation, error) {
+	paths, _ := s.doc["paths"].(map[string]any)
+	names := make([]string, 0, len(paths))
+	for name := range paths {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var operations []Operation
+	for _, path := range names {
+		itemPointer, item, err := s.resolve("#/paths/" + escapePointer(path))
+		if err != nil {
+			return nil, err
+		}
+		shared, err := s.parameters(itemPointer, item)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, method := range methods {
+			object, ok := item[method].(map[string]any)
+			if !ok {
+				continue
+			}
+			pointer := itemPointer + "/" + method
+			op := Operation{Method: strings.ToUpper(method), Path: path}
+			if err := decode(object, &op); err != nil {
+				return nil, fmt.Errorf("%s %s: %w", op.Method, path, err)
+			}
+
+			own, err := s.parameters(pointer, object)
+			if err != nil {
+				return nil, err
+			}
+			op.Parameters = mergeParameters(shared, own)
+
+			if _, ok := object["requestBody"]; ok {
+				bodyPointer, body, err := s.resolve(pointer + "/requestBody")
+				if err != nil {
+					return nil, err
+				}
+				op.RequestBody = &RequestBody{}
+				if err := decode(body, op.RequestBody); err != nil {
+					return nil, fmt.Errorf("%s %s: %w", op.Method, path, err)
+				}
+				setMediaTypePointers(op.RequestBody.Content, bodyPointer)
+			}
+
+			op.Responses = make(map[string]Response)
+			responses, _ := object["responses"].(map[string]any)
+			for code := range responses {
+				responsePointer, value, err := s.resolve(pointer + "/responses/" + escapePointer(code))
+				if err != nil {
+					return nil, err
+				}
+				var response Response
+				if err := decode(value, &response); err != nil {
+					return nil, fmt.Errorf("%s %s: %w", op.Method, path, err)
+				}
+				setMediaTypePointers(response.Content, responsePointer)
+				op.Responses[code] = response
+			}
+
+			operations = append(operations, op)
+		}
+	}
+	return operations, nil
+}
+
+func (s *Spec) parameters(pointer string, object map[string]any) ([]Parameter, error) {
+	list, _ := object["parameters"].([]any)
+	parameters := make([]Parameter, 0, len(list))
+	for i := range list {
+		paramPointer, value, err := s.resolve(fmt.Sprintf("%s/parameters/%d", pointer, i))
+		if err != nil {
+			return nil, err
+		}
+		var param Parameter
+		if err := decode(value, &param); err != nil {
+			return nil, fmt.Errorf("%s: %w", paramPointer, err)
+		}
+		param.Pointer = paramPointer
+		parameters = append(parameters, param)
+	}
+	return parameters, nil
+}
+
+// mergeParameters applies operation parameters over the path item's, which
+// they override by name and location.
+func mergeParameters(shared, own []Parameter) []Parameter {
+	merged := make([]Parameter, 0, len(shared)+len(own))
+	for _, param := range shared {
+		overridden := false
+		for _, other := range own {
+			if other.Name == param.Name && other.In == param.In {
+				overridden = true
+				break
+			}
+		}
+		if !overridden {
+			merged = append(merged, param)
+		}
+	}
+	return append(merged, own...)
+}
+
+func setMediaTypePointers(content map[string]MediaType, pointer string) {
+	for name, mediaType := range content {
+		mediaType.Pointer = pointer + "/content/" + escapePointer(name)
+		content[name] = mediaType
+	}
+}
+
+// resolve looks up the object at pointer, following local $refs.
+func (s *Spec) resolve(pointer string) (string, map[string]any, error) {
+	for depth := 0; depth < 32; depth++ {
+		object, ok := s.lookup(pointer).(map[string]any)
+		if !ok {
+			return "", nil, fmt.Errorf("%s does not resolve to an object", pointer)
+		}
+		ref, ok := object["$ref"].(string)
+		if !ok {
+			return pointer, object, nil
+		}
+		if !strings.HasPrefix(ref, "#/") {
+			return "", nil, fmt.Errorf("external reference %s is not supported", ref)
+		}
+		pointer = ref
+	}
+	return "", nil, fmt.Errorf("reference loop at %s", pointer)
+}
+
+func (s *Spec) lookup(pointer string) any {
+	if !strings.HasPrefix(pointer, "#/") {
+		return nil
+	}
+	var value any = s.doc
+	for _, token := range strings.Split(pointer[2:], "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		switch v := value.(type) {
+		case map[string]any:
+			value = v[token]
+		case []any:
+			index, err := strconv.Atoi(token)
+			if err != nil || index < 0 || index >= len(v) {
+				return nil
+			}
+			value = v[index]
+		default:
+			return nil
+		}
+	}
+	return value
+}
+
+func escapePointer(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
+
+func decode(value any, out any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+// normalize turns the map[any]any that YAML produces for non-string keys,
+// such as unquoted response codes, into map[string]any.
+func normalize(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, item := range v {
+			v[key] = normalize(item)
+		}
+		return v
+	case map[any]any:
+		out := make(map[string]any, len(v))
+		for key, item := range v {
+			out[fmt.Sprint(key)] = normalize(item)
+		}
+		return out
+	case []any:
+		for i, item := range v {
+			v[i] = normalize(item)
+		}
+		return v
+	}
+	return value
+}
@@ -0,0 +1,34 @@
+package parser
+
+import "strings"
+
+// Source splits the capture expression into where the value comes from and
+// the argument for that source:
+//
+//	status                    -> status, ""
+//	header.Location           -> header, Location
+//	cookie.session_id         -> cookie, session_id
+//	regex:value="([^"]+)"     -> regex, value="([^"]+)"
+//	xpath://user/id           -> xpath, //user/id
+//	body.status               -> body, status
+//	data.items.0.id           -> body, data.items.0.id
+//
+// The body. prefix is only needed for JSON fields that would otherwise be
+// read as another source, such as a top-level "status".
+func (c CaptureRule) Source() (string, string) {
+	expression := strings.TrimSpace(c.JSONPath)
+	if expression == CaptureStatus {
+		return CaptureStatus, ""
+	}
+	for _, prefix := range []string{CaptureRegex, CaptureXPath} {
+		if rest, ok := strings.CutPrefix(expression, prefix+":"); ok {
+			return prefix, rest
+		}
+	}
+	for _, prefix := range []string{CaptureHeader, CaptureCookie, CaptureBody} {
+		if rest, ok := strings.CutPrefix(expression, prefix+"."); ok && rest != "" {
+			return prefix, rest
+		}
+	}
+	return CaptureBody, expression
+}
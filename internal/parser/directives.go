@@ -0,0 +1,90 @@
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func parseRetryDirective(args string) (*RetryPolicy, error) {
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("@retry requires a retry count")
+	}
+
+	count, err := strconv.Atoi(fields[0])
+	if err != nil || count < 0 {
+		return nil, fmt.Errorf("invalid @retry count %q", fields[0])
+	}
+	policy := &RetryPolicy{MaxRetries: count}
+
+	for _, field := range fields[1:] {
+		parts := strings.SplitN(field, "=", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			return nil, fmt.Errorf("invalid @retry option %q", field)
+		}
+		key, value := strings.ToLower(parts[0]), parts[1]
+		switch key {
+		case "backoff":
+			switch strings.ToLower(value) {
+			case BackoffNone, BackoffConstant, BackoffLinear, BackoffExponential:
+				policy.Backoff = strings.ToLower(value)
+			default:
+				return nil, fmt.Errorf("unknown @retry backoff %q", value)
+			}
+		case "delay":
+			delay, err := time.ParseDuration(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid @retry delay %q", value)
+			}
+			policy.Delay = delay
+		case "on":
+			for _, cond := range strings.Split(value, ",") {
+				cond = strings.ToLower(strings.TrimSpace(cond))
+				if !validRetryCondition(cond) {
+					return nil, fmt.Errorf("unknown @retry condition %q", cond)
+				}
+				policy.On = append(policy.On, cond)
+			}
+		case "methods":
+			for _, method := range strings.Split(value, ",") {
+				policy.Methods = append(policy.Methods, strings.ToUpper(strings.TrimSpace(method)))
+			}
+		default:
+			return nil, fmt.Errorf("unknown @retry option %q", key)
+		}
+	}
+
+	return policy, nil
+}
+
+func validRetryCondition(cond string) bool {
+	switch cond {
+	case RetryOn5xx, RetryOn4xx, RetryOnTimeout, RetryOnNetwork:
+		return true
+	}
+	code, err := strconv.Atoi(cond)
+	return err == nil && code >= 100 && code <= 599
+}
+
+// parsePromptDirective reads the description and --secret flag after the
+// variable name of an @prompt. The description may be quoted.
+func parsePromptDirective(name, args string) Prompt {
+	prompt := Prompt{Name: name}
+	var words []string
+	for _, field := range strings.Fields(args) {
+		if field == "--secret" {
+			prompt.Secret = true
+		} else {
+			words = append(words, field)
+		}
+	}
+
+	description := strings.Join(words, " ")
+	if unquoted, err := strconv.Unquote(description); err == nil && strings.HasPrefix(description, `"`) {
+		description = unquoted
+	}
+	prompt.Description = description
+	return prompt
+}
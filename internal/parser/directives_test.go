@@ -0,0 +1,311 @@
+package parser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParsePluginDirective(t *testing.T) {
+	content := `### Signed Request
+# Sends a signed order
+# @plugin sign-hmac key=orders sha256
+# @plugin audit
+POST https://api.example.com/orders
+Content-Type: application/json
+
+{"id": 1}
+`
+
+	httpFile, err := ParseString(content)
+	if err != nil {
+		t.Fatalf("Failed to parse file: %v", err)
+	}
+
+	if len(httpFile.Requests) != 1 {
+		t.Fatalf("Expected 1 request, got %d", len(httpFile.Requests))
+	}
+
+	req := httpFile.Requests[0]
+	if len(req.Plugins) != 2 {
+		t.Fatalf("Expected 2 plugins, got %d", len(req.Plugins))
+	}
+
+	if req.Plugins[0].Name != "sign-hmac" {
+		t.Errorf("Expected plugin name 'sign-hmac', got %q", req.Plugins[0].Name)
+	}
+	if len(req.Plugins[0].Args) != 2 || req.Plugins[0].Args[0] != "key=orders" || req.Plugins[0].Args[1] != "sha256" {
+		t.Errorf("Expected plugin args [key=orders sha256], got %v", req.Plugins[0].Args)
+	}
+	if req.Plugins[1].Name != "audit" || len(req.Plugins[1].Args) != 0 {
+		t.Errorf("Expected plugin 'audit' without args, got %+v", req.Plugins[1])
+	}
+
+	if req.Description != "Sends a signed order" {
+		t.Errorf("Expected plugin directives to be kept out of the description, got %q", req.Description)
+	}
+}
+
+func TestParseRetryDirective(t *testing.T) {
+	content := `### Flaky
+# @retry 3 backoff=linear delay=100ms on=5xx,timeout,429 methods=POST
+POST https://api.example.com/orders
+`
+
+	httpFile, err := ParseString(content)
+	if err != nil {
+		t.Fatalf("Failed to parse file: %v", err)
+	}
+
+	policy := httpFile.Requests[0].Retry
+	if policy == nil {
+		t.Fatal("Expected retry policy to be parsed")
+	}
+	if policy.MaxRetries != 3 {
+		t.Errorf("Expected 3 retries, got %d", policy.MaxRetries)
+	}
+	if policy.Backoff != BackoffLinear {
+		t.Errorf("Expected linear backoff, got %q", policy.Backoff)
+	}
+	if policy.Delay != 100*time.Millisecond {
+		t.Errorf("Expected 100ms delay, got %v", policy.Delay)
+	}
+	if strings.Join(policy.On, ",") != "5xx,timeout,429" {
+		t.Errorf("Expected conditions 5xx,timeout,429, got %v", policy.On)
+	}
+	if strings.Join(policy.Methods, ",") != "POST" {
+		t.Errorf("Expected methods POST, got %v", policy.Methods)
+	}
+}
+
+func TestParseRetryDirective_Invalid(t *testing.T) {
+	tests := []string{
+		"# @retry",
+		"# @retry many",
+		"# @retry 3 backoff=random",
+		"# @retry 3 on=sometimes",
+		"# @retry 3 delay=soon",
+		"# @retry 3 jitter=yes",
+	}
+
+	for _, directive := range tests {
+		content := "### Flaky\n" + directive + "\nGET https://api.example.com\n"
+		if _, err := ParseString(content); err == nil {
+			t.Errorf("Expected error for %q", directive)
+		}
+	}
+}
+
+func TestParseDependsOnDirective(t *testing.T) {
+	content := `### Create order
+# @depends-on Step 1: login
+# @depends-on seed
+POST https://api.example.com/orders
+`
+
+	httpFile, err := ParseString(content)
+	if err != nil {
+		t.Fatalf("Failed to parse file: %v", err)
+	}
+
+	deps := httpFile.Requests[0].DependsOn
+	if len(deps) != 2 || deps[0] != "Step 1: login" || deps[1] != "seed" {
+		t.Errorf("Expected dependencies [Step 1: login, seed], got %v", deps)
+	}
+}
+
+func TestVariableReferences(t *testing.T) {
+	content := `### Refs
+POST {{baseUrl}}/users/{{ userId }}
+Authorization: Bearer {{token}}
+X-Trace: {{traceId}}
+
+{"name": "{{name}}", "owner": "{{userId}}"}
+`
+
+	httpFile, err := ParseString(content)
+	if err != nil {
+		t.Fatalf("Failed to parse file: %v", err)
+	}
+
+	refs := httpFile.Requests[0].VariableReferences()
+	expected := "baseUrl,userId,token,traceId,name"
+	if strings.Join(refs, ",") != expected {
+		t.Errorf("Expected references %s, got %v", expected, refs)
+	}
+}
+
+func TestParseSnapshotIgnoreDirective(t *testing.T) {
+	content := `### Get User
+# @snapshot-ignore body.createdAt, header Date
+# @snapshot-ignore body.items.*.id
+GET https://api.example.com/users/1
+`
+
+	httpFile, err := ParseString(content)
+	if err != nil {
+		t.Fatalf("Failed to parse file: %v", err)
+	}
+
+	rules := httpFile.Requests[0].SnapshotIgnore
+	expected := "body.createdAt|header Date|body.items.*.id"
+	if strings.Join(rules, "|") != expected {
+		t.Errorf("Expected rules %s, got %v", expected, rules)
+	}
+}
+
+func TestParseSchemaDirective(t *testing.T) {
+	content := `### Get User
+# Fetch one user
+# @schema ./schemas/user.json
+GET https://api.example.com/users/1
+`
+
+	httpFile, err := ParseString(content)
+	if err != nil {
+		t.Fatalf("Failed to parse file: %v", err)
+	}
+
+	req := httpFile.Requests[0]
+	if req.Schema != "./schemas/user.json" {
+		t.Errorf("Expected schema './schemas/user.json', got %q", req.Schema)
+	}
+	if req.Description != "Fetch one user" {
+		t.Errorf("Expected schema directive to be kept out of the description, got %q", req.Description)
+	}
+}
+
+func TestParseTagDirective(t *testing.T) {
+	content := `### Charge card
+# Charges the test card
+# @tag smoke, payments
+# @tags slow Smoke
+POST https://api.example.com/charges
+
+### Health
+GET https://api.example.com/health
+`
+
+	httpFile, err := ParseString(content)
+	if err != nil {
+		t.Fatalf("Failed to parse file: %v", err)
+	}
+
+	req := httpFile.Requests[0]
+	if strings.Join(req.Tags, ",") != "smoke,payments,slow" {
+		t.Errorf("Expected tags smoke,payments,slow, got %v", req.Tags)
+	}
+	if req.Description != "Charges the test card" {
+		t.Errorf("Expected tag directives to be kept out of the description, got %q", req.Description)
+	}
+	if strings.Join(httpFile.Tags(), ",") != "payments,slow,smoke" {
+		t.Errorf("Expected file tags payments,slow,smoke, got %v", httpFile.Tags())
+	}
+
+	tests := []struct {
+		name     string
+		include  []string
+		exclude  []string
+		expected []bool
+	}{
+		{name: "No filters", expected: []bool{true, true}},
+		{name: "Include", include: []string{"SMOKE"}, expected: []bool{true, false}},
+		{name: "Include any", include: []string{"nightly", "payments"}, expected: []bool{true, false}},
+		{name: "Exclude", exclude: []string{"slow"}, expected: []bool{false, true}},
+		{name: "Exclude wins", include: []string{"smoke"}, exclude: []string{"slow"}, expected: []bool{false, false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i, expected := range tt.expected {
+				if got := httpFile.Requests[i].MatchesTags(tt.include, tt.exclude); got != expected {
+					t.Errorf("Request %d: expected %v, got %v", i, expected, got)
+				}
+			}
+		})
+	}
+}
+
+func TestParseExecutionDirectives(t *testing.T) {
+	content := `### Log in as admin
+# @name login
+# @timeout 2m
+# @only
+POST https://api.example.com/login
+
+### Export
+# @skip flaky until the export service is fixed
+GET https://api.example.com/export
+
+### Legacy
+# @skip
+GET https://api.example.com/legacy
+`
+
+	httpFile, err := ParseString(content)
+	if err != nil {
+		t.Fatalf("Failed to parse file: %v", err)
+	}
+
+	login := httpFile.Requests[0]
+	if login.Name != "login" || login.Title != "Log in as admin" {
+		t.Errorf("Expected name login with title kept, got %q / %q", login.Name, login.Title)
+	}
+	if login.Timeout != 2*time.Minute || !login.Only || login.Skip {
+		t.Errorf("Unexpected directives on login: timeout %v, only %v, skip %v", login.Timeout, login.Only, login.Skip)
+	}
+	if login.Description != "" {
+		t.Errorf("Expected directives to be kept out of the description, got %q", login.Description)
+	}
+
+	export := httpFile.Requests[1]
+	if !export.Skip || export.SkipReason != "flaky until the export service is fixed" || export.Name != "Export" {
+		t.Errorf("Unexpected skip on export: %v %q (%q)", export.Skip, export.SkipReason, export.Name)
+	}
+	if legacy := httpFile.Requests[2]; !legacy.Skip || legacy.SkipReason != "" {
+		t.Errorf("Expected bare @skip, got %v %q", legacy.Skip, legacy.SkipReason)
+	}
+
+	if got := Select(httpFile.Requests, nil, nil); !got[0] || got[1] || got[2] {
+		t.Errorf("Expected @only to focus the run, got %v", got)
+	}
+	if got := Select(httpFile.Requests, nil, []string{"none"}); !got[0] {
+		t.Errorf("Expected unrelated exclusions to keep the focus, got %v", got)
+	}
+
+	if _, err := ParseString("### Bad\n# @timeout soon\nGET https://api.example.com\n"); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("Expected invalid @timeout to fail on line 2, got %v", err)
+	}
+}
+
+func TestParsePromptDirective(t *testing.T) {
+	content := `### Verify
+# Confirms the login
+# @prompt otp "Enter your 2FA code"
+# @prompt password --secret
+# @prompt region Region to deploy to
+# @data ./codes.csv
+POST https://api.example.com/verify
+`
+
+	httpFile, err := ParseString(content)
+	if err != nil {
+		t.Fatalf("Failed to parse file: %v", err)
+	}
+
+	req := httpFile.Requests[0]
+	expected := []Prompt{
+		{Name: "otp", Description: "Enter your 2FA code"},
+		{Name: "password", Secret: true},
+		{Name: "region", Description: "Region to deploy to"},
+	}
+	if !reflect.DeepEqual(req.Prompts, expected) {
+		t.Errorf("Expected prompts %v, got %v", expected, req.Prompts)
+	}
+	if req.Data != "./codes.csv" {
+		t.Errorf("Expected @data ./codes.csv, got %q", req.Data)
+	}
+	if req.Description != "Confirms the login" {
+		t.Errorf("Expected @prompt and @data to be kept out of the description, got %q", req.Description)
+	}
+}
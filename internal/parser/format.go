@@ -0,0 +1,67 @@
+package parser
+
+import (
+	"sort"
+	"strings"
+)
+
+// Format renders a file as .http text that ParseFile reads back. It writes
+// variables, titles, descriptions, @name when it differs from the title,
+// captures, request lines, headers and bodies; other directives are left
+// out.
+func Format(file *HTTPFile) string {
+	var b strings.Builder
+
+	names := make([]string, 0, len(file.Variables))
+	for name := range file.Variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if file.Secrets[name] {
+			b.WriteString("@!" + name + " = " + file.Variables[name] + "\n")
+		} else {
+			b.WriteString("@" + name + " = " + file.Variables[name] + "\n")
+		}
+	}
+
+	for _, req := range file.Requests {
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		title := req.Title
+		if title == "" {
+			title = req.Name
+		}
+		b.WriteString(strings.TrimSpace("### "+title) + "\n")
+		for _, line := range strings.Split(req.Description, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				b.WriteString("# " + line + "\n")
+			}
+		}
+		if req.Name != "" && req.Name != title {
+			b.WriteString("# @name " + req.Name + "\n")
+		}
+		for _, capture := range req.Captures {
+			b.WriteString("# @capture " + capture.VariableName + " = " + capture.JSONPath + "\n")
+		}
+
+		b.WriteString(req.Method + " " + req.URL + "\n")
+
+		headerNames := make([]string, 0, len(req.Headers))
+		for key := range req.Headers {
+			headerNames = append(headerNames, key)
+		}
+		sort.Strings(headerNames)
+		for _, key := range headerNames {
+			for _, value := range req.Headers[key] {
+				b.WriteString(key + ": " + value + "\n")
+			}
+		}
+
+		if req.Body != "" {
+			b.WriteString("\n" + strings.TrimRight(req.Body, "\n") + "\n")
+		}
+	}
+	return b.String()
+}
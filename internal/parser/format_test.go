@@ -0,0 +1,68 @@
+package parser
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestFormat_RoundTrip(t *testing.T) {
+	file := &HTTPFile{
+		Variables: map[string]string{"baseUrl": "https://api.example.com", "id": "1", "apiKey": "k-123"},
+		Secrets:   map[string]bool{"apiKey": true},
+		Requests: []HTTPRequest{
+			{
+				Name:        "getUser",
+				Description: "Get a user\nby id",
+				Method:      "GET",
+				URL:         "{{baseUrl}}/users/{{id}}",
+				Headers:     http.Header{"Accept": []string{"application/json"}},
+			},
+			{
+				Name:     "createUser",
+				Title:    "Create a user",
+				Method:   "POST",
+				URL:      "{{baseUrl}}/users",
+				Headers:  http.Header{"Content-Type": []string{"application/json"}},
+				Body:     "{\n  \"name\": \"Jane\"\n}",
+				Captures: []CaptureRule{{VariableName: "userId", JSONPath: "id"}},
+			},
+			{Method: "DELETE", URL: "{{baseUrl}}/users/{{id}}"},
+		},
+	}
+
+	parsed, err := ParseString(Format(file))
+	if err != nil {
+		t.Fatalf("ParseString failed: %v", err)
+	}
+
+	if len(parsed.Variables) != 3 || parsed.Variables["baseUrl"] != "https://api.example.com" {
+		t.Errorf("Expected variables to round-trip, got %v", parsed.Variables)
+	}
+	if parsed.Variables["apiKey"] != "k-123" || !parsed.Secrets["apiKey"] || parsed.Secrets["baseUrl"] {
+		t.Errorf("Expected only apiKey to round-trip as secret, got %v", parsed.Secrets)
+	}
+	if len(parsed.Requests) != len(file.Requests) {
+		t.Fatalf("Expected %d requests, got %d", len(file.Requests), len(parsed.Requests))
+	}
+	for i, expected := range file.Requests {
+		got := parsed.Requests[i]
+		if got.Name != expected.Name || got.Method != expected.Method || got.URL != expected.URL || strings.TrimSpace(got.Body) != expected.Body {
+			t.Errorf("Request %d: expected %+v, got %+v", i, expected, got)
+		}
+		for key := range expected.Headers {
+			if got.Headers.Get(key) != expected.Headers.Get(key) {
+				t.Errorf("Request %d: expected header %s=%q, got %q", i, key, expected.Headers.Get(key), got.Headers.Get(key))
+			}
+		}
+	}
+	if parsed.Requests[0].Description != "Get a user by id" {
+		t.Errorf("Expected description to be joined, got %q", parsed.Requests[0].Description)
+	}
+	if parsed.Requests[1].Title != "Create a user" {
+		t.Errorf("Expected title to be kept alongside @name, got %q", parsed.Requests[1].Title)
+	}
+	if len(parsed.Requests[1].Captures) != 1 || parsed.Requests[1].Captures[0].VariableName != "userId" {
+		t.Errorf("Expected capture to round-trip, got %v", parsed.Requests[1].Captures)
+	}
+}
@@ -2,17 +2,37 @@ package parser
 
 import (
 	"bufio"
+	"errors"
+	"fmt"
 	"net/http"
 	"os"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 )
 
 var (
-	requestLineRegex = regexp.MustCompile(`^(GET|POST|PUT|DELETE|PATCH|HEAD|OPTIONS|TRACE|CONNECT)\s+(.+?)(?:\s+HTTP/[\d.]+)?$`)
-	variableRegex    = regexp.MustCompile(`\{\{(.+?)\}\}`)
-	separatorRegex   = regexp.MustCompile(`^###\s*(.*)$`)
-	captureRegex     = regexp.MustCompile(`^@capture\s+(\w+)\s*=\s*(.+)$`)
+	requestLineRegex    = regexp.MustCompile(`^(GET|POST|PUT|DELETE|PATCH|HEAD|OPTIONS|TRACE|CONNECT)\s+(.+?)(?:\s+HTTP/[\d.]+)?$`)
+	variableRegex       = regexp.MustCompile(`\{\{(.+?)\}\}`)
+	plainReferenceRegex = regexp.MustCompile(`^\{\{\s*[A-Za-z_][A-Za-z0-9_]*\s*\}\}$`)
+	separatorRegex      = regexp.MustCompile(`^###\s*(.*)$`)
+	continuationRegex   = regexp.MustCompile(`^\s+([?&/].*?)(?:\s+HTTP/[\d.]+)?$`)
+	captureRegex        = regexp.MustCompile(`^@capture\s+(\w+)\s*=\s*(.+)$`)
+	pluginRegex         = regexp.MustCompile(`^@plugin\s+(\S+)(.*)$`)
+	retryRegex          = regexp.MustCompile(`^@retry(?:\s+(.*))?$`)
+	dependsOnRegex      = regexp.MustCompile(`^@depends-on\s+(.+)$`)
+	snapshotIgnoreRegex = regexp.MustCompile(`^@snapshot-ignore\s+(.+)$`)
+	schemaRegex         = regexp.MustCompile(`^@schema\s+(.+)$`)
+	tagRegex            = regexp.MustCompile(`^@tags?\s+(.+)$`)
+	nameRegex           = regexp.MustCompile(`^@name\s+(.+)$`)
+	timeoutRegex        = regexp.MustCompile(`^@timeout(?:\s+(.*))?$`)
+	skipRegex           = regexp.MustCompile(`^@skip(?:\s+(.*))?$`)
+	onlyRegex           = regexp.MustCompile(`^@only\s*$`)
+	promptRegex         = regexp.MustCompile(`^@prompt\s+(\w+)(.*)$`)
+	dataRegex           = regexp.MustCompile(`^@data\s+(.+)$`)
+	responseRegex       = regexp.MustCompile(`^HTTP/[\d.]+\s+(\d{3})(?:\s+(.*))?$`)
 )
 
 func ParseFile(path string) (*HTTPFile, error) {
@@ -36,30 +56,97 @@ func ParseFile(path string) (*HTTPFile, error) {
 	inBody := false
 	bodyLines := []string{}
 	descriptionLines := []string{}
+	inResponse := false
+	inResponseBody := false
+	responseBodyLines := []string{}
+	// urlOpen is set after a request line, while indented lines starting
+	// with ?, & or / continue the URL.
+	urlOpen := false
+	// An example response starts with a status line after a blank line, so
+	// a body line such as `HTTP/1.1 200` is kept in the body.
+	previousLine := ""
+
+	finishRequest := func() {
+		if currentRequest == nil || currentRequest.Method == "" {
+			return
+		}
+		if len(bodyLines) > 0 {
+			currentRequest.Body = strings.Join(bodyLines, "\n")
+		}
+		if currentRequest.ExampleResponse != nil {
+			currentRequest.ExampleResponse.Body = strings.TrimRight(strings.Join(responseBodyLines, "\n"), "\n")
+		}
+		httpFile.Requests = append(httpFile.Requests, *currentRequest)
+	}
 
 	for scanner.Scan() {
 		lineNum++
 		line := scanner.Text()
+		afterBlank := strings.TrimSpace(previousLine) == ""
+		previousLine = line
 
 		if separatorRegex.MatchString(line) {
-			if currentRequest != nil && currentRequest.Method != "" {
-				if len(bodyLines) > 0 {
-					currentRequest.Body = strings.Join(bodyLines, "\n")
-				}
-				httpFile.Requests = append(httpFile.Requests, *currentRequest)
-			}
+			finishRequest()
 			match := separatorRegex.FindStringSubmatch(line)
 			currentRequest = &HTTPRequest{
 				Headers:    make(http.Header),
 				Name:       strings.TrimSpace(match[1]),
+				Title:      strings.TrimSpace(match[1]),
 				LineNumber: lineNum,
 			}
 			inBody = false
 			bodyLines = []string{}
 			descriptionLines = []string{}
+			inResponse = false
+			inResponseBody = false
+			responseBodyLines = []string{}
 			continue
 		}
 
+		if urlOpen {
+			if matches := continuationRegex.FindStringSubmatch(line); matches != nil {
+				currentRequest.URL += strings.TrimSpace(matches[1])
+				continue
+			}
+			urlOpen = false
+		}
+
+		if inResponse {
+			if inResponseBody {
+				responseBodyLines = append(responseBodyLines, line)
+				continue
+			}
+			if strings.TrimSpace(line) == "" {
+				inResponseBody = true
+				continue
+			}
+			parts := strings.SplitN(line, ":", 2)
+			if len(parts) == 2 && strings.TrimSpace(parts[0]) != "" {
+				currentRequest.ExampleResponse.Headers.Add(strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]))
+			} else {
+				inResponseBody = true
+				responseBodyLines = append(responseBodyLines, line)
+			}
+			continue
+		}
+
+		if currentRequest != nil && currentRequest.Method != "" && afterBlank {
+			if matches := responseRegex.FindStringSubmatch(strings.TrimSpace(line)); matches != nil {
+				for len(bodyLines) > 0 && strings.TrimSpace(bodyLines[len(bodyLines)-1]) == "" {
+					bodyLines = bodyLines[:len(bodyLines)-1]
+				}
+				status, _ := strconv.Atoi(matches[1])
+				currentRequest.ExampleResponse = &ExampleResponse{
+					StatusCode: status,
+					Status:     strings.TrimSpace(matches[1] + " " + matches[2]),
+					Headers:    make(http.Header),
+				}
+				inBody = false
+				inResponse = true
+				continue
+			}
+		}
+
 		if inBody {
 			bodyLines = append(bodyLines, line)
 			continue
@@ -79,6 +166,50 @@ func ParseFile(path string) (*HTTPFile, error) {
 							VariableName: matches[1],
 							JSONPath:     strings.TrimSpace(matches[2]),
 						})
+					} else if matches := pluginRegex.FindStringSubmatch(comment); len(matches) == 3 {
+						currentRequest.Plugins = append(currentRequest.Plugins, PluginDirective{
+							Name: matches[1],
+							Args: strings.Fields(matches[2]),
+						})
+					} else if matches := retryRegex.FindStringSubmatch(comment); len(matches) == 2 {
+						policy, err := parseRetryDirective(matches[1])
+						if err != nil {
+							return nil, ParseError{Line: lineNum, Message: fmt.Sprintf("line %d: %v", lineNum, err)}
+						}
+						currentRequest.Retry = policy
+					} else if matches := dependsOnRegex.FindStringSubmatch(comment); len(matches) == 2 {
+						currentRequest.DependsOn = append(currentRequest.DependsOn, strings.TrimSpace(matches[1]))
+					} else if matches := tagRegex.FindStringSubmatch(comment); len(matches) == 2 {
+						for _, tag := range strings.FieldsFunc(matches[1], func(r rune) bool { return r == ',' || r == ' ' || r == '\t' }) {
+							if !currentRequest.HasTag(tag) {
+								currentRequest.Tags = append(currentRequest.Tags, tag)
+							}
+						}
+					} else if matches := nameRegex.FindStringSubmatch(comment); len(matches) == 2 {
+						currentRequest.Name = strings.TrimSpace(matches[1])
+					} else if matches := timeoutRegex.FindStringSubmatch(comment); len(matches) == 2 {
+						timeout, err := time.ParseDuration(strings.TrimSpace(matches[1]))
+						if err != nil || timeout <= 0 {
+							return nil, ParseError{Line: lineNum, Message: fmt.Sprintf("line %d: invalid @timeout %q", lineNum, strings.TrimSpace(matches[1]))}
+						}
+						currentRequest.Timeout = timeout
+					} else if matches := skipRegex.FindStringSubmatch(comment); len(matches) == 2 {
+						currentRequest.Skip = true
+						currentRequest.SkipReason = strings.TrimSpace(matches[1])
+					} else if onlyRegex.MatchString(comment) {
+						currentRequest.Only = true
+					} else if matches := promptRegex.FindStringSubmatch(comment); len(matches) == 3 {
+						currentRequest.Prompts = append(currentRequest.Prompts, parsePromptDirective(matches[1], matches[2]))
+					} else if matches := dataRegex.FindStringSubmatch(comment); len(matches) == 2 {
+						currentRequest.Data = strings.TrimSpace(matches[1])
+					} else if matches := schemaRegex.FindStringSubmatch(comment); len(matches) == 2 {
+						currentRequest.Schema = strings.TrimSpace(matches[1])
+					} else if matches := snapshotIgnoreRegex.FindStringSubmatch(comment); len(matches) == 2 {
+						for _, rule := range strings.Split(matches[1], ",") {
+							if rule = strings.TrimSpace(rule); rule != "" {
+								currentRequest.SnapshotIgnore = append(currentRequest.SnapshotIgnore, rule)
+							}
+						}
 					} else {
 						descriptionLines = append(descriptionLines, comment)
 					}
@@ -90,7 +221,15 @@ func ParseFile(path string) (*HTTPFile, error) {
 		if strings.HasPrefix(line, "@") {
 			parts := strings.SplitN(line[1:], "=", 2)
 			if len(parts) == 2 {
-				httpFile.Variables[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+				name := strings.TrimSpace(parts[0])
+				if strings.HasPrefix(name, "!") {
+					name = strings.TrimSpace(name[1:])
+					if httpFile.Secrets == nil {
+						httpFile.Secrets = make(map[string]bool)
+					}
+					httpFile.Secrets[name] = true
+				}
+				httpFile.Variables[name] = strings.TrimSpace(parts[1])
 			}
 			continue
 		}
@@ -108,6 +247,7 @@ func ParseFile(path string) (*HTTPFile, error) {
 			matches := requestLineRegex.FindStringSubmatch(line)
 			currentRequest.Method = matches[1]
 			currentRequest.URL = matches[2]
+			urlOpen = true
 			if len(descriptionLines) > 0 {
 				currentRequest.Description = strings.Join(descriptionLines, " ")
 				descriptionLines = []string{}
@@ -162,12 +302,7 @@ func ParseFile(path string) (*HTTPFile, error) {
 		}
 	}
 
-	if currentRequest != nil && currentRequest.Method != "" {
-		if len(bodyLines) > 0 {
-			currentRequest.Body = strings.Join(bodyLines, "\n")
-		}
-		httpFile.Requests = append(httpFile.Requests, *currentRequest)
-	}
+	finishRequest()
 
 	if err := scanner.Err(); err != nil {
 		return nil, err
@@ -176,25 +311,126 @@ func ParseFile(path string) (*HTTPFile, error) {
 	return httpFile, nil
 }
 
+// ReplaceVariables replaces the {{...}} references it can resolve and
+// leaves the rest as written. Use ExpandVariables where an unresolved
+// reference is an error.
 func ReplaceVariables(text string, variables map[string]string) string {
+	r := newResolver(variables)
 	return variableRegex.ReplaceAllStringFunc(text, func(match string) string {
-		varName := variableRegex.FindStringSubmatch(match)[1]
-		if value, ok := variables[varName]; ok {
+		expr, err := parseVariableExpr(variableRegex.FindStringSubmatch(match)[1])
+		if err != nil {
+			return match
+		}
+		if value, ok, err := expr.evaluate(r.lookup); ok && err == nil {
 			return value
 		}
 		return match
 	})
 }
 
-func (r *HTTPRequest) ApplyVariables(variables map[string]string) {
-	r.URL = ReplaceVariables(r.URL, variables)
-	r.Body = ReplaceVariables(r.Body, variables)
-	
-	for key, values := range r.Headers {
-		for i, value := range values {
-			r.Headers[key][i] = ReplaceVariables(value, variables)
+// VariableReferences returns the names of the variables used in the URL,
+// headers and body, in order of first use.
+func (r *HTTPRequest) VariableReferences() []string {
+	return r.references(VariableNames)
+}
+
+// RequiredVariableReferences is like VariableReferences but leaves out the
+// variables that are only used with a `??` fallback.
+func (r *HTTPRequest) RequiredVariableReferences() []string {
+	return r.references(RequiredVariableNames)
+}
+
+func (r *HTTPRequest) references(variableNames func(text string) []string) []string {
+	seen := make(map[string]bool)
+	var names []string
+	collect := func(text string) {
+		for _, name := range variableNames(text) {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+
+	collect(r.URL)
+	headerNames := make([]string, 0, len(r.Headers))
+	for key := range r.Headers {
+		headerNames = append(headerNames, key)
+	}
+	sort.Strings(headerNames)
+	for _, key := range headerNames {
+		for _, value := range r.Headers[key] {
+			collect(value)
+		}
+	}
+	collect(r.Body)
+	return names
+}
+
+// ApplyVariables expands the variables in the URL, headers and body,
+// following variables that refer to other variables. It fails when a
+// reference is invalid, names a variable that is not defined and has no
+// fallback, or is part of a cycle, listing every unresolved name.
+func (r *HTTPRequest) ApplyVariables(variables map[string]string) error {
+	return r.applyVariables(newResolver(variables))
+}
+
+// ApplyExpressions is like ApplyVariables but leaves plain {{name}}
+// references as written, for commands that read them from the environment.
+// Fallbacks, filters and the variables other variables refer to are still
+// resolved, so they fail when a variable they need is not defined.
+func (r *HTTPRequest) ApplyExpressions(variables map[string]string) error {
+	resolver := newResolver(variables)
+	resolver.keepPlain = true
+	return r.applyVariables(resolver)
+}
+
+func (r *HTTPRequest) applyVariables(resolver *resolver) error {
+	var unresolved []string
+	// resolve reports whether every reference in text was resolved.
+	resolve := func(text string) (string, bool, error) {
+		if resolver.keepPlain && plainReferenceRegex.MatchString(text) {
+			return text, false, nil
 		}
+		expanded, err := resolver.expand(text)
+		var unresolvedErr *UnresolvedError
+		if errors.As(err, &unresolvedErr) {
+			for _, name := range unresolvedErr.Names {
+				unresolved = appendUnique(unresolved, name)
+			}
+			return expanded, false, nil
+		}
+		return expanded, err == nil, err
+	}
+	expand := func(text string) (string, error) {
+		expanded, _, err := resolve(text)
+		return expanded, err
+	}
+
+	var err error
+	if r.URL, err = expandURL(r.URL, resolve); err != nil {
+		return err
 	}
+	keys := make([]string, 0, len(r.Headers))
+	for key := range r.Headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for i, value := range r.Headers[key] {
+			if r.Headers[key][i], err = expand(value); err != nil {
+				return err
+			}
+		}
+	}
+	if r.Body, err = expand(r.Body); err != nil {
+		return err
+	}
+
+	if len(unresolved) > 0 {
+		return &UnresolvedError{Names: unresolved}
+	}
+	return nil
 }
 
 func ParseString(content string) (*HTTPFile, error) {
@@ -214,4 +450,4 @@ func ParseString(content string) (*HTTPFile, error) {
 	}
 
 	return ParseFile(tmpFile.Name())
-}
\ No newline at end of file
+}
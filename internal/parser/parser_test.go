@@ -313,3 +313,95 @@ content-type: application/json
 		t.Errorf("Expected body to contain JSON, got: %s", req.Body)
 	}
 }
+
+func TestParseFile_ExampleResponse(t *testing.T) {
+	content := `### Get User
+GET {{baseUrl}}/users/{{id}}
+
+HTTP/1.1 200 OK
+Content-Type: application/json
+
+{"id": "{{id}}", "name": "Jane"}
+
+### Create User
+POST {{baseUrl}}/users
+Content-Type: application/json
+
+{"name": "Jane"}
+
+HTTP/1.1 201 Created
+Location: /users/1
+
+### No Example
+DELETE {{baseUrl}}/users/1`
+
+	httpFile, err := ParseString(content)
+	if err != nil {
+		t.Fatalf("ParseString failed: %v", err)
+	}
+
+	if len(httpFile.Requests) != 3 {
+		t.Fatalf("Expected 3 requests, got %d", len(httpFile.Requests))
+	}
+
+	get := httpFile.Requests[0]
+	if get.Body != "" {
+		t.Errorf("Expected example response to stay out of the request body, got %q", get.Body)
+	}
+	if get.ExampleResponse == nil {
+		t.Fatal("Expected example response for GET")
+	}
+	if get.ExampleResponse.StatusCode != 200 || get.ExampleResponse.Status != "200 OK" {
+		t.Errorf("Unexpected status %d %q", get.ExampleResponse.StatusCode, get.ExampleResponse.Status)
+	}
+	if get.ExampleResponse.Headers.Get("Content-Type") != "application/json" {
+		t.Errorf("Expected Content-Type header, got %v", get.ExampleResponse.Headers)
+	}
+	if get.ExampleResponse.Body != `{"id": "{{id}}", "name": "Jane"}` {
+		t.Errorf("Unexpected example body %q", get.ExampleResponse.Body)
+	}
+
+	post := httpFile.Requests[1]
+	if post.Body != `{"name": "Jane"}` {
+		t.Errorf("Expected request body without trailing blank lines, got %q", post.Body)
+	}
+	if post.ExampleResponse == nil || post.ExampleResponse.StatusCode != 201 {
+		t.Fatalf("Expected 201 example response, got %+v", post.ExampleResponse)
+	}
+	if post.ExampleResponse.Headers.Get("Location") != "/users/1" || post.ExampleResponse.Body != "" {
+		t.Errorf("Unexpected example response %+v", post.ExampleResponse)
+	}
+
+	if httpFile.Requests[2].ExampleResponse != nil {
+		t.Errorf("Expected no example response for DELETE")
+	}
+}
+
+func TestParseFile_StatusLineInBody(t *testing.T) {
+	content := `### Upload Transcript
+POST https://api.example.com/transcripts
+Content-Type: text/plain
+
+> GET /health
+HTTP/1.1 200 OK
+< done
+
+### Next
+GET https://api.example.com/health`
+
+	httpFile, err := ParseString(content)
+	if err != nil {
+		t.Fatalf("ParseString failed: %v", err)
+	}
+	if len(httpFile.Requests) != 2 {
+		t.Fatalf("Expected 2 requests, got %d", len(httpFile.Requests))
+	}
+
+	upload := httpFile.Requests[0]
+	if upload.ExampleResponse != nil {
+		t.Errorf("Expected no example response, got %+v", upload.ExampleResponse)
+	}
+	if upload.Body != "> GET /health\nHTTP/1.1 200 OK\n< done\n" {
+		t.Errorf("Expected the status line to stay in the body, got %q", upload.Body)
+	}
+}
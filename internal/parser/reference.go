@@ -0,0 +1,70 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+)
+
+var responseReferenceRegex = regexp.MustCompile(`^([^.\s]+)\.response\.(body|headers)\.(.+)$`)
+
+// ResponseReference is a `{{login.response.body.$.token}}` or
+// `{{login.response.headers.X-Request-Id}}` variable, read from the last
+// response of the request named login.
+type ResponseReference struct {
+	Request string
+	// Part is "body" or "headers".
+	Part string
+	// Path is a header name, `*` for the whole body, a JSONPath starting
+	// with $, an XPath starting with /, or a gjson path.
+	Path string
+}
+
+// ParseResponseReference reports whether a variable name refers to the
+// response of another request.
+func ParseResponseReference(name string) (ResponseReference, bool) {
+	matches := responseReferenceRegex.FindStringSubmatch(name)
+	if matches == nil {
+		return ResponseReference{}, false
+	}
+	return ResponseReference{Request: matches[1], Part: matches[2], Path: matches[3]}, true
+}
+
+// Capture returns the capture rule that reads the same value, or false for
+// the whole body.
+func (r ResponseReference) Capture() (CaptureRule, bool) {
+	if r.Part == "headers" {
+		return CaptureRule{JSONPath: CaptureHeader + "." + r.Path}, true
+	}
+	switch {
+	case r.Path == "*" || r.Path == "$":
+		return CaptureRule{}, false
+	case strings.HasPrefix(r.Path, "/"):
+		return CaptureRule{JSONPath: CaptureXPath + ":" + r.Path}, true
+	case strings.HasPrefix(r.Path, "$"):
+		return CaptureRule{JSONPath: CaptureBody + "." + jsonPathToGJSON(r.Path)}, true
+	default:
+		return CaptureRule{JSONPath: CaptureBody + "." + r.Path}, true
+	}
+}
+
+var jsonPathIndexRegex = regexp.MustCompile(`\[\s*(?:(\d+|\*)|'([^']*)'|"([^"]*)")\s*\]`)
+
+// jsonPathToGJSON converts the JSONPath subset REST Client files use,
+// such as `$.items[0]['name']`, into a gjson path.
+func jsonPathToGJSON(path string) string {
+	path = strings.TrimPrefix(path, "$")
+	path = jsonPathIndexRegex.ReplaceAllStringFunc(path, func(match string) string {
+		parts := jsonPathIndexRegex.FindStringSubmatch(match)
+		switch {
+		case parts[1] == "*":
+			return ".#"
+		case parts[1] != "":
+			return "." + parts[1]
+		case parts[2] != "":
+			return "." + parts[2]
+		default:
+			return "." + parts[3]
+		}
+	})
+	return strings.TrimPrefix(path, ".")
+}
@@ -0,0 +1,37 @@
+package parser
+
+import "testing"
+
+func TestParseResponseReference(t *testing.T) {
+	tests := []struct {
+		name    string
+		request string
+		capture string
+		ok      bool
+	}{
+		{name: "login.response.body.$.token", request: "login", capture: "body.token", ok: true},
+		{name: "login.response.body.$.items[0]['id']", request: "login", capture: "body.items.0.id", ok: true},
+		{name: "login.response.body.$.items[*].id", request: "login", capture: "body.items.#.id", ok: true},
+		{name: "login.response.body.data.token", request: "login", capture: "body.data.token", ok: true},
+		{name: "login.response.body.//user/id", request: "login", capture: "xpath://user/id", ok: true},
+		{name: "login.response.body.*", request: "login", capture: "", ok: true},
+		{name: "login.response.headers.X-Request-Id", request: "login", capture: "header.X-Request-Id", ok: true},
+		{name: "login.request.body.$.token"},
+		{name: "token"},
+	}
+
+	for _, tt := range tests {
+		ref, ok := ParseResponseReference(tt.name)
+		if ok != tt.ok {
+			t.Errorf("%s: expected ok %v, got %v", tt.name, tt.ok, ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		capture, _ := ref.Capture()
+		if ref.Request != tt.request || capture.JSONPath != tt.capture {
+			t.Errorf("%s: expected %s and capture %q, got %s and %q", tt.name, tt.request, tt.capture, ref.Request, capture.JSONPath)
+		}
+	}
+}
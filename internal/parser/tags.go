@@ -0,0 +1,69 @@
+package parser
+
+import (
+	"sort"
+	"strings"
+)
+
+// HasTag reports whether the request has the tag, ignoring case.
+func (r *HTTPRequest) HasTag(tag string) bool {
+	for _, t := range r.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchesTags reports whether the request should run for the --tag and
+// --exclude-tag filters: it needs one of the included tags, when there are
+// any, and none of the excluded ones.
+func (r *HTTPRequest) MatchesTags(include, exclude []string) bool {
+	for _, tag := range exclude {
+		if r.HasTag(tag) {
+			return false
+		}
+	}
+	if len(include) == 0 {
+		return true
+	}
+	for _, tag := range include {
+		if r.HasTag(tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// Select reports which requests to run: those matching the tag filters
+// and, when any of them is marked @only, only the @only ones.
+func Select(requests []HTTPRequest, include, exclude []string) []bool {
+	selected := make([]bool, len(requests))
+	focused := false
+	for i := range requests {
+		selected[i] = requests[i].MatchesTags(include, exclude)
+		focused = focused || selected[i] && requests[i].Only
+	}
+	if focused {
+		for i := range requests {
+			selected[i] = selected[i] && requests[i].Only
+		}
+	}
+	return selected
+}
+
+// Tags returns every tag used in the file, sorted and without duplicates.
+func (f *HTTPFile) Tags() []string {
+	seen := make(map[string]bool)
+	var tags []string
+	for _, req := range f.Requests {
+		for _, tag := range req.Tags {
+			if key := strings.ToLower(tag); !seen[key] {
+				seen[key] = true
+				tags = append(tags, tag)
+			}
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
@@ -1,12 +1,73 @@
 package parser
 
-import "net/http"
+import (
+	"net/http"
+	"time"
+)
 
+// CaptureRule is a `# @capture name = expression` directive. JSONPath holds
+// the expression: a gjson path into a JSON body, or one of the source
+// prefixes handled by Source.
 type CaptureRule struct {
 	VariableName string
 	JSONPath     string
 }
 
+// Capture sources, as returned by CaptureRule.Source.
+const (
+	CaptureBody   = "body"
+	CaptureStatus = "status"
+	CaptureHeader = "header"
+	CaptureCookie = "cookie"
+	CaptureRegex  = "regex"
+	CaptureXPath  = "xpath"
+)
+
+// Prompt is a `# @prompt name [description] [--secret]` directive: a
+// variable asked for each time the request runs.
+type Prompt struct {
+	Name        string
+	Description string
+	// Secret input is not echoed, and the value is masked in output.
+	Secret bool
+}
+
+type PluginDirective struct {
+	Name string
+	Args []string
+}
+
+const (
+	BackoffNone        = "none"
+	BackoffConstant    = "constant"
+	BackoffLinear      = "linear"
+	BackoffExponential = "exponential"
+
+	RetryOn5xx     = "5xx"
+	RetryOn4xx     = "4xx"
+	RetryOnTimeout = "timeout"
+	RetryOnNetwork = "network"
+)
+
+// RetryPolicy is parsed from `# @retry 3 backoff=exponential on=5xx,timeout`.
+// Zero values are filled in with executor defaults. Methods overrides the
+// default of only retrying idempotent methods; "*" allows every method.
+type RetryPolicy struct {
+	MaxRetries int
+	Backoff    string
+	Delay      time.Duration
+	On         []string
+	Methods    []string
+}
+
+// ExampleResponse is an `HTTP/1.1 200 OK` block written after a request.
+type ExampleResponse struct {
+	StatusCode int
+	Status     string
+	Headers    http.Header
+	Body       string
+}
+
 type HTTPRequest struct {
 	Method      string
 	URL         string
@@ -17,12 +78,44 @@ type HTTPRequest struct {
 	LineNumber  int
 	Variables   map[string]string
 	Captures    []CaptureRule
+	Plugins     []PluginDirective
+	Retry       *RetryPolicy
+	DependsOn   []string
+	Schema      string
+	// SnapshotIgnore lists fields masked in snapshots, from
+	// `# @snapshot-ignore body.createdAt, header Date`.
+	SnapshotIgnore []string
+	// Tags come from `# @tag smoke, payments` and select requests with
+	// --tag and --exclude-tag.
+	Tags []string
+	// Title is the text after ###. Name is the same unless `# @name`
+	// gives the request a separate identifier.
+	Title string
+	// Timeout overrides the global timeout for this request.
+	Timeout time.Duration
+	// Skip is set by `# @skip [reason]`; Only by `# @only`, which runs
+	// the file's @only requests and nothing else.
+	Skip       bool
+	SkipReason string
+	Only       bool
+	Prompts    []Prompt
+	// Data is the CSV or JSON file from `# @data ./users.csv`; the request
+	// runs once per row. Iteration numbers the row a copy runs with, from
+	// 1, and is 0 for a request without @data. The row's columns are in
+	// Variables, which override the file variables.
+	Data      string
+	Iteration int
+
+	ExampleResponse *ExampleResponse
 }
 
 type HTTPFile struct {
 	Path     string
 	Requests []HTTPRequest
 	Variables map[string]string
+	// Secrets holds the names of variables declared with `@!name = value`,
+	// whose values are masked in output.
+	Secrets map[string]bool
 }
 
 type ParseError struct {
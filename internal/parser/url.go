@@ -0,0 +1,153 @@
+package parser
+
+import (
+	"net/url"
+	"strings"
+)
+
+// QueryParam is one name=value pair of a request's query string.
+type QueryParam struct {
+	Name  string
+	Value string
+}
+
+// QueryParams returns the query parameters of the URL in order, decoded.
+// Variables are left as written.
+func (r *HTTPRequest) QueryParams() []QueryParam {
+	_, query, ok := strings.Cut(r.URL, "?")
+	if !ok {
+		return nil
+	}
+	query, _, _ = strings.Cut(query, "#")
+
+	var params []QueryParam
+	for _, pair := range strings.Split(query, "&") {
+		if pair == "" {
+			continue
+		}
+		name, value, _ := strings.Cut(pair, "=")
+		params = append(params, QueryParam{Name: unescapeQuery(name), Value: unescapeQuery(value)})
+	}
+	return params
+}
+
+func unescapeQuery(s string) string {
+	if unescaped, err := url.QueryUnescape(s); err == nil {
+		return unescaped
+	}
+	return s
+}
+
+// expandURL expands the variables in a URL like resolve, and query-escapes
+// the values of variables in a query parameter value, so `?q={{q}}` with
+// `rock & roll` sends `q=rock+%26+roll` instead of splitting the parameter.
+// Other values, such as `{{baseUrl}}`, and values already passed through
+// the urlencode filter are inserted as they are.
+func expandURL(rawURL string, resolve func(string) (string, bool, error)) (string, error) {
+	var b strings.Builder
+	inQuery, inValue, inFragment := false, false, false
+	scan := func(text string) {
+		for i := 0; i < len(text); i++ {
+			switch c := text[i]; {
+			case inFragment:
+			case c == '#':
+				inFragment, inQuery, inValue = true, false, false
+			case c == '?' && !inQuery:
+				inQuery = true
+			case c == '&' && inQuery:
+				inValue = false
+			case c == '=' && inQuery:
+				inValue = true
+			}
+		}
+	}
+
+	last := 0
+	for _, loc := range variableRegex.FindAllStringIndex(rawURL, -1) {
+		literal := rawURL[last:loc[0]]
+		b.WriteString(literal)
+		scan(literal)
+
+		match := rawURL[loc[0]:loc[1]]
+		value, resolved, err := resolve(match)
+		if err != nil {
+			return "", err
+		}
+		if resolved && inValue && !urlEncoded(match) {
+			value = url.QueryEscape(value)
+		} else {
+			scan(value)
+		}
+		b.WriteString(value)
+		last = loc[1]
+	}
+	b.WriteString(rawURL[last:])
+	return b.String(), nil
+}
+
+// urlEncoded reports whether a {{...}} reference ends with the urlencode
+// filter.
+func urlEncoded(match string) bool {
+	expr, err := parseVariableExpr(variableRegex.FindStringSubmatch(match)[1])
+	return err == nil && len(expr.filters) > 0 && expr.filters[len(expr.filters)-1].name == "urlencode"
+}
+
+// EncodeURL percent-encodes the characters that cannot appear as written
+// in the path, query or fragment of a URL, such as spaces, quotes and
+// non-ASCII text. Existing %XX escapes and the characters that give a URL
+// its structure, including & and = in the query, are kept, so encoding an
+// encoded URL changes nothing.
+func EncodeURL(rawURL string) string {
+	rest := rawURL
+	var prefix string
+	if i := strings.Index(rest, "://"); i >= 0 {
+		authority := rest[i+3:]
+		end := strings.IndexAny(authority, "/?#")
+		if end < 0 {
+			return rawURL
+		}
+		prefix = rest[:i+3+end]
+		rest = authority[end:]
+	}
+
+	rest, fragment, hasFragment := strings.Cut(rest, "#")
+	path, query, hasQuery := strings.Cut(rest, "?")
+
+	encoded := prefix + escapeInvalid(path, "!$&'()*+,;=:@/")
+	if hasQuery {
+		encoded += "?" + escapeInvalid(query, "!$&'()*+,;=:@/?")
+	}
+	if hasFragment {
+		encoded += "#" + escapeInvalid(fragment, "!$&'()*+,;=:@/?")
+	}
+	return encoded
+}
+
+// escapeInvalid percent-encodes every byte of s that is neither unreserved,
+// in allowed, nor part of a valid %XX escape.
+func escapeInvalid(s string, allowed string) string {
+	const hex = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case isUnreserved(c) || strings.IndexByte(allowed, c) >= 0:
+			b.WriteByte(c)
+		case c == '%' && i+2 < len(s) && isHex(s[i+1]) && isHex(s[i+2]):
+			b.WriteByte(c)
+		default:
+			b.WriteByte('%')
+			b.WriteByte(hex[c>>4])
+			b.WriteByte(hex[c&15])
+		}
+	}
+	return b.String()
+}
+
+func isUnreserved(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
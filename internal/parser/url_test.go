@@ -0,0 +1,100 @@
+package parser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseFile_MultiLineURL(t *testing.T) {
+	content := `### Search
+GET https://api.example.com
+    /users
+    ?page=1
+    &q=Zoë Smith
+    &size=20 HTTP/1.1
+Accept: application/json
+
+### Next
+GET https://api.example.com/health
+`
+
+	httpFile, err := ParseString(content)
+	if err != nil {
+		t.Fatalf("ParseString failed: %v", err)
+	}
+	if len(httpFile.Requests) != 2 {
+		t.Fatalf("Expected 2 requests, got %d", len(httpFile.Requests))
+	}
+
+	req := httpFile.Requests[0]
+	if req.URL != "https://api.example.com/users?page=1&q=Zoë Smith&size=20" {
+		t.Errorf("Expected continuation lines to be joined, got %q", req.URL)
+	}
+	if req.Headers.Get("Accept") != "application/json" || req.Body != "" {
+		t.Errorf("Expected headers after the URL, got %v and body %q", req.Headers, req.Body)
+	}
+
+	expected := []QueryParam{{"page", "1"}, {"q", "Zoë Smith"}, {"size", "20"}}
+	if params := req.QueryParams(); !reflect.DeepEqual(params, expected) {
+		t.Errorf("Expected query params %v, got %v", expected, params)
+	}
+}
+
+func TestEncodeURL(t *testing.T) {
+	tests := []struct {
+		url      string
+		expected string
+	}{
+		{"https://api.example.com/users?page=1&size=20", "https://api.example.com/users?page=1&size=20"},
+		{"https://api.example.com/users?q=Zoë Smith&tag=a+b", "https://api.example.com/users?q=Zo%C3%AB%20Smith&tag=a+b"},
+		{"https://api.example.com/files/my report.pdf", "https://api.example.com/files/my%20report.pdf"},
+		{"https://api.example.com/search?q=100%&filter[name]=x", "https://api.example.com/search?q=100%25&filter%5Bname%5D=x"},
+		{"https://api.example.com/a%20b?q=%C3%AB", "https://api.example.com/a%20b?q=%C3%AB"},
+		{"https://api.example.com/docs#section two", "https://api.example.com/docs#section%20two"},
+		{"https://api.example.com", "https://api.example.com"},
+		{"/relative path?x=\"y\"", "/relative%20path?x=%22y%22"},
+	}
+
+	for _, tt := range tests {
+		if result := EncodeURL(tt.url); result != tt.expected {
+			t.Errorf("EncodeURL(%q): expected %q, got %q", tt.url, tt.expected, result)
+		}
+		if twice := EncodeURL(EncodeURL(tt.url)); twice != tt.expected {
+			t.Errorf("Expected EncodeURL to be idempotent for %q, got %q", tt.url, twice)
+		}
+	}
+}
+
+func TestApplyVariables_EncodesQueryValues(t *testing.T) {
+	variables := map[string]string{
+		"baseUrl": "https://api.example.com/v1",
+		"email":   "a+b@x.com",
+		"q":       "rock & roll",
+		"tag":     "#1",
+		"path":    "users/7",
+		"filter":  "page=2",
+	}
+	tests := []struct {
+		url      string
+		expected string
+	}{
+		{"{{baseUrl}}/users?email={{email}}", "https://api.example.com/v1/users?email=a%2Bb%40x.com"},
+		{"{{baseUrl}}/search?q={{q}}&limit=10", "https://api.example.com/v1/search?q=rock+%26+roll&limit=10"},
+		{"{{baseUrl}}/search?tag={{tag}}#results", "https://api.example.com/v1/search?tag=%231#results"},
+		{"{{baseUrl}}/{{path}}?{{filter}}", "https://api.example.com/v1/users/7?page=2"},
+		{"{{baseUrl}}/search?q={{q | urlencode}}", "https://api.example.com/v1/search?q=rock+%26+roll"},
+		{"{{baseUrl}}/search?q={{missing}}", "https://api.example.com/v1/search?q={{missing}}"},
+	}
+
+	for _, tt := range tests {
+		req := HTTPRequest{URL: tt.url}
+		_ = req.ApplyVariables(variables)
+		if req.URL != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.url, tt.expected, req.URL)
+		}
+		if EncodeURL(req.URL) != req.URL && !strings.Contains(tt.url, "missing") {
+			t.Errorf("%s: expected the sent URL to be unchanged, got %q", tt.url, EncodeURL(req.URL))
+		}
+	}
+}
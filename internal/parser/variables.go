@@ -0,0 +1,382 @@
+package parser
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// variableExpr is the text between {{ and }}: a variable name, an optional
+// `?? "fallback"` and any number of `| filter args` pipes.
+type variableExpr struct {
+	name     string
+	fallback *string
+	filters  []variableFilter
+}
+
+type variableFilter struct {
+	name string
+	args []string
+}
+
+// filters maps each filter name to its implementation and argument count.
+var filters = map[string]struct {
+	args  int
+	apply func(value string, args []string) (string, error)
+}{
+	"base64": {apply: func(value string, _ []string) (string, error) {
+		return base64.StdEncoding.EncodeToString([]byte(value)), nil
+	}},
+	"urlencode": {apply: func(value string, _ []string) (string, error) {
+		return url.QueryEscape(value), nil
+	}},
+	"sha256": {apply: func(value string, _ []string) (string, error) {
+		sum := sha256.Sum256([]byte(value))
+		return hex.EncodeToString(sum[:]), nil
+	}},
+	"upper": {apply: func(value string, _ []string) (string, error) {
+		return strings.ToUpper(value), nil
+	}},
+	"lower": {apply: func(value string, _ []string) (string, error) {
+		return strings.ToLower(value), nil
+	}},
+	"trim": {apply: func(value string, _ []string) (string, error) {
+		return strings.TrimSpace(value), nil
+	}},
+	"format": {args: 1, apply: func(value string, args []string) (string, error) {
+		t, err := parseTime(value)
+		if err != nil {
+			return "", err
+		}
+		return t.Format(args[0]), nil
+	}},
+}
+
+// timeLayouts are the layouts the format filter reads values in, after
+// Unix timestamps, which are read as UTC.
+var timeLayouts = []string{time.RFC3339Nano, time.DateTime, time.DateOnly, time.RFC1123, time.RFC1123Z}
+
+func parseTime(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+		// Anything past the year 33658 in seconds is taken as milliseconds.
+		if n > 1e12 || n < -1e12 {
+			return time.UnixMilli(n).UTC(), nil
+		}
+		return time.Unix(n, 0).UTC(), nil
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q is not a date", value)
+}
+
+// parseVariableExpr parses the inside of a {{...}} reference.
+func parseVariableExpr(text string) (variableExpr, error) {
+	tokens, err := tokenizeExpr(text)
+	if err != nil {
+		return variableExpr{}, err
+	}
+
+	var expr variableExpr
+	if len(tokens) == 0 || tokens[0].operator || tokens[0].quoted {
+		return expr, fmt.Errorf("{{%s}}: expected a variable name", text)
+	}
+	expr.name = tokens[0].text
+	tokens = tokens[1:]
+
+	if len(tokens) > 0 && tokens[0].is("??") {
+		if len(tokens) < 2 || tokens[1].operator {
+			return expr, fmt.Errorf("{{%s}}: expected a fallback after ??", text)
+		}
+		fallback := tokens[1].text
+		expr.fallback = &fallback
+		tokens = tokens[2:]
+	}
+
+	for len(tokens) > 0 {
+		if !tokens[0].is("|") {
+			return expr, fmt.Errorf("{{%s}}: unexpected %q", text, tokens[0].text)
+		}
+		if len(tokens) < 2 || tokens[1].operator || tokens[1].quoted {
+			return expr, fmt.Errorf("{{%s}}: expected a filter after |", text)
+		}
+		filter := variableFilter{name: tokens[1].text}
+		tokens = tokens[2:]
+		for len(tokens) > 0 && !tokens[0].operator {
+			filter.args = append(filter.args, tokens[0].text)
+			tokens = tokens[1:]
+		}
+
+		definition, ok := filters[filter.name]
+		if !ok {
+			return expr, fmt.Errorf("{{%s}}: unknown filter %q", text, filter.name)
+		}
+		if len(filter.args) != definition.args {
+			return expr, fmt.Errorf("{{%s}}: filter %s takes %d arguments, got %d", text, filter.name, definition.args, len(filter.args))
+		}
+		expr.filters = append(expr.filters, filter)
+	}
+	return expr, nil
+}
+
+// evaluate resolves the expression with lookup. ok is false when the
+// variable is not defined and there is no fallback.
+func (e variableExpr) evaluate(lookup func(name string) (string, bool, error)) (value string, ok bool, err error) {
+	if value, ok, err = lookup(e.name); err != nil {
+		return "", true, err
+	}
+	if !ok && e.fallback != nil {
+		value, ok = *e.fallback, true
+	}
+	if !ok {
+		return "", false, nil
+	}
+	for _, filter := range e.filters {
+		if value, err = filters[filter.name].apply(value, filter.args); err != nil {
+			return "", true, fmt.Errorf("{{%s | %s}}: %v", e.name, filter.name, err)
+		}
+	}
+	return value, true, nil
+}
+
+type exprToken struct {
+	text     string
+	quoted   bool
+	operator bool
+}
+
+func (t exprToken) is(operator string) bool {
+	return t.operator && t.text == operator
+}
+
+// tokenizeExpr splits an expression into words, quoted strings and the
+// ?? and | operators. Double-quoted strings take Go escapes; single-quoted
+// ones are taken literally.
+func tokenizeExpr(text string) ([]exprToken, error) {
+	var tokens []exprToken
+	for i := 0; i < len(text); {
+		switch c := text[i]; {
+		case unicode.IsSpace(rune(c)):
+			i++
+		case c == '|':
+			tokens = append(tokens, exprToken{text: "|", operator: true})
+			i++
+		case strings.HasPrefix(text[i:], "??"):
+			tokens = append(tokens, exprToken{text: "??", operator: true})
+			i += 2
+		case c == '"' || c == '\'':
+			end := i + 1
+			for end < len(text) && text[end] != c {
+				if c == '"' && text[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(text) {
+				return nil, fmt.Errorf("{{%s}}: unterminated string", text)
+			}
+			value := text[i+1 : end]
+			if c == '"' {
+				unquoted, err := strconv.Unquote(text[i : end+1])
+				if err != nil {
+					return nil, fmt.Errorf("{{%s}}: invalid string %s", text, text[i:end+1])
+				}
+				value = unquoted
+			}
+			tokens = append(tokens, exprToken{text: value, quoted: true})
+			i = end + 1
+		default:
+			end := i
+			for end < len(text) && !unicode.IsSpace(rune(text[end])) && text[end] != '|' && !strings.HasPrefix(text[end:], "??") {
+				end++
+			}
+			tokens = append(tokens, exprToken{text: text[i:end]})
+			i = end
+		}
+	}
+	return tokens, nil
+}
+
+// UnresolvedError lists the variables a request uses without defining.
+type UnresolvedError struct {
+	Names []string
+}
+
+func (e *UnresolvedError) Error() string {
+	if len(e.Names) == 1 {
+		return fmt.Sprintf("unresolved variable {{%s}}", e.Names[0])
+	}
+	return fmt.Sprintf("unresolved variables {{%s}}", strings.Join(e.Names, "}}, {{"))
+}
+
+// CycleError reports variables that refer back to themselves. Chain starts
+// and ends with the same name.
+type CycleError struct {
+	Chain []string
+}
+
+func (e *CycleError) Error() string {
+	return "variable cycle: " + strings.Join(e.Chain, " -> ")
+}
+
+// resolver expands variables whose values refer to other variables, such
+// as `@apiUrl = {{baseUrl}}/v2`, so the result does not depend on the order
+// variables were defined or set in.
+type resolver struct {
+	variables map[string]string
+	resolved  map[string]string
+	stack     []string
+	// keepPlain leaves plain {{name}} references in the text being
+	// expanded as written. References inside variable values are expanded.
+	keepPlain bool
+}
+
+func newResolver(variables map[string]string) *resolver {
+	return &resolver{variables: variables, resolved: make(map[string]string)}
+}
+
+// lookup returns the fully expanded value of a variable.
+func (r *resolver) lookup(name string) (string, bool, error) {
+	if value, ok := r.resolved[name]; ok {
+		return value, true, nil
+	}
+	raw, ok := r.variables[name]
+	if !ok {
+		// Names that are not defined fall back to the environment, such
+		// as variables loaded with --env. Their values are used as set.
+		if value, set := os.LookupEnv(name); set {
+			r.resolved[name] = value
+			return value, true, nil
+		}
+		return "", false, nil
+	}
+	for i, pending := range r.stack {
+		if pending == name {
+			chain := append(append([]string(nil), r.stack[i:]...), name)
+			return "", true, &CycleError{Chain: chain}
+		}
+	}
+
+	r.stack = append(r.stack, name)
+	value, err := r.expand(raw)
+	r.stack = r.stack[:len(r.stack)-1]
+	if err != nil {
+		return "", true, err
+	}
+	r.resolved[name] = value
+	return value, true, nil
+}
+
+// expand replaces every {{...}} reference in text. Undefined variables
+// without a fallback, including those reached through other variables, are
+// collected into one *UnresolvedError; any other error is returned first.
+func (r *resolver) expand(text string) (string, error) {
+	var unresolved []string
+	var firstErr error
+	result := variableRegex.ReplaceAllStringFunc(text, func(match string) string {
+		if r.keepPlain && len(r.stack) == 0 && plainReferenceRegex.MatchString(match) {
+			return match
+		}
+		expr, err := parseVariableExpr(variableRegex.FindStringSubmatch(match)[1])
+		if err == nil {
+			var value string
+			var ok bool
+			value, ok, err = expr.evaluate(r.lookup)
+			var unresolvedErr *UnresolvedError
+			switch {
+			case err == nil && ok:
+				return value
+			case err == nil:
+				unresolved = appendUnique(unresolved, expr.name)
+				return match
+			case errors.As(err, &unresolvedErr):
+				for _, name := range unresolvedErr.Names {
+					unresolved = appendUnique(unresolved, name)
+				}
+				return match
+			}
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+		return match
+	})
+	if firstErr != nil {
+		return result, firstErr
+	}
+	if len(unresolved) > 0 {
+		return result, &UnresolvedError{Names: unresolved}
+	}
+	return result, nil
+}
+
+// ExpandVariables replaces every {{...}} reference in text, expanding
+// variables that refer to other variables. Undefined variables without a
+// fallback are reported together in an *UnresolvedError, and variables
+// that refer back to themselves in a *CycleError.
+func ExpandVariables(text string, variables map[string]string) (string, error) {
+	return newResolver(variables).expand(text)
+}
+
+// ApplyEnv overrides the file's variables with the environment variables
+// of the same name that are set and not empty. Names the file does not
+// define are looked up in the environment when they are resolved.
+func (f *HTTPFile) ApplyEnv() {
+	for name := range f.Variables {
+		if value := os.Getenv(name); value != "" {
+			f.Variables[name] = value
+		}
+	}
+}
+
+// VariableNames returns the names of the variables text refers to, in
+// order of first use.
+func VariableNames(text string) []string {
+	var names []string
+	for _, match := range variableRegex.FindAllStringSubmatch(text, -1) {
+		names = appendUnique(names, variableName(match[1]))
+	}
+	return names
+}
+
+// RequiredVariableNames returns the names of the variables text refers to
+// without a `??` fallback, in order of first use.
+func RequiredVariableNames(text string) []string {
+	var names []string
+	for _, match := range variableRegex.FindAllStringSubmatch(text, -1) {
+		if expr, err := parseVariableExpr(match[1]); err == nil && expr.fallback != nil {
+			continue
+		}
+		names = appendUnique(names, variableName(match[1]))
+	}
+	return names
+}
+
+// variableName returns the variable a {{...}} reference reads, or the
+// trimmed text when it is not a valid expression.
+func variableName(text string) string {
+	if expr, err := parseVariableExpr(text); err == nil {
+		return expr.name
+	}
+	return strings.TrimSpace(text)
+}
+
+func appendUnique(names []string, name string) []string {
+	for _, existing := range names {
+		if existing == name {
+			return names
+		}
+	}
+	return append(names, name)
+}
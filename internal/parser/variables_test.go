@@ -0,0 +1,209 @@
+package parser
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestExpandVariables(t *testing.T) {
+	vars := map[string]string{
+		"token":   "user:pass",
+		"query":   "a b&c",
+		"pwd":     "secret",
+		"date":    "2024-03-05T10:30:00Z",
+		"created": "1709634600",
+		"id":      "ab-12",
+		"empty":   "",
+	}
+
+	tests := []struct {
+		name     string
+		text     string
+		expected string
+	}{
+		{"Plain", "{{id}}", "ab-12"},
+		{"Spaces", "{{ id }}", "ab-12"},
+		{"Defined ignores fallback", `{{id ?? "x"}}`, "ab-12"},
+		{"Empty value ignores fallback", `[{{empty ?? "x"}}]`, "[]"},
+		{"Double-quoted fallback", `{{missing ?? "http://localhost:8080"}}`, "http://localhost:8080"},
+		{"Single-quoted fallback", `{{missing ?? 'a "b"'}}`, `a "b"`},
+		{"Bare fallback", `{{page ?? 1}}`, "1"},
+		{"Empty fallback", `[{{missing ?? ""}}]`, "[]"},
+		{"Base64", "{{token | base64}}", "dXNlcjpwYXNz"},
+		{"Urlencode", "q={{query | urlencode}}", "q=a+b%26c"},
+		{"Sha256", "{{pwd | sha256}}", "2bb80d537b1da3e38bd30361aa855686bde0eacd7162fef6a25fe97bf527a25b"},
+		{"Upper", "{{id | upper}}", "AB-12"},
+		{"Format date", `{{date | format "2006-01-02"}}`, "2024-03-05"},
+		{"Format unix time", `{{created | format "2006-01-02T15:04Z07:00"}}`, "2024-03-05T10:30Z"},
+		{"Pipeline", `{{missing ?? "Ada" | upper | base64}}`, "QURB"},
+		{"Filter with pipe in argument", `{{date | format "Jan|2"}}`, "Mar|5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ExpandVariables(tt.text, vars)
+			if err != nil {
+				t.Fatalf("ExpandVariables failed: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestExpandVariables_Errors(t *testing.T) {
+	vars := map[string]string{"id": "1", "date": "yesterday"}
+
+	tests := []struct {
+		name     string
+		text     string
+		expected string
+	}{
+		{"Unresolved", "{{baseUrl}}/users/{{id}}", "unresolved variable {{baseUrl}}"},
+		{"Several unresolved", "{{a}}/{{b}}/{{a}}", "unresolved variables {{a}}, {{b}}"},
+		{"Unknown filter", "{{id | reverse}}", `unknown filter "reverse"`},
+		{"Missing argument", "{{id | format}}", "filter format takes 1 arguments, got 0"},
+		{"Unterminated string", `{{id ?? "x}}`, "unterminated string"},
+		{"Missing fallback", "{{id ??}}", "expected a fallback after ??"},
+		{"Not a date", `{{date | format "2006"}}`, `"yesterday" is not a date`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ExpandVariables(tt.text, vars)
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("Expected error containing %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestExpandVariables_Nested(t *testing.T) {
+	vars := map[string]string{
+		"host":     "api.example.com",
+		"baseUrl":  "https://{{host}}",
+		"apiUrl":   "{{baseUrl}}/v2",
+		"auth":     "Bearer {{token ?? \"anonymous\" | upper}}",
+		"usersUrl": "{{apiUrl}}/users/{{userId}}",
+	}
+
+	result, err := ExpandVariables("{{apiUrl}}/me {{auth}}", vars)
+	if err != nil {
+		t.Fatalf("ExpandVariables failed: %v", err)
+	}
+	if result != "https://api.example.com/v2/me Bearer ANONYMOUS" {
+		t.Errorf("Expected nested variables to be expanded, got %q", result)
+	}
+
+	_, err = ExpandVariables("{{usersUrl}}", vars)
+	var unresolved *UnresolvedError
+	if !errors.As(err, &unresolved) || strings.Join(unresolved.Names, ",") != "userId" {
+		t.Errorf("Expected userId to be reported as unresolved, got %v", err)
+	}
+}
+
+func TestExpandVariables_Cycle(t *testing.T) {
+	vars := map[string]string{
+		"a":    "{{b}}/x",
+		"b":    "{{c | upper}}",
+		"c":    "{{a}}",
+		"self": "{{self ?? 1}}",
+	}
+
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		{"{{a}}", "variable cycle: a -> b -> c -> a"},
+		{"{{c}}", "variable cycle: c -> a -> b -> c"},
+		{"{{self}}", "variable cycle: self -> self"},
+	}
+
+	for _, tt := range tests {
+		_, err := ExpandVariables(tt.text, vars)
+		var cycle *CycleError
+		if !errors.As(err, &cycle) || err.Error() != tt.expected {
+			t.Errorf("Expected %q for %s, got %v", tt.expected, tt.text, err)
+		}
+	}
+}
+
+func TestApplyVariables_Unresolved(t *testing.T) {
+	req := HTTPRequest{
+		URL:     "{{baseUrl}}/users/{{id}}",
+		Headers: http.Header{"Authorization": {"Bearer {{token}}"}, "X-Page": {`{{page ?? "1"}}`}},
+		Body:    `{"id": "{{id}}"}`,
+	}
+
+	err := req.ApplyVariables(map[string]string{"id": "7"})
+	var unresolved *UnresolvedError
+	if !errors.As(err, &unresolved) || strings.Join(unresolved.Names, ",") != "baseUrl,token" {
+		t.Fatalf("Expected baseUrl and token to be unresolved, got %v", err)
+	}
+	if req.URL != "{{baseUrl}}/users/7" || req.Body != `{"id": "7"}` || req.Headers.Get("X-Page") != "1" {
+		t.Errorf("Expected resolvable references to be expanded, got %q %q %v", req.URL, req.Body, req.Headers)
+	}
+}
+
+func TestApplyExpressions(t *testing.T) {
+	req := HTTPRequest{
+		URL:     `{{baseUrl}}/me?q={{q ?? "rock & roll"}}&user={{user | upper}}`,
+		Headers: http.Header{"Authorization": {"Basic {{auth | base64}}"}},
+		Body:    `{"id": "{{ id }}"}`,
+	}
+	vars := map[string]string{"user": "ada", "auth": "{{user}}:{{password}}", "password": "pw"}
+	if err := req.ApplyExpressions(vars); err != nil {
+		t.Fatalf("ApplyExpressions failed: %v", err)
+	}
+	if req.URL != "{{baseUrl}}/me?q=rock+%26+roll&user=ADA" {
+		t.Errorf("Expected plain references kept and expressions resolved, got %q", req.URL)
+	}
+	if req.Headers.Get("Authorization") != "Basic YWRhOnB3" || req.Body != `{"id": "{{ id }}"}` {
+		t.Errorf("Expected nested variables resolved, got %v %q", req.Headers, req.Body)
+	}
+
+	for _, text := range []string{"{{token | upper}}", "{{login.response.body.$.id}}"} {
+		req := HTTPRequest{URL: "https://api.example.com/" + text}
+		var unresolved *UnresolvedError
+		if err := req.ApplyExpressions(nil); !errors.As(err, &unresolved) {
+			t.Errorf("Expected %s to be unresolved, got %v", text, err)
+		}
+	}
+}
+
+func TestReplaceVariables_Lenient(t *testing.T) {
+	result := ReplaceVariables(`{{id | upper}} {{missing}} {{id | nope}}`, map[string]string{"id": "a"})
+	if result != "A {{missing}} {{id | nope}}" {
+		t.Errorf("Expected unresolved references to be left as written, got %q", result)
+	}
+}
+
+func TestExpandVariables_Env(t *testing.T) {
+	t.Setenv("HRUN_API_KEY", "k-123")
+	t.Setenv("HRUN_HOST", "env.example.com")
+
+	vars := map[string]string{
+		"HRUN_HOST": "file.example.com",
+		"auth":      "Key {{HRUN_API_KEY}}",
+	}
+	result, err := ExpandVariables("https://{{HRUN_HOST}}/?{{auth}}", vars)
+	if err != nil {
+		t.Fatalf("ExpandVariables failed: %v", err)
+	}
+	if result != "https://file.example.com/?Key k-123" {
+		t.Errorf("Expected undefined names to come from the environment, got %q", result)
+	}
+
+	file := &HTTPFile{Variables: vars}
+	file.ApplyEnv()
+	if vars["HRUN_HOST"] != "env.example.com" || vars["auth"] != "Key {{HRUN_API_KEY}}" {
+		t.Errorf("Expected ApplyEnv to override declared names only, got %v", vars)
+	}
+
+	if _, err := ExpandVariables("{{HRUN_UNSET_VARIABLE}}", nil); err == nil {
+		t.Error("Expected a name missing from the environment to stay unresolved")
+	}
+}
@@ -0,0 +1,106 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"strings"
+)
+
+const (
+	// ExecutablePrefix is prepended to a plugin name to find its executable
+	// on $PATH.
+	ExecutablePrefix = "hrun-plugin-"
+
+	HookPreRequest   = "pre-request"
+	HookPostResponse = "post-response"
+)
+
+// Request is the outgoing request. In a plugin's output, a nil Body leaves
+// the body as it is, while an empty one removes it.
+type Request struct {
+	Method  string      `json:"method"`
+	URL     string      `json:"url"`
+	Headers http.Header `json:"headers"`
+	Body    *string     `json:"body"`
+}
+
+type Response struct {
+	StatusCode int         `json:"statusCode"`
+	Headers    http.Header `json:"headers"`
+	Body       string      `json:"body"`
+}
+
+// Input is written as JSON to the plugin's stdin.
+type Input struct {
+	Hook     string    `json:"hook"`
+	Args     []string  `json:"args,omitempty"`
+	Request  Request   `json:"request"`
+	Response *Response `json:"response,omitempty"`
+}
+
+// Output is read as JSON from the plugin's stdout. Empty output means the
+// plugin has nothing to change for this hook.
+type Output struct {
+	Request   *Request          `json:"request,omitempty"`
+	Variables map[string]string `json:"variables,omitempty"`
+}
+
+type Error struct {
+	Name   string
+	Hook   string
+	Err    error
+	Stderr string
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("plugin %s (%s): %v", e.Name, e.Hook, e.Err)
+	if e.Stderr != "" {
+		msg += ": " + e.Stderr
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// LookPath resolves the executable for a plugin name.
+func LookPath(name string) (string, error) {
+	return exec.LookPath(ExecutablePrefix + name)
+}
+
+// Run invokes the named plugin once for the given hook.
+func Run(ctx context.Context, name string, input Input) (*Output, error) {
+	path, err := LookPath(name)
+	if err != nil {
+		return nil, &Error{Name: name, Hook: input.Hook, Err: err}
+	}
+
+	payload, err := json.Marshal(input)
+	if err != nil {
+		return nil, &Error{Name: name, Hook: input.Hook, Err: err}
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, path)
+	cmd.Stdin = bytes.NewReader(payload)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, &Error{Name: name, Hook: input.Hook, Err: err, Stderr: strings.TrimSpace(stderr.String())}
+	}
+
+	output := &Output{}
+	if len(bytes.TrimSpace(stdout.Bytes())) == 0 {
+		return output, nil
+	}
+	if err := json.Unmarshal(stdout.Bytes(), output); err != nil {
+		return nil, &Error{Name: name, Hook: input.Hook, Err: fmt.Errorf("invalid output: %w", err)}
+	}
+	return output, nil
+}
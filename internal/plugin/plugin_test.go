@@ -0,0 +1,93 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func installPlugin(t *testing.T, name, script string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("shell plugins are not supported on windows")
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, ExecutablePrefix+name)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0o755); err != nil {
+		t.Fatalf("Failed to write plugin: %v", err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestRun_ReturnsOutput(t *testing.T) {
+	installPlugin(t, "echo", `input=$(cat)
+case "$input" in
+  *'"hook":"pre-request"'*) echo '{"request":{"method":"PUT"}}' ;;
+  *) echo '{"variables":{"seen":"yes"}}' ;;
+esac
+`)
+
+	output, err := Run(context.Background(), "echo", Input{Hook: HookPreRequest})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if output.Request == nil || output.Request.Method != "PUT" {
+		t.Errorf("Expected modified request with method PUT, got %+v", output.Request)
+	}
+
+	output, err = Run(context.Background(), "echo", Input{Hook: HookPostResponse})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if output.Variables["seen"] != "yes" {
+		t.Errorf("Expected variable seen=yes, got %v", output.Variables)
+	}
+}
+
+func TestRun_EmptyOutput(t *testing.T) {
+	installPlugin(t, "noop", "cat >/dev/null\n")
+
+	output, err := Run(context.Background(), "noop", Input{Hook: HookPreRequest})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if output.Request != nil || len(output.Variables) != 0 {
+		t.Errorf("Expected empty output, got %+v", output)
+	}
+}
+
+func TestRun_Failure(t *testing.T) {
+	installPlugin(t, "broken", "echo 'bad key' >&2\nexit 3\n")
+
+	_, err := Run(context.Background(), "broken", Input{Hook: HookPreRequest})
+	if err == nil {
+		t.Fatal("Expected error from failing plugin")
+	}
+	var pluginErr *Error
+	if !errors.As(err, &pluginErr) {
+		t.Fatalf("Expected *Error, got %T", err)
+	}
+	if !strings.Contains(err.Error(), "bad key") {
+		t.Errorf("Expected stderr in error, got %v", err)
+	}
+}
+
+func TestRun_InvalidOutput(t *testing.T) {
+	installPlugin(t, "garbage", "echo 'not json'\n")
+
+	if _, err := Run(context.Background(), "garbage", Input{Hook: HookPreRequest}); err == nil {
+		t.Error("Expected error for invalid plugin output")
+	}
+}
+
+func TestRun_NotFound(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+
+	if _, err := Run(context.Background(), "missing", Input{Hook: HookPreRequest}); err == nil {
+		t.Error("Expected error for missing plugin")
+	}
+}
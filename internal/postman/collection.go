@@ -0,0 +1,221 @@
+package postman
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const SchemaURL = "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"
+
+// Collection is a Postman v2.1 collection. Only the parts hrun can
+// represent are modelled; everything else is dropped on import.
+type Collection struct {
+	Info     Info       `json:"info"`
+	Item     []Item     `json:"item"`
+	Variable []Variable `json:"variable,omitempty"`
+	Auth     *Auth      `json:"auth,omitempty"`
+	Event    []Event    `json:"event,omitempty"`
+}
+
+type Info struct {
+	PostmanID   string      `json:"_postman_id,omitempty"`
+	Name        string      `json:"name"`
+	Description Description `json:"description,omitempty"`
+	Schema      string      `json:"schema"`
+}
+
+// Item is either a folder, with child items, or a request.
+type Item struct {
+	Name        string      `json:"name"`
+	Description Description `json:"description,omitempty"`
+	Item        []Item      `json:"item,omitempty"`
+	Request     *Request    `json:"request,omitempty"`
+	Auth        *Auth       `json:"auth,omitempty"`
+	Event       []Event     `json:"event,omitempty"`
+}
+
+func (i Item) IsFolder() bool {
+	return i.Request == nil
+}
+
+type Request struct {
+	Method      string      `json:"method"`
+	Header      []KeyValue  `json:"header"`
+	Body        *Body       `json:"body,omitempty"`
+	URL         URL         `json:"url"`
+	Auth        *Auth       `json:"auth,omitempty"`
+	Description Description `json:"description,omitempty"`
+}
+
+// UnmarshalJSON accepts the short form where a request is just its URL.
+func (r *Request) UnmarshalJSON(data []byte) error {
+	var raw string
+	if err := json.Unmarshal(data, &raw); err == nil {
+		*r = Request{Method: "GET", URL: URL{Raw: raw}}
+		return nil
+	}
+	type request Request
+	var out request
+	if err := json.Unmarshal(data, &out); err != nil {
+		return err
+	}
+	*r = Request(out)
+	return nil
+}
+
+type URL struct {
+	Raw      string     `json:"raw"`
+	Protocol string     `json:"protocol,omitempty"`
+	Host     []string   `json:"host,omitempty"`
+	Path     []string   `json:"path,omitempty"`
+	Query    []KeyValue `json:"query,omitempty"`
+}
+
+// UnmarshalJSON accepts a plain string URL as well as the object form.
+func (u *URL) UnmarshalJSON(data []byte) error {
+	var raw string
+	if err := json.Unmarshal(data, &raw); err == nil {
+		*u = URL{Raw: raw}
+		return nil
+	}
+	type url URL
+	var out url
+	if err := json.Unmarshal(data, &out); err != nil {
+		return err
+	}
+	*u = URL(out)
+	return nil
+}
+
+type KeyValue struct {
+	Key      string `json:"key"`
+	Value    string `json:"value"`
+	Type     string `json:"type,omitempty"`
+	Src      any    `json:"src,omitempty"`
+	Disabled bool   `json:"disabled,omitempty"`
+}
+
+type Body struct {
+	Mode       string       `json:"mode"`
+	Raw        string       `json:"raw,omitempty"`
+	URLEncoded []KeyValue   `json:"urlencoded,omitempty"`
+	FormData   []KeyValue   `json:"formdata,omitempty"`
+	GraphQL    *GraphQL     `json:"graphql,omitempty"`
+	Options    *BodyOptions `json:"options,omitempty"`
+}
+
+type GraphQL struct {
+	Query     string `json:"query"`
+	Variables string `json:"variables,omitempty"`
+}
+
+type BodyOptions struct {
+	Raw struct {
+		Language string `json:"language"`
+	} `json:"raw"`
+}
+
+type Auth struct {
+	Type   string      `json:"type"`
+	Bearer []AuthParam `json:"bearer,omitempty"`
+	Basic  []AuthParam `json:"basic,omitempty"`
+	APIKey []AuthParam `json:"apikey,omitempty"`
+}
+
+type AuthParam struct {
+	Key   string `json:"key"`
+	Value any    `json:"value"`
+	Type  string `json:"type,omitempty"`
+}
+
+type Event struct {
+	Listen string `json:"listen"`
+	Script Script `json:"script"`
+}
+
+type Script struct {
+	Type string `json:"type,omitempty"`
+	Exec Lines  `json:"exec"`
+}
+
+// Lines is a script body, stored by Postman either as one string or as a
+// list of lines.
+type Lines []string
+
+func (l *Lines) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*l = strings.Split(text, "\n")
+		return nil
+	}
+	var lines []string
+	if err := json.Unmarshal(data, &lines); err != nil {
+		return err
+	}
+	*l = lines
+	return nil
+}
+
+type Variable struct {
+	Key      string `json:"key"`
+	Value    any    `json:"value"`
+	Type     string `json:"type,omitempty"`
+	Disabled bool   `json:"disabled,omitempty"`
+}
+
+// Description is plain text; Postman also writes it as an object with the
+// text under "content".
+type Description string
+
+func (d *Description) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*d = Description(text)
+		return nil
+	}
+	var object struct {
+		Content string `json:"content"`
+	}
+	if err := json.Unmarshal(data, &object); err != nil {
+		return err
+	}
+	*d = Description(object.Content)
+	return nil
+}
+
+func Load(path string) (*Collection, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c Collection
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if c.Info.Schema != "" && !strings.Contains(c.Info.Schema, "v2.1") && !strings.Contains(c.Info.Schema, "v2.0") {
+		return nil, fmt.Errorf("%s: unsupported collection schema %s (expected v2.1)", path, c.Info.Schema)
+	}
+	return &c, nil
+}
+
+func (c *Collection) JSON() ([]byte, error) {
+	return json.MarshalIndent(c, "", "  ")
+}
+
+func (a AuthParam) String() string {
+	if a.Value == nil {
+		return ""
+	}
+	return fmt.Sprint(a.Value)
+}
+
+func authParam(params []AuthParam, key string) string {
+	for _, param := range params {
+		if param.Key == key {
+			return param.String()
+		}
+	}
+	return ""
+}
@@ -0,0 +1,178 @@
+package postman
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/cassielabs/hrun/internal/parser"
+)
+
+var jsIdentifierRegex = regexp.MustCompile(`^[A-Za-z_$][\w$]*$`)
+
+// Export builds a collection from .http files. A single file's requests
+// sit at the top level; with several files, each becomes a folder named
+// after it. Request names containing FolderSeparator are nested into
+// folders, and captures become test scripts that set collection variables.
+// Secret `@!` variables are exported as secret variables without a value,
+// so a shared collection does not carry credentials.
+func Export(name string, files []*parser.HTTPFile) *Collection {
+	c := &Collection{Info: Info{Name: name, Schema: SchemaURL}, Item: []Item{}}
+
+	seen := make(map[string]bool)
+	for _, file := range files {
+		keys := make([]string, 0, len(file.Variables))
+		for key := range file.Variables {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if !seen[key] {
+				seen[key] = true
+				variable := Variable{Key: key, Value: file.Variables[key], Type: "string"}
+				if file.Secrets[key] {
+					variable.Type = "secret"
+					variable.Value = ""
+				}
+				c.Variable = append(c.Variable, variable)
+			}
+		}
+	}
+
+	for _, file := range files {
+		items := &c.Item
+		if len(files) > 1 {
+			folderName := strings.TrimSuffix(filepath.Base(file.Path), filepath.Ext(file.Path))
+			c.Item = append(c.Item, Item{Name: folderName, Item: []Item{}})
+			items = &c.Item[len(c.Item)-1].Item
+		}
+		for _, req := range file.Requests {
+			path := strings.Split(req.Name, FolderSeparator)
+			if req.Name == "" {
+				path = []string{req.Method + " " + req.URL}
+			}
+			addItem(items, path[:len(path)-1], exportRequest(path[len(path)-1], req))
+		}
+	}
+	return c
+}
+
+// addItem places an item under the named folders, creating them as needed.
+func addItem(items *[]Item, folders []string, item Item) {
+	for _, folder := range folders {
+		index := -1
+		for i, existing := range *items {
+			if existing.IsFolder() && existing.Name == folder {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			*items = append(*items, Item{Name: folder, Item: []Item{}})
+			index = len(*items) - 1
+		}
+		items = &(*items)[index].Item
+	}
+	*items = append(*items, item)
+}
+
+func exportRequest(name string, req parser.HTTPRequest) Item {
+	r := &Request{
+		Method:      req.Method,
+		Header:      []KeyValue{},
+		URL:         exportURL(req.URL),
+		Description: Description(req.Description),
+	}
+
+	keys := make([]string, 0, len(req.Headers))
+	for key := range req.Headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for _, value := range req.Headers[key] {
+			r.Header = append(r.Header, KeyValue{Key: key, Value: value, Type: "text"})
+		}
+	}
+
+	if body := strings.TrimRight(req.Body, "\r\n"); body != "" {
+		r.Body = &Body{Mode: "raw", Raw: body}
+		if strings.Contains(req.Headers.Get("Content-Type"), "json") {
+			r.Body.Options = &BodyOptions{}
+			r.Body.Options.Raw.Language = "json"
+		}
+	}
+
+	item := Item{Name: name, Request: r}
+	if len(req.Captures) > 0 {
+		var lines []string
+		for _, capture := range req.Captures {
+			lines = append(lines, captureScript(capture))
+		}
+		item.Event = []Event{{Listen: "test", Script: Script{Type: "text/javascript", Exec: lines}}}
+	}
+	return item
+}
+
+// captureScript turns a capture into a line of Postman test script.
+// Expressions Postman has no simple equivalent for are kept as a comment.
+func captureScript(capture parser.CaptureRule) string {
+	set := func(expression string) string {
+		return fmt.Sprintf("pm.collectionVariables.set(%q, %s);", capture.VariableName, expression)
+	}
+
+	source, arg := capture.Source()
+	switch source {
+	case parser.CaptureStatus:
+		return set("pm.response.code")
+	case parser.CaptureHeader:
+		return set(fmt.Sprintf("pm.response.headers.get(%q)", arg))
+	case parser.CaptureCookie:
+		return set(fmt.Sprintf("pm.cookies.get(%q)", arg))
+	case parser.CaptureBody:
+		expression := "pm.response.json()"
+		for _, part := range strings.Split(arg, ".") {
+			switch {
+			case part != "" && strings.Trim(part, "0123456789") == "":
+				expression += "[" + part + "]"
+			case jsIdentifierRegex.MatchString(part):
+				expression += "." + part
+			default:
+				return fmt.Sprintf("// hrun capture %s = %s", capture.VariableName, capture.JSONPath)
+			}
+		}
+		return set(expression)
+	default:
+		return fmt.Sprintf("// hrun capture %s = %s", capture.VariableName, capture.JSONPath)
+	}
+}
+
+// exportURL splits a URL into the host, path and query parts Postman shows
+// in its editor, keeping {{variables}} intact.
+func exportURL(raw string) URL {
+	u := URL{Raw: raw}
+	rest := raw
+	if protocol, after, ok := strings.Cut(rest, "://"); ok {
+		u.Protocol, rest = protocol, after
+	}
+	rest, query, _ := strings.Cut(rest, "?")
+	host, path, _ := strings.Cut(rest, "/")
+
+	if strings.HasPrefix(host, "{{") {
+		u.Host = []string{host}
+	} else if host != "" {
+		u.Host = strings.Split(host, ".")
+	}
+	if path != "" {
+		u.Path = strings.Split(path, "/")
+	}
+	if query != "" {
+		for _, pair := range strings.Split(query, "&") {
+			key, value, _ := strings.Cut(pair, "=")
+			u.Query = append(u.Query, KeyValue{Key: key, Value: value})
+		}
+	}
+	return u
+}
@@ -0,0 +1,294 @@
+package postman
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/cassielabs/hrun/internal/parser"
+)
+
+// FolderSeparator joins folder and request names, so that "Users / Get
+// user" is the request "Get user" in the folder "Users".
+const FolderSeparator = " / "
+
+// setVariableRegex recognises the test-script lines that Export writes for
+// captures, and the common hand-written equivalent.
+var setVariableRegex = regexp.MustCompile(`pm\.(?:environment|collectionVariables|globals|variables)\.set\(\s*["']([\w.-]+)["']\s*,\s*(?:(?:pm\.response\.json\(\)|jsonData)((?:\.[A-Za-z_$][\w$]*|\[\d+\])*)|pm\.response\.(code)|pm\.response\.headers\.get\(\s*["']([^"']+)["']\s*\)|pm\.cookies\.get\(\s*["']([^"']+)["']\s*\))\s*\)`)
+
+// File is one generated .http file.
+type File struct {
+	// Name is the folder the file was built from, or the collection name.
+	Name string
+	HTTP *parser.HTTPFile
+}
+
+// Import converts a collection into .http requests. Nested folders become
+// name prefixes joined by FolderSeparator. With split, each top-level
+// folder gets its own file and requests outside folders stay in a file
+// named after the collection. Scripts are kept as description comments,
+// except for variable assignments from the response body, which become
+// captures.
+func Import(c *Collection, split bool) []File {
+	variables := make(map[string]string)
+	secrets := make(map[string]bool)
+	for _, variable := range c.Variable {
+		if !variable.Disabled && variable.Value != nil {
+			variables[variable.Key] = fmt.Sprint(variable.Value)
+			if variable.Type == "secret" {
+				secrets[variable.Key] = true
+			}
+		}
+	}
+	newFile := func(name string) File {
+		vars := make(map[string]string, len(variables))
+		for key, value := range variables {
+			vars[key] = value
+		}
+		file := &parser.HTTPFile{Variables: vars}
+		if len(secrets) > 0 {
+			file.Secrets = make(map[string]bool, len(secrets))
+			for key := range secrets {
+				file.Secrets[key] = true
+			}
+		}
+		return File{Name: name, HTTP: file}
+	}
+
+	root := newFile(c.Info.Name)
+	if !split {
+		collectItems(root.HTTP, c.Item, nil, c.Auth)
+		return []File{root}
+	}
+
+	var files []File
+	var loose []Item
+	for _, item := range c.Item {
+		if !item.IsFolder() {
+			loose = append(loose, item)
+			continue
+		}
+		file := newFile(item.Name)
+		collectItems(file.HTTP, item.Item, nil, inheritAuth(c.Auth, item.Auth))
+		files = append(files, file)
+	}
+	if len(loose) > 0 {
+		collectItems(root.HTTP, loose, nil, c.Auth)
+		files = append([]File{root}, files...)
+	}
+	return files
+}
+
+func collectItems(file *parser.HTTPFile, items []Item, folders []string, auth *Auth) {
+	for _, item := range items {
+		if item.IsFolder() {
+			collectItems(file, item.Item, append(folders, item.Name), inheritAuth(auth, item.Auth))
+			continue
+		}
+		file.Requests = append(file.Requests, importRequest(item, folders, inheritAuth(auth, item.Request.Auth)))
+	}
+}
+
+// inheritAuth returns the auth that applies below a level: its own, unless
+// it is unset or set to inherit.
+func inheritAuth(parent, own *Auth) *Auth {
+	if own == nil || own.Type == "inherit" {
+		return parent
+	}
+	return own
+}
+
+func importRequest(item Item, folders []string, auth *Auth) parser.HTTPRequest {
+	r := item.Request
+	req := parser.HTTPRequest{
+		Name:    strings.Join(append(append([]string{}, folders...), item.Name), FolderSeparator),
+		Method:  strings.ToUpper(r.Method),
+		URL:     r.URL.Raw,
+		Headers: make(http.Header),
+	}
+	if req.Method == "" {
+		req.Method = "GET"
+	}
+	if req.URL == "" {
+		req.URL = strings.Join(r.URL.Host, ".")
+		if len(r.URL.Path) > 0 {
+			req.URL += "/" + strings.Join(r.URL.Path, "/")
+		}
+		var query []string
+		for _, param := range r.URL.Query {
+			if !param.Disabled {
+				query = append(query, param.Key+"="+param.Value)
+			}
+		}
+		if len(query) > 0 {
+			req.URL += "?" + strings.Join(query, "&")
+		}
+	}
+
+	for _, header := range r.Header {
+		if !header.Disabled {
+			req.Headers.Add(header.Key, header.Value)
+		}
+	}
+
+	var notes []string
+	description := string(r.Description)
+	if description == "" {
+		description = string(item.Description)
+	}
+	if description != "" {
+		notes = append(notes, description)
+	}
+
+	notes = append(notes, applyAuth(&req, auth)...)
+	notes = append(notes, applyBody(&req, r.Body)...)
+
+	for _, event := range item.Event {
+		lines := trimLines(event.Script.Exec)
+		if len(lines) == 0 {
+			continue
+		}
+		title := "Tests:"
+		if event.Listen == "prerequest" {
+			title = "Pre-request script:"
+		}
+		notes = append(notes, title+"\n"+strings.Join(lines, "\n"))
+
+		if event.Listen != "test" {
+			continue
+		}
+		for _, line := range lines {
+			for _, match := range setVariableRegex.FindAllStringSubmatch(line, -1) {
+				capture := parser.CaptureRule{VariableName: match[1], JSONPath: jsPathToGJSON(match[2])}
+				switch {
+				case match[3] != "":
+					capture.JSONPath = parser.CaptureStatus
+				case match[4] != "":
+					capture.JSONPath = parser.CaptureHeader + "." + match[4]
+				case match[5] != "":
+					capture.JSONPath = parser.CaptureCookie + "." + match[5]
+				default:
+					if source, _ := capture.Source(); source != parser.CaptureBody {
+						capture.JSONPath = "body." + capture.JSONPath
+					}
+				}
+				req.Captures = append(req.Captures, capture)
+			}
+		}
+	}
+
+	req.Description = strings.Join(notes, "\n")
+	return req
+}
+
+func applyAuth(req *parser.HTTPRequest, auth *Auth) []string {
+	if auth == nil || req.Headers.Get("Authorization") != "" {
+		return nil
+	}
+	switch auth.Type {
+	case "bearer":
+		req.Headers.Set("Authorization", "Bearer "+authParam(auth.Bearer, "token"))
+	case "basic":
+		username, password := authParam(auth.Basic, "username"), authParam(auth.Basic, "password")
+		if strings.Contains(username+password, "{{") {
+			return []string{"Basic auth uses variables, so the Authorization header must be set by hand: " + username + ":" + password}
+		}
+		req.Headers.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(username+":"+password)))
+	case "apikey":
+		key, value := authParam(auth.APIKey, "key"), authParam(auth.APIKey, "value")
+		if authParam(auth.APIKey, "in") == "query" {
+			separator := "?"
+			if strings.Contains(req.URL, "?") {
+				separator = "&"
+			}
+			req.URL += separator + key + "=" + value
+		} else {
+			req.Headers.Set(key, value)
+		}
+	case "noauth", "":
+	default:
+		return []string{fmt.Sprintf("Postman %s auth is not supported", auth.Type)}
+	}
+	return nil
+}
+
+func applyBody(req *parser.HTTPRequest, body *Body) []string {
+	if body == nil {
+		return nil
+	}
+	var notes []string
+	switch body.Mode {
+	case "raw":
+		req.Body = body.Raw
+		if body.Options != nil && body.Options.Raw.Language == "json" && req.Headers.Get("Content-Type") == "" {
+			req.Headers.Set("Content-Type", "application/json")
+		}
+	case "urlencoded":
+		var fields []string
+		for _, field := range body.URLEncoded {
+			if !field.Disabled {
+				fields = append(fields, url.QueryEscape(field.Key)+"="+url.QueryEscape(field.Value))
+			}
+		}
+		req.Body = strings.Join(fields, "&")
+		if req.Headers.Get("Content-Type") == "" {
+			req.Headers.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+	case "formdata":
+		const boundary = "hrun-form-boundary"
+		var b strings.Builder
+		for _, field := range body.FormData {
+			if field.Disabled {
+				continue
+			}
+			if field.Type == "file" {
+				notes = append(notes, fmt.Sprintf("Form file field %q (%v) was not imported", field.Key, field.Src))
+				continue
+			}
+			fmt.Fprintf(&b, "--%s\nContent-Disposition: form-data; name=%q\n\n%s\n", boundary, field.Key, field.Value)
+		}
+		if b.Len() > 0 {
+			req.Body = b.String() + "--" + boundary + "--"
+			req.Headers.Set("Content-Type", "multipart/form-data; boundary="+boundary)
+		}
+	case "graphql":
+		if body.GraphQL != nil {
+			payload := map[string]any{"query": body.GraphQL.Query}
+			var variables any
+			if err := json.Unmarshal([]byte(body.GraphQL.Variables), &variables); err == nil {
+				payload["variables"] = variables
+			}
+			if data, err := json.MarshalIndent(payload, "", "  "); err == nil {
+				req.Body = string(data)
+			}
+			if req.Headers.Get("Content-Type") == "" {
+				req.Headers.Set("Content-Type", "application/json")
+			}
+		}
+	case "":
+	default:
+		notes = append(notes, fmt.Sprintf("Postman %s body is not supported", body.Mode))
+	}
+	return notes
+}
+
+func trimLines(lines []string) []string {
+	start, end := 0, len(lines)
+	for start < end && strings.TrimSpace(lines[start]) == "" {
+		start++
+	}
+	for end > start && strings.TrimSpace(lines[end-1]) == "" {
+		end--
+	}
+	return lines[start:end]
+}
+
+// jsPathToGJSON turns .data.items[0].id into data.items.0.id.
+func jsPathToGJSON(path string) string {
+	path = strings.NewReplacer("[", ".", "]", "").Replace(path)
+	return strings.TrimPrefix(path, ".")
+}
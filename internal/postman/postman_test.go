@@ -0,0 +1,263 @@
+package postman
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cassielabs/hrun/internal/parser"
+)
+
+const storeCollection = `{
+  "info": {
+    "name": "Store",
+    "schema": "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"
+  },
+  "auth": {"type": "bearer", "bearer": [{"key": "token", "value": "{{token}}", "type": "string"}]},
+  "variable": [
+    {"key": "baseUrl", "value": "https://store.example.com"},
+    {"key": "retries", "value": 3},
+    {"key": "apiKey", "value": "k-1", "type": "secret"}
+  ],
+  "item": [
+    {
+      "name": "Health",
+      "request": "{{baseUrl}}/health"
+    },
+    {
+      "name": "Auth",
+      "auth": {"type": "noauth"},
+      "item": [
+        {
+          "name": "Login",
+          "event": [
+            {"listen": "prerequest", "script": {"exec": "console.log('logging in')"}},
+            {"listen": "test", "script": {"exec": [
+              "pm.test('ok', () => pm.response.to.have.status(200));",
+              "pm.environment.set(\"token\", pm.response.json().data.token);",
+              "pm.environment.set(\"state\", pm.response.json().status);",
+              "pm.collectionVariables.set(\"loc\", pm.response.headers.get(\"Location\"));"
+            ]}}
+          ],
+          "request": {
+            "method": "POST",
+            "description": {"content": "Exchange credentials for a token"},
+            "header": [{"key": "X-Debug", "value": "1", "disabled": true}],
+            "body": {"mode": "raw", "raw": "{\"user\": \"{{user}}\"}", "options": {"raw": {"language": "json"}}},
+            "url": {"raw": "{{baseUrl}}/login", "host": ["{{baseUrl}}"], "path": ["login"]}
+          }
+        }
+      ]
+    },
+    {
+      "name": "Orders",
+      "item": [
+        {
+          "name": "Admin",
+          "item": [
+            {
+              "name": "Export orders",
+              "request": {
+                "method": "GET",
+                "auth": {"type": "apikey", "apikey": [
+                  {"key": "key", "value": "api_key"}, {"key": "value", "value": "{{apiKey}}"}, {"key": "in", "value": "query"}
+                ]},
+                "url": {"host": ["{{baseUrl}}"], "path": ["orders", "export"], "query": [{"key": "format", "value": "csv"}]}
+              }
+            }
+          ]
+        },
+        {
+          "name": "Create order",
+          "request": {
+            "method": "POST",
+            "body": {"mode": "urlencoded", "urlencoded": [{"key": "sku", "value": "A 1"}, {"key": "qty", "value": "2"}]},
+            "url": "{{baseUrl}}/orders"
+          }
+        }
+      ]
+    }
+  ]
+}`
+
+func loadCollection(t *testing.T) *Collection {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "store.postman_collection.json")
+	if err := os.WriteFile(path, []byte(storeCollection), 0o644); err != nil {
+		t.Fatalf("Failed to write collection: %v", err)
+	}
+	c, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	return c
+}
+
+func TestImport(t *testing.T) {
+	files := Import(loadCollection(t), false)
+	if len(files) != 1 {
+		t.Fatalf("Expected 1 file, got %d", len(files))
+	}
+	file := files[0].HTTP
+
+	if file.Variables["baseUrl"] != "https://store.example.com" || file.Variables["retries"] != "3" {
+		t.Errorf("Unexpected variables: %v", file.Variables)
+	}
+	if !file.Secrets["apiKey"] || file.Secrets["baseUrl"] {
+		t.Errorf("Expected secret collection variables to stay secret, got %v", file.Secrets)
+	}
+
+	byName := make(map[string]parser.HTTPRequest)
+	var names []string
+	for _, req := range file.Requests {
+		byName[req.Name] = req
+		names = append(names, req.Name)
+	}
+	expectedNames := "Health,Auth / Login,Orders / Admin / Export orders,Orders / Create order"
+	if strings.Join(names, ",") != expectedNames {
+		t.Fatalf("Expected requests %s, got %s", expectedNames, strings.Join(names, ","))
+	}
+
+	health := byName["Health"]
+	if health.Method != "GET" || health.URL != "{{baseUrl}}/health" || health.Headers.Get("Authorization") != "Bearer {{token}}" {
+		t.Errorf("Unexpected health request: %+v", health)
+	}
+
+	login := byName["Auth / Login"]
+	if login.Headers.Get("Authorization") != "" {
+		t.Errorf("Expected noauth folder to drop collection auth, got %q", login.Headers.Get("Authorization"))
+	}
+	if login.Headers.Get("X-Debug") != "" {
+		t.Errorf("Expected disabled header to be skipped")
+	}
+	if login.Headers.Get("Content-Type") != "application/json" || login.Body != `{"user": "{{user}}"}` {
+		t.Errorf("Unexpected login body: %q (%q)", login.Body, login.Headers.Get("Content-Type"))
+	}
+	for _, expected := range []string{"Exchange credentials for a token", "Pre-request script:\nconsole.log('logging in')", "Tests:\npm.test("} {
+		if !strings.Contains(login.Description, expected) {
+			t.Errorf("Expected description to contain %q, got %q", expected, login.Description)
+		}
+	}
+	var captures []string
+	for _, capture := range login.Captures {
+		captures = append(captures, capture.VariableName+"="+capture.JSONPath)
+	}
+	if strings.Join(captures, ",") != "token=data.token,state=body.status,loc=header.Location" {
+		t.Errorf("Expected token, state and loc captures, got %v", captures)
+	}
+
+	export := byName["Orders / Admin / Export orders"]
+	if export.URL != "{{baseUrl}}/orders/export?format=csv&api_key={{apiKey}}" {
+		t.Errorf("Unexpected export URL %q", export.URL)
+	}
+
+	create := byName["Orders / Create order"]
+	if create.Body != "sku=A+1&qty=2" || create.Headers.Get("Content-Type") != "application/x-www-form-urlencoded" {
+		t.Errorf("Unexpected create body %q", create.Body)
+	}
+}
+
+func TestImport_Split(t *testing.T) {
+	files := Import(loadCollection(t), true)
+
+	var names []string
+	for _, file := range files {
+		names = append(names, file.Name)
+	}
+	if strings.Join(names, ",") != "Store,Auth,Orders" {
+		t.Fatalf("Expected files Store,Auth,Orders, got %v", names)
+	}
+	orders := files[2].HTTP
+	if len(orders.Requests) != 2 || orders.Requests[0].Name != "Admin / Export orders" {
+		t.Errorf("Expected folder-relative names, got %+v", orders.Requests)
+	}
+	if orders.Requests[1].Headers.Get("Authorization") != "Bearer {{token}}" {
+		t.Errorf("Expected collection auth to be inherited in split files")
+	}
+	if orders.Variables["baseUrl"] == "" {
+		t.Errorf("Expected every split file to declare the collection variables")
+	}
+}
+
+func TestExport_RoundTrip(t *testing.T) {
+	original := Import(loadCollection(t), false)[0].HTTP
+	original.Path = "store.http"
+
+	data, err := Export("Store", []*parser.HTTPFile{original}).JSON()
+	if err != nil {
+		t.Fatalf("JSON failed: %v", err)
+	}
+
+	var c Collection
+	if err := json.Unmarshal(data, &c); err != nil {
+		t.Fatalf("Exported collection does not parse: %v", err)
+	}
+	if c.Info.Schema != SchemaURL {
+		t.Errorf("Expected v2.1 schema, got %q", c.Info.Schema)
+	}
+	if len(c.Item) != 3 || !c.Item[1].IsFolder() || c.Item[1].Name != "Auth" {
+		t.Fatalf("Expected folders to be rebuilt from names, got %+v", c.Item)
+	}
+	orders := c.Item[2]
+	if len(orders.Item) != 2 || orders.Item[0].Name != "Admin" || orders.Item[0].Item[0].Name != "Export orders" {
+		t.Errorf("Expected nested folders, got %+v", orders.Item)
+	}
+
+	login := c.Item[1].Item[0]
+	if login.Request.Body == nil || login.Request.Body.Options == nil || login.Request.Body.Options.Raw.Language != "json" {
+		t.Errorf("Expected raw JSON body, got %+v", login.Request.Body)
+	}
+	if len(login.Event) != 1 || login.Event[0].Script.Exec[0] != `pm.collectionVariables.set("token", pm.response.json().data.token);` {
+		t.Errorf("Expected capture test script, got %+v", login.Event)
+	}
+	if login.Request.URL.Host[0] != "{{baseUrl}}" || login.Request.URL.Path[0] != "login" {
+		t.Errorf("Expected URL parts, got %+v", login.Request.URL)
+	}
+
+	reimported := Import(&c, false)[0].HTTP
+	if len(reimported.Requests) != len(original.Requests) {
+		t.Fatalf("Expected %d requests after round trip, got %d", len(original.Requests), len(reimported.Requests))
+	}
+	for i, req := range reimported.Requests {
+		if req.Name != original.Requests[i].Name || req.URL != original.Requests[i].URL || req.Body != original.Requests[i].Body {
+			t.Errorf("Request %d changed in round trip: %+v", i, req)
+		}
+	}
+	if !reimported.Secrets["apiKey"] {
+		t.Errorf("Expected secret variables to survive round trip, got %v", reimported.Secrets)
+	}
+	for _, variable := range c.Variable {
+		if variable.Key == "apiKey" && variable.Value != "" {
+			t.Errorf("Expected secret value to be left out of the collection, got %q", variable.Value)
+		}
+		if variable.Key == "baseUrl" && variable.Value != original.Variables["baseUrl"] {
+			t.Errorf("Expected baseUrl %q, got %q", original.Variables["baseUrl"], variable.Value)
+		}
+	}
+	if reimported.Requests[1].Captures[0].JSONPath != "data.token" {
+		t.Errorf("Expected capture to survive round trip, got %+v", reimported.Requests[1].Captures)
+	}
+}
+
+func TestCaptureScript(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{path: "id", expected: `pm.collectionVariables.set("v", pm.response.json().id);`},
+		{path: "items.0.id", expected: `pm.collectionVariables.set("v", pm.response.json().items[0].id);`},
+		{path: "items.#.id", expected: "// hrun capture v = items.#.id"},
+		{path: "status", expected: `pm.collectionVariables.set("v", pm.response.code);`},
+		{path: "body.status", expected: `pm.collectionVariables.set("v", pm.response.json().status);`},
+		{path: "header.Location", expected: `pm.collectionVariables.set("v", pm.response.headers.get("Location"));`},
+		{path: "cookie.sid", expected: `pm.collectionVariables.set("v", pm.cookies.get("sid"));`},
+		{path: "regex:id=(\\d+)", expected: "// hrun capture v = regex:id=(\\d+)"},
+	}
+	for _, tt := range tests {
+		if got := captureScript(parser.CaptureRule{VariableName: "v", JSONPath: tt.path}); got != tt.expected {
+			t.Errorf("Expected %s, got %s", tt.expected, got)
+		}
+	}
+}
@@ -2,50 +2,142 @@ package runner
 
 import (
 	"fmt"
-	"os"
 	"strings"
 	"time"
 
+	"github.com/cassielabs/hrun/internal/dataset"
 	"github.com/cassielabs/hrun/internal/executor"
+	"github.com/cassielabs/hrun/internal/openapi"
 	"github.com/cassielabs/hrun/internal/parser"
+	"github.com/cassielabs/hrun/internal/schema"
+	"github.com/cassielabs/hrun/internal/secret"
+	"github.com/cassielabs/hrun/internal/snapshot"
 )
 
-func RunTests(filePath string, timeout time.Duration) error {
+type Options struct {
+	Timeout time.Duration
+	Retries int
+	// UpdateSnapshots rewrites stored snapshots instead of comparing
+	// against them.
+	UpdateSnapshots bool
+	// SchemaDir holds NAME.json schemas for requests without an @schema
+	// directive.
+	SchemaDir string
+	// OpenAPI is a spec to validate requests and responses against; a
+	// coverage report is printed after the run.
+	OpenAPI string
+	// Tags and ExcludeTags select requests by their @tag directives.
+	Tags        []string
+	ExcludeTags []string
+	// Secrets masks secret variable values in the output. The file's `@!`
+	// variables are added to it.
+	Secrets *secret.Masker
+	// ExecutorOptions are passed on to executor.New, after the options
+	// derived from the fields above.
+	ExecutorOptions []executor.Option
+}
+
+func RunTests(filePath string, opts Options) error {
 	httpFile, err := parser.ParseFile(filePath)
 	if err != nil {
 		return fmt.Errorf("failed to parse file: %w", err)
 	}
 
-	for key, value := range httpFile.Variables {
-		if envValue := os.Getenv(key); envValue != "" {
-			httpFile.Variables[key] = envValue
-		} else {
-			httpFile.Variables[key] = value
+	httpFile.ApplyEnv()
+
+	opts.Secrets.AddFile(httpFile)
+	mask := opts.Secrets.Mask
+
+	exec := executor.New(opts.Timeout, append([]executor.Option{executor.WithRetries(opts.Retries), executor.WithExecuteHook(opts.Secrets.Hook())}, opts.ExecutorOptions...)...)
+	
+	validator := schema.NewValidator()
+
+	var contract *openapi.Contract
+	if opts.OpenAPI != "" {
+		spec, err := openapi.Load(opts.OpenAPI)
+		if err != nil {
+			return fmt.Errorf("failed to load OpenAPI spec: %w", err)
+		}
+		if contract, err = openapi.NewContract(spec); err != nil {
+			return fmt.Errorf("failed to load OpenAPI spec: %w", err)
 		}
 	}
 
-	exec := executor.New(timeout)
-	
-	totalTests := len(httpFile.Requests)
+	// Each test is a request, or one row of a request's @data file, with
+	// the index of the request in the file.
+	type test struct {
+		index   int
+		req     parser.HTTPRequest
+		dataErr error
+	}
+	selected := parser.Select(httpFile.Requests, opts.Tags, opts.ExcludeTags)
+	var tests []test
+	focused := false
+	for i, req := range httpFile.Requests {
+		if !selected[i] {
+			continue
+		}
+		focused = focused || req.Only
+		iterations, err := dataset.Iterations(filePath, req)
+		if err != nil {
+			tests = append(tests, test{index: i, req: req, dataErr: err})
+			continue
+		}
+		for _, iteration := range iterations {
+			tests = append(tests, test{index: i, req: iteration})
+		}
+	}
+	totalTests := len(tests)
+	if totalTests == 0 && len(httpFile.Requests) > 0 {
+		return fmt.Errorf("no requests in %s match the tag filters", filePath)
+	}
 	passed := 0
 	failed := 0
+	skipped := 0
 
-	fmt.Printf("Running %d tests from %s\n\n", totalTests, filePath)
+	if focused {
+		fmt.Printf("Running %d tests from %s (focused with @only)\n\n", totalTests, filePath)
+	} else {
+		fmt.Printf("Running %d tests from %s\n\n", totalTests, filePath)
+	}
 
-	for i, req := range httpFile.Requests {
-		req.ApplyVariables(httpFile.Variables)
+	for _, t := range tests {
+		i, req := t.index, t.req
+		req.Headers = req.Headers.Clone()
+		varErr := t.dataErr
+		if !req.Skip && varErr == nil {
+			varErr = exec.ApplyVariables(httpFile, &req, httpFile.Variables)
+		}
 
 		testName := fmt.Sprintf("Test %d: %s %s", i+1, req.Method, req.URL)
-		if req.Name != "" {
-			testName = fmt.Sprintf("Test %d [%s]: %s %s", i+1, req.Name, req.Method, req.URL)
+		if label := dataset.Label(req); label != "" {
+			testName = fmt.Sprintf("Test %d [%s]: %s %s", i+1, label, req.Method, req.URL)
 		}
 
-		fmt.Printf("Running %s... ", testName)
+		fmt.Printf("Running %s... ", mask(testName))
+
+		if req.Skip {
+			if req.SkipReason != "" {
+				fmt.Printf("⏭️  SKIPPED (%s)\n", req.SkipReason)
+			} else {
+				fmt.Printf("⏭️  SKIPPED\n")
+			}
+			skipped++
+			continue
+		}
+
+		if varErr != nil {
+			fmt.Printf("❌ FAILED\n")
+			fmt.Printf("  Error: %s\n", mask(varErr.Error()))
+			failed++
+			continue
+		}
 
 		resp, err := exec.Execute(req)
 		if err != nil {
 			fmt.Printf("❌ FAILED\n")
-			fmt.Printf("  Error: %v\n", err)
+			fmt.Printf("  Error: %s\n", mask(err.Error()))
+			printAttempts(resp, mask)
 			failed++
 			continue
 		}
@@ -54,24 +146,88 @@ func RunTests(filePath string, timeout time.Duration) error {
 			httpFile.Variables[varName] = varValue
 		}
 
+		var contractNote string
+		if contract != nil {
+			op, violations, err := contract.Check(req, resp)
+			if err != nil || len(violations) > 0 {
+				fmt.Printf("❌ FAILED\n")
+				if err != nil {
+					fmt.Printf("  Contract: %s\n", mask(err.Error()))
+				}
+				for _, violation := range violations {
+					fmt.Printf("  Contract: %s\n", mask(violation.String()))
+				}
+				failed++
+				continue
+			}
+			if op == nil {
+				contractNote = fmt.Sprintf("no operation in %s matches %s %s", opts.OpenAPI, req.Method, req.URL)
+			}
+		}
+
+		var snapshotNote string
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			if len(resp.CaptureWarnings) > 0 {
+				fmt.Printf("❌ FAILED\n")
+				for _, warning := range resp.CaptureWarnings {
+					fmt.Printf("  Capture: %s\n", mask(warning))
+				}
+				failed++
+				continue
+			}
+
+			if err := checkSchema(validator, filePath, req, resp, opts.SchemaDir); err != nil {
+				fmt.Printf("❌ FAILED\n")
+				fmt.Printf("  Schema: %s\n", mask(err.Error()))
+				failed++
+				continue
+			}
+
+			snapshotNote, err = checkSnapshot(filePath, i, req, resp, opts.UpdateSnapshots, mask)
+			if err != nil {
+				fmt.Printf("❌ FAILED\n")
+				fmt.Printf("  Snapshot: %s\n", mask(err.Error()))
+				failed++
+				continue
+			}
+		}
+
 		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
 			fmt.Printf("✅ PASSED (Status: %d, Duration: %v)\n", resp.StatusCode, resp.Duration)
+			if snapshotNote != "" {
+				fmt.Printf("  Snapshot: %s\n", snapshotNote)
+			}
+			if contractNote != "" {
+				fmt.Printf("  Contract: warning, %s\n", mask(contractNote))
+			}
 			if len(resp.CapturedVariables) > 0 {
 				fmt.Printf("  Captured variables: %d\n", len(resp.CapturedVariables))
 			}
+			printAttempts(resp, mask)
 			passed++
 		} else {
 			fmt.Printf("❌ FAILED\n")
 			fmt.Printf("  Status: %d %s\n", resp.StatusCode, resp.Status)
 			if resp.Body != "" && len(resp.Body) < 200 {
-				fmt.Printf("  Body: %s\n", strings.TrimSpace(resp.Body))
+				fmt.Printf("  Body: %s\n", mask(strings.TrimSpace(resp.Body)))
+			}
+			if contractNote != "" {
+				fmt.Printf("  Contract: warning, %s\n", mask(contractNote))
 			}
+			printAttempts(resp, mask)
 			failed++
 		}
 	}
 
+	if contract != nil {
+		fmt.Print("\n" + contract.Coverage().String())
+	}
+
 	fmt.Print("\n" + strings.Repeat("-", 50) + "\n")
 	fmt.Printf("Test Results: %d/%d passed", passed, totalTests)
+	if skipped > 0 {
+		fmt.Printf(", %d skipped", skipped)
+	}
 	
 	if failed > 0 {
 		fmt.Printf(" (%d failed)\n", failed)
@@ -80,4 +236,83 @@ func RunTests(filePath string, timeout time.Duration) error {
 	
 	fmt.Printf(" ✅\n")
 	return nil
-}
\ No newline at end of file
+}
+func printAttempts(resp *executor.Response, mask func(string) string) {
+	if resp == nil || len(resp.Attempts) <= 1 {
+		return
+	}
+	fmt.Printf("  Attempts: %d\n", len(resp.Attempts))
+	for _, attempt := range resp.Attempts {
+		if attempt.Error != nil {
+			fmt.Printf("    #%d error: %s (%v)\n", attempt.Number, mask(attempt.Error.Error()), attempt.Duration)
+		} else {
+			fmt.Printf("    #%d status %d (%v)\n", attempt.Number, attempt.StatusCode, attempt.Duration)
+		}
+	}
+}
+
+// checkSchema validates the response body against the request's schema, if
+// it has one.
+func checkSchema(validator *schema.Validator, filePath string, req parser.HTTPRequest, resp *executor.Response, schemaDir string) error {
+	schemaPath := schema.Resolve(filePath, req.Schema, schemaDir, req.Name)
+	if schemaPath == "" {
+		return nil
+	}
+
+	violations, err := validator.Validate(schemaPath, resp.Body)
+	if err != nil {
+		return err
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+
+	lines := make([]string, len(violations))
+	for i, violation := range violations {
+		lines[i] = violation.String()
+	}
+	return fmt.Errorf("response does not match %s\n%s", schemaPath, indent(strings.Join(lines, "\n"), "    "))
+}
+
+// checkSnapshot compares the response with the stored snapshot, or writes
+// it when updating. Requests without a snapshot are not checked, and the
+// note says where it would be. Each @data row has its own snapshot. Only
+// 2xx responses get here; other statuses already fail the test. Secret
+// values are masked with mask before the snapshot is written or compared.
+func checkSnapshot(filePath string, index int, req parser.HTTPRequest, resp *executor.Response, update bool, mask func(string) string) (string, error) {
+	name := req.Name
+	if req.Iteration > 0 {
+		if name == "" {
+			name = fmt.Sprintf("request-%d", index+1)
+		}
+		name = fmt.Sprintf("%s-%d", name, req.Iteration)
+	}
+	path := snapshot.Path(filePath, name, index)
+	actual, err := snapshot.Normalize(resp, req.SnapshotIgnore, mask)
+	if err != nil {
+		return "", err
+	}
+
+	if update {
+		if err := snapshot.Write(path, actual); err != nil {
+			return "", err
+		}
+		return "updated " + path, nil
+	}
+
+	diff, exists, err := snapshot.Compare(path, actual)
+	if err != nil {
+		return "", err
+	}
+	if !exists {
+		return fmt.Sprintf("none at %s (run with --update-snapshots)", path), nil
+	}
+	if diff != "" {
+		return "", fmt.Errorf("response differs from %s\n%s", path, indent(diff, "    "))
+	}
+	return "matches " + path, nil
+}
+
+func indent(text, prefix string) string {
+	return prefix + strings.ReplaceAll(text, "\n", "\n"+prefix)
+}
@@ -0,0 +1,40 @@
+package runner
+
+import (
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cassielabs/hrun/internal/executor"
+	"github.com/cassielabs/hrun/internal/parser"
+)
+
+func TestCheckSnapshot(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "api.http")
+	path := filepath.Join(filepath.Dir(filePath), "__snapshots__", "api", "Get_order.json")
+	req := parser.HTTPRequest{Name: "Get order", Method: "GET", URL: "https://api.example.com/orders/1"}
+	resp := &executor.Response{StatusCode: 200, Headers: http.Header{"Content-Type": {"application/json"}}, Body: `{"id":1}`}
+
+	note, err := checkSnapshot(filePath, 0, req, resp, false, nil)
+	if err != nil {
+		t.Fatalf("checkSnapshot failed: %v", err)
+	}
+	expected := "none at " + path + " (run with --update-snapshots)"
+	if note != expected {
+		t.Errorf("Expected note %q, got %q", expected, note)
+	}
+
+	if note, err = checkSnapshot(filePath, 0, req, resp, true, nil); err != nil || note != "updated "+path {
+		t.Fatalf("Expected snapshot to be written to %s, got %q, %v", path, note, err)
+	}
+	if note, err = checkSnapshot(filePath, 0, req, resp, false, nil); err != nil || note != "matches "+path {
+		t.Errorf("Expected snapshot to match, got %q, %v", note, err)
+	}
+
+	resp.Body = `{"id":2}`
+	_, err = checkSnapshot(filePath, 0, req, resp, false, nil)
+	if err == nil || !strings.Contains(err.Error(), "response differs from "+path) {
+		t.Errorf("Expected a diff against %s, got %v", path, err)
+	}
+}
@@ -0,0 +1,120 @@
+package schema
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+// Violation is one place where a document breaks its schema.
+type Violation struct {
+	// Pointer is the JSON pointer of the offending value; empty for the
+	// document root.
+	Pointer string
+	Message string
+}
+
+func (v Violation) String() string {
+	pointer := v.Pointer
+	if pointer == "" {
+		pointer = "/"
+	}
+	return fmt.Sprintf("%s: %s", pointer, v.Message)
+}
+
+// Validator compiles JSON Schemas (draft 2020-12 unless the schema says
+// otherwise) and caches them by path.
+type Validator struct {
+	compiler *jsonschema.Compiler
+	schemas  map[string]*jsonschema.Schema
+}
+
+func NewValidator() *Validator {
+	compiler := jsonschema.NewCompiler()
+	compiler.DefaultDraft(jsonschema.Draft2020)
+	return &Validator{compiler: compiler, schemas: make(map[string]*jsonschema.Schema)}
+}
+
+// Validate checks a JSON document against the schema at schemaPath.
+func (v *Validator) Validate(schemaPath string, document string) ([]Violation, error) {
+	abs, err := filepath.Abs(schemaPath)
+	if err != nil {
+		return nil, err
+	}
+
+	sch, ok := v.schemas[abs]
+	if !ok {
+		sch, err = v.compiler.Compile(abs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load schema %s: %w", schemaPath, err)
+		}
+		v.schemas[abs] = sch
+	}
+
+	instance, err := jsonschema.UnmarshalJSON(strings.NewReader(document))
+	if err != nil {
+		return []Violation{{Message: "response body is not valid JSON"}}, nil
+	}
+
+	return Violations(sch.Validate(instance))
+}
+
+// Violations converts the error returned by jsonschema.Schema.Validate into
+// violations sorted by pointer. Errors other than validation failures are
+// returned as is.
+func Violations(err error) ([]Violation, error) {
+	if err == nil {
+		return nil, nil
+	}
+	validationErr, ok := err.(*jsonschema.ValidationError)
+	if !ok {
+		return nil, err
+	}
+
+	var violations []Violation
+	collectViolations(validationErr, &violations)
+	sort.SliceStable(violations, func(i, j int) bool {
+		return violations[i].Pointer < violations[j].Pointer
+	})
+	return violations, nil
+}
+
+// collectViolations gathers the leaves of the error tree; the inner nodes
+// only group and reference them.
+func collectViolations(err *jsonschema.ValidationError, violations *[]Violation) {
+	if len(err.Causes) > 0 {
+		for _, cause := range err.Causes {
+			collectViolations(cause, violations)
+		}
+		return
+	}
+	var pointer strings.Builder
+	for _, token := range err.InstanceLocation {
+		pointer.WriteString("/" + strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1"))
+	}
+	*violations = append(*violations, Violation{Pointer: pointer.String(), Message: err.BasicOutput().Error.String()})
+}
+
+// Resolve finds the schema for a request: an explicit @schema path,
+// relative to the .http file, or NAME.json in schemaDir. It returns "" when
+// the request has no schema.
+func Resolve(httpFilePath, schemaRef, schemaDir, requestName string) string {
+	if schemaRef != "" {
+		if filepath.IsAbs(schemaRef) {
+			return schemaRef
+		}
+		return filepath.Join(filepath.Dir(httpFilePath), schemaRef)
+	}
+	if schemaDir == "" || requestName == "" || strings.ContainsAny(requestName, `/\`) {
+		return ""
+	}
+	path := filepath.Join(schemaDir, requestName+".json")
+	if _, err := os.Stat(path); err != nil {
+		return ""
+	}
+	return path
+}
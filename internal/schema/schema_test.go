@@ -0,0 +1,103 @@
+package schema
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const userSchema = `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "type": "object",
+  "required": ["id", "email"],
+  "properties": {
+    "id": {"type": "integer"},
+    "email": {"type": "string"},
+    "tags": {"type": "array", "prefixItems": [{"type": "string"}]}
+  }
+}`
+
+func writeSchema(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write schema: %v", err)
+	}
+	return path
+}
+
+func TestValidator_Validate(t *testing.T) {
+	path := writeSchema(t, t.TempDir(), "user.json", userSchema)
+	validator := NewValidator()
+
+	tests := []struct {
+		name     string
+		document string
+		expected []string
+	}{
+		{name: "Valid", document: `{"id": 1, "email": "a@example.com"}`},
+		{name: "Wrong type", document: `{"id": "1", "email": "a@example.com"}`, expected: []string{"/id"}},
+		{name: "Missing property", document: `{"id": 1}`, expected: []string{"/"}},
+		{name: "Nested prefixItems", document: `{"id": 1, "email": "x", "tags": [5]}`, expected: []string{"/tags/0"}},
+		{name: "Not JSON", document: `<html>`, expected: []string{"/"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			violations, err := validator.Validate(path, tt.document)
+			if err != nil {
+				t.Fatalf("Validate failed: %v", err)
+			}
+			if len(violations) != len(tt.expected) {
+				t.Fatalf("Expected %d violations, got %v", len(tt.expected), violations)
+			}
+			for i, pointer := range tt.expected {
+				got := violations[i].Pointer
+				if got == "" {
+					got = "/"
+				}
+				if got != pointer {
+					t.Errorf("Expected pointer %q, got %q (%s)", pointer, got, violations[i].Message)
+				}
+			}
+		})
+	}
+}
+
+func TestValidator_InvalidSchema(t *testing.T) {
+	path := writeSchema(t, t.TempDir(), "broken.json", `{"type": 5}`)
+
+	if _, err := NewValidator().Validate(path, `{}`); err == nil {
+		t.Error("Expected error for invalid schema")
+	}
+	if _, err := NewValidator().Validate(filepath.Join(t.TempDir(), "missing.json"), `{}`); err == nil {
+		t.Error("Expected error for missing schema")
+	}
+}
+
+func TestResolve(t *testing.T) {
+	dir := t.TempDir()
+	writeSchema(t, dir, "Get User.json", userSchema)
+
+	tests := []struct {
+		name      string
+		ref       string
+		schemaDir string
+		request   string
+		expected  string
+	}{
+		{name: "Relative to http file", ref: "./schemas/user.json", expected: filepath.Join("api", "schemas", "user.json")},
+		{name: "Absolute", ref: "/tmp/user.json", expected: "/tmp/user.json"},
+		{name: "By request name", schemaDir: dir, request: "Get User", expected: filepath.Join(dir, "Get User.json")},
+		{name: "No schema for name", schemaDir: dir, request: "Other"},
+		{name: "No directive or dir", request: "Get User"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Resolve(filepath.Join("api", "users.http"), tt.ref, tt.schemaDir, tt.request); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,237 @@
+// Package secret masks the values of secret variables in output.
+package secret
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/cassielabs/hrun/internal/executor"
+	"github.com/cassielabs/hrun/internal/parser"
+)
+
+// Mask replaces secret values in output.
+const Mask = "••••"
+
+// DefaultPattern marks variables such as apiKey, password or accessToken as
+// secret by name.
+const DefaultPattern = `(?i)(password|passwd|secret|token|api[-_]?key|credential)`
+
+// MinLength is the length below which a secret value is not masked. Short
+// values such as `8` would otherwise be masked inside unrelated output.
+const MinLength = 4
+
+// IsPrivateEnvFile reports whether every variable in the env file at path
+// is secret, as in `.env.private` or `prod.private.env`.
+func IsPrivateEnvFile(path string) bool {
+	return path != "" && strings.Contains(filepath.Base(path), ".private")
+}
+
+// Masker remembers which variables are secret and the values they have
+// taken, and replaces those values in text. A nil *Masker masks nothing.
+// It is safe for concurrent use.
+type Masker struct {
+	pattern *regexp.Regexp
+
+	mu      sync.RWMutex
+	names   map[string]bool
+	values  map[string]bool
+	matcher *regexp.Regexp
+}
+
+// NewMasker returns a Masker treating names that match pattern as secret.
+// An empty pattern matches no names.
+func NewMasker(pattern string) (*Masker, error) {
+	m := &Masker{names: make(map[string]bool), values: make(map[string]bool)}
+	if pattern != "" {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		m.pattern = re
+	}
+	return m, nil
+}
+
+// AddNames marks variables as secret.
+func (m *Masker) AddNames(names ...string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, name := range names {
+		m.names[name] = true
+	}
+}
+
+// AddFile marks the file's `@!` variables as secret and tracks its
+// variables, along with the environment variables its requests and
+// variables use without defining them.
+func (m *Masker) AddFile(file *parser.HTTPFile) {
+	if m == nil || file == nil {
+		return
+	}
+	for name := range file.Secrets {
+		m.AddNames(name)
+	}
+	m.Track(file.Variables)
+
+	var names []string
+	for _, value := range file.Variables {
+		names = append(names, parser.VariableNames(value)...)
+	}
+	for i := range file.Requests {
+		names = append(names, file.Requests[i].VariableReferences()...)
+	}
+	env := make(map[string]string)
+	for _, name := range names {
+		if _, defined := file.Variables[name]; defined {
+			continue
+		}
+		if value, set := os.LookupEnv(name); set {
+			env[name] = value
+		}
+	}
+	m.Track(env)
+}
+
+// IsSecret reports whether the variable's value is masked.
+func (m *Masker) IsSecret(name string) bool {
+	if m == nil {
+		return false
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.names[name] || (m.pattern != nil && m.pattern.MatchString(name))
+}
+
+// Track remembers the values of the secret variables among variables, with
+// references to other variables expanded, so they are masked from then on.
+// Values shorter than MinLength are not masked.
+func (m *Masker) Track(variables map[string]string) {
+	if m == nil {
+		return
+	}
+	var values []string
+	for name, value := range variables {
+		if m.IsSecret(name) {
+			values = append(values, value, parser.ReplaceVariables(value, variables))
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, value := range values {
+		for _, form := range []string{value, url.QueryEscape(value), url.PathEscape(value)} {
+			if len(form) >= MinLength && !m.values[form] {
+				m.values[form] = true
+				m.matcher = nil
+			}
+		}
+	}
+}
+
+// Hook tracks secret variables captured from responses.
+func (m *Masker) Hook() executor.ExecuteHook {
+	return func(_ parser.HTTPRequest, resp *executor.Response) {
+		if resp != nil {
+			m.Track(resp.CapturedVariables)
+		}
+	}
+}
+
+// Mask replaces every tracked secret value in text with Mask. A value is
+// only replaced as a whole token: where it starts or ends with a letter or
+// digit, the text next to it must not be one, so a secret such as `1834`
+// is not masked inside `18345ms`.
+func (m *Masker) Mask(text string) string {
+	if m == nil {
+		return text
+	}
+	m.mu.RLock()
+	matcher := m.matcher
+	m.mu.RUnlock()
+	if matcher == nil {
+		matcher = m.buildMatcher()
+	}
+	if matcher == nil {
+		return text
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, loc := range matcher.FindAllStringIndex(text, -1) {
+		start, end := loc[0], loc[1]
+		if !tokenBoundary(text, start, end) {
+			continue
+		}
+		b.WriteString(text[last:start])
+		b.WriteString(Mask)
+		last = end
+	}
+	if last == 0 {
+		return text
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// tokenBoundary reports whether text[start:end] is not part of a longer
+// word.
+func tokenBoundary(text string, start, end int) bool {
+	if start > 0 {
+		before, _ := utf8.DecodeLastRuneInString(text[:start])
+		first, _ := utf8.DecodeRuneInString(text[start:end])
+		if isWordRune(before) && isWordRune(first) {
+			return false
+		}
+	}
+	if end < len(text) {
+		after, _ := utf8.DecodeRuneInString(text[end:])
+		lastRune, _ := utf8.DecodeLastRuneInString(text[start:end])
+		if isWordRune(after) && isWordRune(lastRune) {
+			return false
+		}
+	}
+	return true
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// buildMatcher compiles the tracked values into one expression, or returns
+// nil when there are none.
+func (m *Masker) buildMatcher() *regexp.Regexp {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.matcher != nil || len(m.values) == 0 {
+		return m.matcher
+	}
+
+	values := make([]string, 0, len(m.values))
+	for value := range m.values {
+		values = append(values, value)
+	}
+	// Longer values first, so a secret containing another is masked whole.
+	sort.Slice(values, func(i, j int) bool {
+		if len(values[i]) != len(values[j]) {
+			return len(values[i]) > len(values[j])
+		}
+		return values[i] < values[j]
+	})
+
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = regexp.QuoteMeta(value)
+	}
+	m.matcher = regexp.MustCompile(strings.Join(quoted, "|"))
+	return m.matcher
+}
@@ -0,0 +1,116 @@
+package secret
+
+import (
+	"testing"
+
+	"github.com/cassielabs/hrun/internal/executor"
+	"github.com/cassielabs/hrun/internal/parser"
+)
+
+func TestMasker(t *testing.T) {
+	masker, err := NewMasker(DefaultPattern)
+	if err != nil {
+		t.Fatalf("NewMasker failed: %v", err)
+	}
+
+	file, err := parser.ParseString(`@baseUrl = https://api.example.com
+@!clientId = client-42
+@apiKey = k 1/2
+@auth = Bearer {{apiKey}}-{{clientId}}
+`)
+	if err != nil {
+		t.Fatalf("ParseString failed: %v", err)
+	}
+	masker.AddFile(file)
+
+	for name, expected := range map[string]bool{"clientId": true, "apiKey": true, "accessToken": true, "DB_PASSWORD": true, "baseUrl": false, "auth": false} {
+		if masker.IsSecret(name) != expected {
+			t.Errorf("Expected IsSecret(%s) to be %v", name, expected)
+		}
+	}
+
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		{"GET https://api.example.com/x?key=k+1%2F2", "GET https://api.example.com/x?key=••••"},
+		{"Authorization: Bearer k 1/2-client-42", "Authorization: Bearer ••••-••••"},
+		{"/clients/client-42", "/clients/••••"},
+		{"nothing secret", "nothing secret"},
+	}
+	for _, tt := range tests {
+		if result := masker.Mask(tt.text); result != tt.expected {
+			t.Errorf("Expected %q, got %q", tt.expected, result)
+		}
+	}
+
+	masker.Hook()(parser.HTTPRequest{}, &executor.Response{CapturedVariables: map[string]string{"sessionToken": "tok-abc", "userId": "7"}})
+	if result := masker.Mask(`{"token":"tok-abc","id":7}`); result != `{"token":"••••","id":7}` {
+		t.Errorf("Expected captured secrets to be masked, got %q", result)
+	}
+}
+
+func TestMasker_Nil(t *testing.T) {
+	var masker *Masker
+	masker.AddNames("apiKey")
+	masker.Track(map[string]string{"apiKey": "k"})
+	if masker.IsSecret("apiKey") || masker.Mask("k") != "k" {
+		t.Error("Expected a nil masker to mask nothing")
+	}
+}
+
+func TestIsPrivateEnvFile(t *testing.T) {
+	tests := map[string]bool{
+		".env.private":            true,
+		"config/prod.private.env": true,
+		".env":                    false,
+		"private/.env":            false,
+		"":                        false,
+	}
+	for path, expected := range tests {
+		if IsPrivateEnvFile(path) != expected {
+			t.Errorf("Expected IsPrivateEnvFile(%q) to be %v", path, expected)
+		}
+	}
+}
+
+func TestMasker_ShortValuesAndWholeTokens(t *testing.T) {
+	masker, err := NewMasker(DefaultPattern)
+	if err != nil {
+		t.Fatalf("NewMasker failed: %v", err)
+	}
+	masker.Track(map[string]string{"passwordMinLength": "8", "tokenTtl": "1834", "apiKey": "k-9f2a"})
+
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		{"Duration: 183ms", "Duration: 183ms"},
+		{"minimum 8 characters", "minimum 8 characters"},
+		{"Duration: 18345ms", "Duration: 18345ms"},
+		{`{"ttl": 1834}`, `{"ttl": ••••}`},
+		{"key=k-9f2a&x=k-9f2ab", "key=••••&x=k-9f2ab"},
+	}
+	for _, tt := range tests {
+		if result := masker.Mask(tt.text); result != tt.expected {
+			t.Errorf("Expected %q, got %q", tt.expected, result)
+		}
+	}
+}
+
+func TestMasker_EnvVariables(t *testing.T) {
+	t.Setenv("HRUN_API_TOKEN", "env-secret-1")
+	masker, err := NewMasker(DefaultPattern)
+	if err != nil {
+		t.Fatalf("NewMasker failed: %v", err)
+	}
+	file, err := parser.ParseString("### Me\nGET https://api.example.com/me\nAuthorization: Bearer {{HRUN_API_TOKEN}}\n")
+	if err != nil {
+		t.Fatalf("ParseString failed: %v", err)
+	}
+	masker.AddFile(file)
+
+	if result := masker.Mask("Authorization: Bearer env-secret-1"); result != "Authorization: Bearer ••••" {
+		t.Errorf("Expected secrets read from the environment to be masked, got %q", result)
+	}
+}
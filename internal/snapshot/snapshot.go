@@ -0,0 +1,268 @@
+package snapshot
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/cassielabs/hrun/internal/executor"
+)
+
+const (
+	Dir = "__snapshots__"
+
+	ignoredValue = "<ignored>"
+)
+
+// volatileHeaders are left out of every snapshot because they change on
+// each response.
+var volatileHeaders = map[string]bool{
+	"Age":            true,
+	"Cf-Ray":         true,
+	"Content-Length": true,
+	"Date":           true,
+	"Etag":           true,
+	"Expires":        true,
+	"Last-Modified":  true,
+	"Nel":            true,
+	"Report-To":      true,
+	"Server-Timing":  true,
+	"Set-Cookie":     true,
+	"X-Request-Id":   true,
+}
+
+var unsafeNameRegex = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+type Snapshot struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    interface{}       `json:"body,omitempty"`
+}
+
+// Path returns where the snapshot of a request lives:
+// __snapshots__/FILE/REQUEST_NAME.json next to the .http file. Unnamed
+// requests use their 1-based position.
+func Path(httpFilePath, requestName string, index int) string {
+	base := strings.TrimSuffix(filepath.Base(httpFilePath), filepath.Ext(httpFilePath))
+	name := strings.Trim(unsafeNameRegex.ReplaceAllString(requestName, "_"), "_")
+	if name == "" {
+		name = fmt.Sprintf("request-%d", index+1)
+	}
+	return filepath.Join(filepath.Dir(httpFilePath), Dir, base, name+".json")
+}
+
+// Normalize turns a response into stable, indented JSON with volatile
+// headers dropped and the ignored fields masked. When mask is not nil, it is
+// applied to header values and to the strings and numbers in the body, so
+// secret values are not written to snapshots.
+func Normalize(resp *executor.Response, ignore []string, mask func(string) string) ([]byte, error) {
+	snap := Snapshot{
+		Status:  resp.StatusCode,
+		Headers: make(map[string]string),
+	}
+
+	for name, values := range resp.Headers {
+		canonical := http.CanonicalHeaderKey(name)
+		if volatileHeaders[canonical] {
+			continue
+		}
+		snap.Headers[canonical] = strings.Join(values, ", ")
+	}
+
+	if resp.Body != "" {
+		var body interface{}
+		if err := json.Unmarshal([]byte(resp.Body), &body); err == nil {
+			snap.Body = body
+		} else {
+			snap.Body = resp.Body
+		}
+	}
+
+	for _, rule := range ignore {
+		if err := applyIgnore(&snap, rule); err != nil {
+			return nil, err
+		}
+	}
+	if mask != nil {
+		for name, value := range snap.Headers {
+			snap.Headers[name] = mask(value)
+		}
+		snap.Body = maskValues(snap.Body, mask)
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(snap); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// applyIgnore masks one field, or drops a header. Rules are `status`,
+// `header Name` (or `header.Name`) and `body.path.to.field`, where `*`
+// matches every key or array element.
+func applyIgnore(snap *Snapshot, rule string) error {
+	rule = strings.TrimSpace(rule)
+	switch {
+	case rule == "status":
+		snap.Status = 0
+	case strings.HasPrefix(rule, "header ") || strings.HasPrefix(rule, "header."):
+		// Ignored headers are dropped like volatile ones, so it does not
+		// matter whether a response has them.
+		delete(snap.Headers, http.CanonicalHeaderKey(strings.TrimSpace(rule[len("header "):])))
+	case rule == "body":
+		if snap.Body != nil {
+			snap.Body = ignoredValue
+		}
+	case strings.HasPrefix(rule, "body."):
+		maskPath(snap.Body, strings.Split(strings.TrimPrefix(rule, "body."), "."))
+	default:
+		return fmt.Errorf("invalid snapshot ignore rule %q", rule)
+	}
+	return nil
+}
+
+// maskValues applies mask to every string and number in a decoded JSON
+// value. A number that contains a secret becomes the masked string.
+func maskValues(value interface{}, mask func(string) string) interface{} {
+	switch v := value.(type) {
+	case string:
+		return mask(v)
+	case float64:
+		text := strconv.FormatFloat(v, 'f', -1, 64)
+		if masked := mask(text); masked != text {
+			return masked
+		}
+	case map[string]interface{}:
+		for k, item := range v {
+			v[k] = maskValues(item, mask)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = maskValues(item, mask)
+		}
+	}
+	return value
+}
+
+func maskPath(value interface{}, path []string) {
+	if len(path) == 0 {
+		return
+	}
+	key, rest := path[0], path[1:]
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for k := range v {
+			if key != "*" && k != key {
+				continue
+			}
+			if len(rest) == 0 {
+				v[k] = ignoredValue
+			} else {
+				maskPath(v[k], rest)
+			}
+		}
+	case []interface{}:
+		for i := range v {
+			if key != "*" && strconv.Itoa(i) != key {
+				continue
+			}
+			if len(rest) == 0 {
+				v[i] = ignoredValue
+			} else {
+				maskPath(v[i], rest)
+			}
+		}
+	}
+}
+
+func Write(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// Compare diffs actual against the stored snapshot. It returns an empty
+// diff when they match and ok=false when no snapshot exists yet.
+func Compare(path string, actual []byte) (diff string, ok bool, err error) {
+	expected, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	if string(expected) == string(actual) {
+		return "", true, nil
+	}
+	return Diff(string(expected), string(actual)), true, nil
+}
+
+// maxDiffCells caps the size of the table Diff builds for the lines that
+// differ, so two large snapshots with little in common do not take
+// gigabytes to compare.
+const maxDiffCells = 1 << 22
+
+// Diff renders a line diff between two texts, with `-` for expected lines
+// and `+` for actual ones. Lines the texts start and end with in common are
+// skipped first. When the rest is still too large to diff, only the first
+// line that differs is reported.
+func Diff(expected, actual string) string {
+	a := strings.Split(strings.TrimRight(expected, "\n"), "\n")
+	b := strings.Split(strings.TrimRight(actual, "\n"), "\n")
+
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	a, b = a[prefix:], b[prefix:]
+	for len(a) > 0 && len(b) > 0 && a[len(a)-1] == b[len(b)-1] {
+		a, b = a[:len(a)-1], b[:len(b)-1]
+	}
+	if (len(a)+1)*(len(b)+1) > maxDiffCells {
+		return fmt.Sprintf("differs from line %d on (%d expected and %d actual lines differ, too many to diff)", prefix+1, len(a), len(b))
+	}
+
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var out []string
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			out = append(out, "- "+a[i])
+			i++
+		default:
+			out = append(out, "+ "+b[j])
+			j++
+		}
+	}
+	return strings.Join(out, "\n")
+}
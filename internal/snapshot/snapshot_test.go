@@ -0,0 +1,138 @@
+package snapshot
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cassielabs/hrun/internal/executor"
+)
+
+func TestPath(t *testing.T) {
+	tests := []struct {
+		file     string
+		name     string
+		index    int
+		expected string
+	}{
+		{"api/users.http", "Get User", 0, filepath.Join("api", Dir, "users", "Get_User.json")},
+		{"users.http", "Step 1: login/now", 0, filepath.Join(Dir, "users", "Step_1_login_now.json")},
+		{"users.http", "", 2, filepath.Join(Dir, "users", "request-3.json")},
+	}
+	for _, tt := range tests {
+		if got := Path(tt.file, tt.name, tt.index); got != tt.expected {
+			t.Errorf("Path(%q, %q): expected %q, got %q", tt.file, tt.name, tt.expected, got)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	resp := &executor.Response{
+		StatusCode: 200,
+		Headers: http.Header{
+			"Content-Type": []string{"application/json"},
+			"Date":         []string{"Mon, 01 Jan 2024 00:00:00 GMT"},
+			"X-Version":    []string{"7"},
+		},
+		Body: `{"id":1,"createdAt":"2024-01-01","items":[{"id":"a","at":1},{"id":"b","at":2}]}`,
+	}
+
+	data, err := Normalize(resp, []string{"body.createdAt", "body.items.*.at", "header X-Version"}, nil)
+	if err != nil {
+		t.Fatalf("Normalize failed: %v", err)
+	}
+	text := string(data)
+
+	if strings.Contains(text, "Date") {
+		t.Errorf("Expected volatile Date header to be dropped:\n%s", text)
+	}
+	if strings.Contains(text, "2024-01-01") || strings.Contains(text, `"at": 1`) {
+		t.Errorf("Expected ignored body fields to be masked:\n%s", text)
+	}
+	if strings.Contains(text, "X-Version") || !strings.Contains(text, "Content-Type") {
+		t.Errorf("Expected the ignored header to be dropped and the others kept:\n%s", text)
+	}
+	if !strings.Contains(text, `"id": "a"`) {
+		t.Errorf("Expected other fields to be kept:\n%s", text)
+	}
+
+	if _, err := Normalize(resp, []string{"cookies.session"}, nil); err == nil {
+		t.Error("Expected error for invalid ignore rule")
+	}
+}
+
+func TestNormalize_Mask(t *testing.T) {
+	resp := &executor.Response{
+		StatusCode: 200,
+		Headers:    http.Header{"X-Token": []string{"Bearer tok-123"}},
+		Body:       `{"token":"tok-123","otp":482913,"count":2}`,
+	}
+	mask := strings.NewReplacer("tok-123", "••••", "482913", "••••").Replace
+
+	data, err := Normalize(resp, nil, mask)
+	if err != nil {
+		t.Fatalf("Normalize failed: %v", err)
+	}
+	text := string(data)
+	if strings.Contains(text, "tok-123") || strings.Contains(text, "482913") {
+		t.Errorf("Expected secret values to be masked:\n%s", text)
+	}
+	if !strings.Contains(text, `"X-Token": "Bearer ••••"`) || !strings.Contains(text, `"otp": "••••"`) || !strings.Contains(text, `"count": 2`) {
+		t.Errorf("Expected masked values in place and other values kept:\n%s", text)
+	}
+}
+
+func TestWriteAndCompare(t *testing.T) {
+	path := filepath.Join(t.TempDir(), Dir, "users", "Get_User.json")
+
+	if _, exists, err := Compare(path, []byte("{}\n")); err != nil || exists {
+		t.Fatalf("Expected missing snapshot, got exists=%v err=%v", exists, err)
+	}
+
+	if err := Write(path, []byte("{\n  \"status\": 200\n}\n")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	diff, exists, err := Compare(path, []byte("{\n  \"status\": 200\n}\n"))
+	if err != nil || !exists || diff != "" {
+		t.Errorf("Expected match, got diff=%q exists=%v err=%v", diff, exists, err)
+	}
+
+	diff, _, _ = Compare(path, []byte("{\n  \"status\": 404\n}\n"))
+	if diff != "-   \"status\": 200\n+   \"status\": 404" {
+		t.Errorf("Unexpected diff:\n%s", diff)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	expected := "a\nb\nc\nd"
+	actual := "a\nc\nd\ne"
+
+	if got := Diff(expected, actual); got != "- b\n+ e" {
+		t.Errorf("Unexpected diff:\n%s", got)
+	}
+}
+
+func TestDiff_Large(t *testing.T) {
+	lines := func(prefix string, n int) []string {
+		out := make([]string, n)
+		for i := range out {
+			out[i] = fmt.Sprintf("%s%d", prefix, i)
+		}
+		return out
+	}
+
+	same := lines("same", 5000)
+	expected := strings.Join(append(append(append([]string{}, same...), "old"), same...), "\n")
+	actual := strings.Join(append(append(append([]string{}, same...), "new"), same...), "\n")
+	if got := Diff(expected, actual); got != "- old\n+ new" {
+		t.Errorf("Expected only the changed line, got:\n%s", got)
+	}
+
+	got := Diff("head\n"+strings.Join(lines("a", 10000), "\n"), "head\n"+strings.Join(lines("b", 10000), "\n"))
+	if got != "differs from line 2 on (10000 expected and 10000 actual lines differ, too many to diff)" {
+		t.Errorf("Expected the first differing line, got %q", got)
+	}
+}
@@ -15,6 +15,8 @@ type keyMap struct {
 	Variables   key.Binding
 	Delete      key.Binding
 	Paste       key.Binding
+	Copy        key.Binding
+	Tag         key.Binding
 }
 
 var keys = keyMap{
@@ -66,4 +68,12 @@ var keys = keyMap{
 		key.WithKeys("ctrl+v"),
 		key.WithHelp("ctrl+v", "paste"),
 	),
+	Copy: key.NewBinding(
+		key.WithKeys("c"),
+		key.WithHelp("c", "copy as curl"),
+	),
+	Tag: key.NewBinding(
+		key.WithKeys("t"),
+		key.WithHelp("t", "filter by tag"),
+	),
 }
\ No newline at end of file
@@ -1,17 +1,20 @@
 package tui
 
 import (
+	"errors"
 	"fmt"
-	"os"
 	"os/exec"
 	"path/filepath"
 	"sort"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/atotto/clipboard"
 	"github.com/cassielabs/hrun/internal/executor"
+	"github.com/cassielabs/hrun/internal/export"
 	"github.com/cassielabs/hrun/internal/parser"
+	"github.com/cassielabs/hrun/internal/secret"
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
@@ -28,6 +31,7 @@ const (
 	stateVariables
 	stateVariableEdit
 	stateQuitConfirm
+	statePrompt
 )
 
 type model struct {
@@ -54,6 +58,18 @@ type model struct {
 	editingValue       string
 	editMode           bool
 	quitConfirmIndex   int
+	status             string
+	tagFilter          string
+	secrets            *secret.Masker
+	promptUndefined    bool
+	prompts            []parser.Prompt
+	promptIndex        int
+	promptValue        string
+	promptValues       map[string]string
+	// promptCopy copies the request as curl after the last prompt instead
+	// of sending it, and promptReturn is the state to go back to then.
+	promptCopy         bool
+	promptReturn       state
 }
 
 type responseMsg struct {
@@ -61,10 +77,15 @@ type responseMsg struct {
 	err      error
 }
 
-func initialModel(filePath string, timeout time.Duration) model {
+// statusMsg replaces the status line.
+type statusMsg string
+
+func initialModel(filePath string, timeout time.Duration, secrets *secret.Masker, promptUndefined bool, opts ...executor.Option) model {
 	m := model{
+		secrets:             secrets,
+		promptUndefined:     promptUndefined,
 		state:               stateFileList,
-		exec:                executor.New(timeout),
+		exec:                executor.New(timeout, opts...),
 		filePath:            filePath,
 		viewport:            viewport.New(80, 20),
 		descriptionViewport: viewport.New(80, 20),
@@ -74,7 +95,9 @@ func initialModel(filePath string, timeout time.Duration) model {
 	if filePath != "" {
 		httpFile, err := parser.ParseFile(filePath)
 		if err == nil {
+			httpFile.ApplyEnv()
 			m.httpFile = httpFile
+			m.secrets.AddFile(httpFile)
 			m.requests = httpFile.Requests
 			m.state = stateRequestList
 		}
@@ -101,7 +124,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.descriptionViewport.Height = m.height - 8
 
 		if m.response != nil {
-			content := executor.FormatResponse(m.response)
+			content := m.mask(executor.FormatResponse(m.response))
 			wrapped := wrapContent(content, m.viewport.Width)
 			m.viewport.SetContent(wrapped)
 		}
@@ -132,6 +155,29 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, nil
 		}
 
+		if m.state == statePrompt {
+			switch {
+			case key.Matches(msg, keys.Paste):
+				if clipboardText, err := clipboard.ReadAll(); err == nil {
+					m.promptValue += clipboardText
+				}
+			case msg.String() == "esc":
+				m.state = m.promptReturn
+				m.prompts = nil
+			case msg.String() == "enter":
+				return m.answerPrompt()
+			case msg.String() == "backspace":
+				if len(m.promptValue) > 0 {
+					m.promptValue = m.promptValue[:len(m.promptValue)-1]
+				}
+			default:
+				if len(msg.String()) == 1 {
+					m.promptValue += msg.String()
+				}
+			}
+			return m, nil
+		}
+
 		if m.state == stateVariableEdit {
 			switch {
 			case key.Matches(msg, keys.Paste):
@@ -172,6 +218,8 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, nil
 		}
 
+		m.status = ""
+
 		switch {
 		case key.Matches(msg, keys.Quit):
 			m.previousState = m.state
@@ -226,9 +274,14 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 			case stateRequestList:
 				if len(m.requests) > 0 {
+					req := m.requests[m.requestIndex]
+					if prompts := m.pendingPrompts(req); len(prompts) > 0 {
+						m.startPrompts(prompts, false)
+						return m, nil
+					}
 					m.loading = true
 					m.state = stateResponse
-					return m, m.executeRequest(m.requests[m.requestIndex])
+					return m, m.executeRequest(req, nil)
 				}
 			case stateVariables:
 				if m.variableIndex < len(m.variableKeys) {
@@ -275,6 +328,9 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				if content == "" {
 					content = "No description available for this request."
 				}
+				if params := req.QueryParams(); len(params) > 0 {
+					content += "\n\n" + renderQueryParams(params)
+				}
 				m.descriptionViewport.SetContent(content)
 				m.state = stateDescription
 			}
@@ -290,6 +346,22 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.state = stateVariables
 			}
 
+		case key.Matches(msg, keys.Copy):
+			if (m.state == stateRequestList || m.state == stateResponse) && len(m.requests) > 0 {
+				req := m.requests[m.requestIndex]
+				if prompts := m.pendingPrompts(req); len(prompts) > 0 {
+					m.startPrompts(prompts, true)
+					return m, nil
+				}
+				m.status = "Copying..."
+				return m, m.copyRequest(req, nil)
+			}
+
+		case key.Matches(msg, keys.Tag):
+			if m.state == stateRequestList && m.httpFile != nil {
+				m.cycleTagFilter()
+			}
+
 		case key.Matches(msg, keys.Delete):
 			if m.state == stateVariables && len(m.variableKeys) > 0 {
 				key := m.variableKeys[m.variableIndex]
@@ -309,9 +381,11 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case *parser.HTTPFile:
 		m.httpFile = msg
+		m.secrets.AddFile(msg)
 		m.requests = msg.Requests
 		m.state = stateRequestList
 		m.requestIndex = 0
+		m.tagFilter = ""
 
 	case responseMsg:
 		m.loading = false
@@ -321,11 +395,14 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			for varName, varValue := range m.response.CapturedVariables {
 				m.runtimeVariables[varName] = varValue
 			}
-			content := executor.FormatResponse(m.response)
+			content := m.mask(executor.FormatResponse(m.response))
 			wrapped := wrapContent(content, m.viewport.Width)
 			m.viewport.SetContent(wrapped)
 		}
 
+	case statusMsg:
+		m.status = m.mask(string(msg))
+
 	case error:
 		m.err = msg
 		m.loading = false
@@ -353,6 +430,8 @@ func (m model) View() string {
 		baseView = m.renderVariables()
 	case stateVariableEdit:
 		baseView = m.renderVariableEdit()
+	case statePrompt:
+		return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, m.renderPrompt(), lipgloss.WithWhitespaceChars(" "), lipgloss.WithWhitespaceForeground(lipgloss.Color("236")))
 	case stateQuitConfirm:
 		switch m.previousState {
 		case stateFileList:
@@ -405,6 +484,9 @@ func (m model) renderRequestList() string {
 	if m.httpFile != nil && m.httpFile.Path != "" {
 		title = fmt.Sprintf("HTTP Requests - %s", filepath.Base(m.httpFile.Path))
 	}
+	if m.tagFilter != "" {
+		title += fmt.Sprintf(" (tag: %s)", m.tagFilter)
+	}
 	b.WriteString(titleStyle.Render(title) + "\n\n")
 
 	if len(m.requests) == 0 {
@@ -419,6 +501,12 @@ func (m model) renderRequestList() string {
 			if req.Name != "" {
 				line = fmt.Sprintf("[%s] %s", req.Name, line)
 			}
+			if len(req.Tags) > 0 {
+				line += " " + tagStyle.Render("#"+strings.Join(req.Tags, " #"))
+			}
+			if req.Skip {
+				line += " " + tagStyle.Render("(skipped)")
+			}
 			
 			if i == m.requestIndex {
 				items[i] = selectedItemStyle.Render("→ ") + line
@@ -430,10 +518,11 @@ func (m model) renderRequestList() string {
 		b.WriteString(listStyle.Width(m.width - 4).Render(content))
 	}
 
-	help := "↑/↓: navigate • enter: execute • d: description • e: edit • v: variables • q: quit"
+	help := "↑/↓: navigate • enter: execute • d: description • e: edit • v: variables • c: copy as curl • t: filter by tag • q: quit"
 	if m.filePath == "" {
 		help += " • esc: back to files"
 	}
+	b.WriteString(m.renderStatus())
 	b.WriteString("\n\n" + helpStyle.Render(help))
 	return b.String()
 }
@@ -451,16 +540,24 @@ func (m model) renderResponse() string {
 	if m.loading {
 		b.WriteString(loadingStyle.Render("Executing request..."))
 	} else if m.err != nil {
-		b.WriteString(statusErrorStyle.Render(fmt.Sprintf("Error: %v", m.err)))
+		b.WriteString(statusErrorStyle.Render(m.mask(fmt.Sprintf("Error: %v", m.err))))
 	} else if m.response != nil {
 		content := m.viewport.View()
 		b.WriteString(responseStyle.Width(m.width - 4).Height(m.height - 6).Render(content))
 	}
 
-	b.WriteString("\n\n" + helpStyle.Render("↑/↓: scroll • esc: back to requests • q: quit"))
+	b.WriteString(m.renderStatus())
+	b.WriteString("\n\n" + helpStyle.Render("↑/↓: scroll • c: copy as curl • esc: back to requests • q: quit"))
 	return b.String()
 }
 
+func (m model) renderStatus() string {
+	if m.status == "" {
+		return ""
+	}
+	return "\n" + statusStyle.Render(m.status)
+}
+
 func (m model) renderDescription() string {
 	var b strings.Builder
 
@@ -478,6 +575,23 @@ func (m model) renderDescription() string {
 	return b.String()
 }
 
+// renderQueryParams lays out query parameters as a two-column table.
+func renderQueryParams(params []parser.QueryParam) string {
+	width := len("Name")
+	for _, param := range params {
+		width = max(width, lipgloss.Width(param.Name))
+	}
+
+	var b strings.Builder
+	b.WriteString(queryHeaderStyle.Render("Query Parameters") + "\n")
+	b.WriteString(queryHeaderStyle.Render(fmt.Sprintf("%-*s  %s", width, "Name", "Value")) + "\n")
+	for _, param := range params {
+		padding := strings.Repeat(" ", width-lipgloss.Width(param.Name))
+		b.WriteString(param.Name + padding + "  " + param.Value + "\n")
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
 func (m model) loadFiles() tea.Cmd {
 	return func() tea.Msg {
 		files, err := filepath.Glob("*.http")
@@ -494,41 +608,184 @@ func (m model) loadFile(path string) tea.Cmd {
 		if err != nil {
 			return err
 		}
-		
-		for key, value := range httpFile.Variables {
-			if envValue := os.Getenv(key); envValue != "" {
-				httpFile.Variables[key] = envValue
-			} else {
-				httpFile.Variables[key] = value
-			}
-		}
-		
+		httpFile.ApplyEnv()
 		return httpFile
 	}
 }
 
-func (m model) executeRequest(req parser.HTTPRequest) tea.Cmd {
+// executeRequest sends req with values, such as answers to prompts, added
+// to the variables.
+func (m model) executeRequest(req parser.HTTPRequest, values map[string]string) tea.Cmd {
+	variables := m.variables()
+	for k, v := range values {
+		variables[k] = v
+	}
 	return func() tea.Msg {
-		variables := make(map[string]string)
+		if err := m.exec.ApplyVariables(m.httpFile, &req, variables); err != nil {
+			return responseMsg{response: &executor.Response{Error: err}, err: err}
+		}
 
-		if m.httpFile != nil {
-			for k, v := range m.httpFile.Variables {
-				variables[k] = v
+		resp, err := m.exec.Execute(req)
+		return responseMsg{
+			response: resp,
+			err:      err,
+		}
+	}
+}
+
+// copyRequest resolves req like executeRequest, which may run the requests
+// it references, and copies it to the clipboard as curl.
+func (m model) copyRequest(req parser.HTTPRequest, values map[string]string) tea.Cmd {
+	variables := m.variables()
+	for k, v := range values {
+		variables[k] = v
+	}
+	return func() tea.Msg {
+		req.Headers = req.Headers.Clone()
+		if err := m.exec.ApplyVariables(m.httpFile, &req, variables); err != nil {
+			return statusMsg(fmt.Sprintf("Copy failed: %v", err))
+		}
+		if err := clipboard.WriteAll(export.Curl(req)); err != nil {
+			return statusMsg(fmt.Sprintf("Copy failed: %v", err))
+		}
+		return statusMsg("Copied as curl")
+	}
+}
+
+// startPrompts opens the prompt dialog for prompts, to send the request
+// after the last answer, or to copy it when copy is set.
+func (m *model) startPrompts(prompts []parser.Prompt, copy bool) {
+	m.prompts = prompts
+	m.promptIndex = 0
+	m.promptValue = ""
+	m.promptValues = make(map[string]string)
+	m.promptCopy = copy
+	m.promptReturn = stateRequestList
+	if copy {
+		m.promptReturn = m.state
+	}
+	m.state = statePrompt
+}
+
+// pendingPrompts lists the variables to ask for before req runs: its
+// @prompt variables, and with promptUndefined any other variable that is
+// not defined.
+func (m model) pendingPrompts(req parser.HTTPRequest) []parser.Prompt {
+	prompts := append([]parser.Prompt(nil), req.Prompts...)
+	if !m.promptUndefined {
+		return prompts
+	}
+
+	variables := m.variables()
+	for _, prompt := range req.Prompts {
+		variables[prompt.Name] = ""
+	}
+	req.Headers = req.Headers.Clone()
+	var unresolved *parser.UnresolvedError
+	if err := req.ApplyVariables(variables); errors.As(err, &unresolved) {
+		for _, name := range unresolved.Names {
+			if _, ok := parser.ParseResponseReference(name); !ok {
+				prompts = append(prompts, parser.Prompt{Name: name})
 			}
 		}
+	}
+	return prompts
+}
 
-		for k, v := range m.runtimeVariables {
-			variables[k] = v
+// answerPrompt records the value typed for the current prompt and moves to
+// the next one, or sends or copies the request after the last. Answers for undefined
+// variables are kept as runtime variables; @prompt answers are used once.
+func (m model) answerPrompt() (tea.Model, tea.Cmd) {
+	prompt := m.prompts[m.promptIndex]
+	m.promptValues[prompt.Name] = m.promptValue
+	if prompt.Secret {
+		m.secrets.AddNames(prompt.Name)
+		m.secrets.Track(map[string]string{prompt.Name: m.promptValue})
+	}
+	if !m.isRequestPrompt(prompt.Name) {
+		m.runtimeVariables[prompt.Name] = m.promptValue
+	}
+
+	m.promptIndex++
+	m.promptValue = ""
+	if m.promptIndex < len(m.prompts) {
+		return m, nil
+	}
+
+	m.prompts = nil
+	if m.promptCopy {
+		m.state = m.promptReturn
+		m.status = "Copying..."
+		return m, m.copyRequest(m.requests[m.requestIndex], m.promptValues)
+	}
+	m.loading = true
+	m.state = stateResponse
+	return m, m.executeRequest(m.requests[m.requestIndex], m.promptValues)
+}
+
+func (m model) isRequestPrompt(name string) bool {
+	for _, prompt := range m.requests[m.requestIndex].Prompts {
+		if prompt.Name == name {
+			return true
 		}
+	}
+	return false
+}
 
-		req.ApplyVariables(variables)
+// cycleTagFilter moves to the next tag used in the file, and back to all
+// requests after the last one.
+func (m *model) cycleTagFilter() {
+	tags := m.httpFile.Tags()
+	next := ""
+	for i, tag := range tags {
+		if m.tagFilter == "" {
+			next = tag
+			break
+		}
+		if strings.EqualFold(tag, m.tagFilter) && i+1 < len(tags) {
+			next = tags[i+1]
+			break
+		}
+	}
+	if len(tags) == 0 {
+		m.status = "No tagged requests in this file"
+	}
 
-		resp, err := m.exec.Execute(req)
-		return responseMsg{
-			response: resp,
-			err:      err,
+	m.tagFilter = next
+	m.requests = m.httpFile.Requests
+	if next != "" {
+		m.requests = nil
+		for _, req := range m.httpFile.Requests {
+			if req.HasTag(next) {
+				m.requests = append(m.requests, req)
+			}
+		}
+	}
+	m.requestIndex = 0
+}
+
+// mask hides the values of secret variables, including ones captured or
+// set since the file was loaded.
+func (m model) mask(text string) string {
+	m.secrets.Track(m.variables())
+	return m.secrets.Mask(text)
+}
+
+// variables merges the file variables with the runtime ones, which win.
+func (m model) variables() map[string]string {
+	variables := make(map[string]string)
+
+	if m.httpFile != nil {
+		for k, v := range m.httpFile.Variables {
+			variables[k] = v
 		}
 	}
+
+	for k, v := range m.runtimeVariables {
+		variables[k] = v
+	}
+
+	return variables
 }
 
 func wrapContent(content string, width int) string {
@@ -568,13 +825,7 @@ func (m model) openInVim() tea.Cmd {
 			return err
 		}
 
-		for key, value := range httpFile.Variables {
-			if envValue := os.Getenv(key); envValue != "" {
-				httpFile.Variables[key] = envValue
-			} else {
-				httpFile.Variables[key] = value
-			}
-		}
+		httpFile.ApplyEnv()
 
 		return httpFile
 	})
@@ -595,6 +846,9 @@ func (m model) renderVariables() string {
 			}
 		}
 
+		if m.secrets.IsSecret(key) {
+			value = secret.Mask
+		}
 		line := fmt.Sprintf("%s %s = %s", source, key, value)
 		if i == m.variableIndex {
 			items = append(items, selectedItemStyle.Render("→ ")+line)
@@ -635,11 +889,15 @@ func (m model) renderVariableEdit() string {
 	}
 	b.WriteString(keyPrompt + "\n")
 
+	value := m.editingValue
+	if value != "" && m.secrets.IsSecret(m.editingKey) {
+		value = secret.Mask
+	}
 	valuePrompt := "Value: "
 	if m.editMode {
-		valuePrompt += m.editingValue + "_"
+		valuePrompt += value + "_"
 	} else {
-		valuePrompt += m.editingValue
+		valuePrompt += value
 	}
 	b.WriteString(valuePrompt + "\n")
 
@@ -647,6 +905,29 @@ func (m model) renderVariableEdit() string {
 	return b.String()
 }
 
+func (m model) renderPrompt() string {
+	var b strings.Builder
+
+	prompt := m.prompts[m.promptIndex]
+	title := prompt.Name
+	if len(m.prompts) > 1 {
+		title += fmt.Sprintf(" (%d/%d)", m.promptIndex+1, len(m.prompts))
+	}
+	b.WriteString(popupTitleStyle.Render(title) + "\n\n")
+	if prompt.Description != "" {
+		b.WriteString(prompt.Description + "\n")
+	}
+
+	value := m.promptValue
+	if prompt.Secret || m.secrets.IsSecret(prompt.Name) {
+		value = strings.Repeat("•", utf8.RuneCountInString(value))
+	}
+	b.WriteString("> " + value + "_\n\n")
+
+	b.WriteString(popupHelpStyle.Render("ctrl+v: paste • enter: confirm • esc: cancel"))
+	return popupStyle.Render(b.String())
+}
+
 func (m *model) updateVariableKeys() {
 	keys := make([]string, 0, len(m.runtimeVariables))
 	for k := range m.runtimeVariables {
@@ -684,9 +965,9 @@ func (m model) renderQuitConfirm() string {
 	return popupStyle.Render(b.String())
 }
 
-func Run(filePath string, timeout time.Duration) error {
+func Run(filePath string, timeout time.Duration, secrets *secret.Masker, promptUndefined bool, opts ...executor.Option) error {
 	p := tea.NewProgram(
-		initialModel(filePath, timeout),
+		initialModel(filePath, timeout, secrets, promptUndefined, opts...),
 		tea.WithAltScreen(),
 		tea.WithMouseCellMotion(),
 	)
@@ -0,0 +1,169 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cassielabs/hrun/internal/executor"
+	"github.com/cassielabs/hrun/internal/parser"
+	"github.com/cassielabs/hrun/internal/secret"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestCycleTagFilter(t *testing.T) {
+	file := &parser.HTTPFile{Requests: []parser.HTTPRequest{
+		{Name: "charge", Tags: []string{"smoke", "payments"}},
+		{Name: "refund", Tags: []string{"payments"}},
+		{Name: "health"},
+	}}
+	m := model{httpFile: file, requests: file.Requests, requestIndex: 2}
+
+	expected := []struct {
+		filter string
+		count  int
+	}{
+		{filter: "payments", count: 2},
+		{filter: "smoke", count: 1},
+		{filter: "", count: 3},
+	}
+	for _, step := range expected {
+		m.cycleTagFilter()
+		if m.tagFilter != step.filter || len(m.requests) != step.count || m.requestIndex != 0 {
+			t.Errorf("Expected filter %q with %d requests, got %q with %d (index %d)", step.filter, step.count, m.tagFilter, len(m.requests), m.requestIndex)
+		}
+	}
+}
+
+func TestRenderVariables_MasksSecrets(t *testing.T) {
+	secrets, err := secret.NewMasker(secret.DefaultPattern)
+	if err != nil {
+		t.Fatalf("NewMasker failed: %v", err)
+	}
+	m := model{
+		secrets:          secrets,
+		runtimeVariables: map[string]string{"accessToken": "tok-abc", "userId": "7"},
+		width:            80,
+	}
+	m.updateVariableKeys()
+
+	view := m.renderVariables()
+	if strings.Contains(view, "tok-abc") || !strings.Contains(view, "accessToken = "+secret.Mask) {
+		t.Errorf("Expected the captured token to be masked, got %s", view)
+	}
+	if !strings.Contains(view, "userId = 7") {
+		t.Errorf("Expected other variables to be shown, got %s", view)
+	}
+	if masked := m.mask(`{"token":"tok-abc"}`); masked != `{"token":"••••"}` {
+		t.Errorf("Expected responses to be masked, got %s", masked)
+	}
+}
+
+func TestRenderQueryParams(t *testing.T) {
+	table := renderQueryParams([]parser.QueryParam{{Name: "page", Value: "1"}, {Name: "q", Value: "Zoë Smith"}})
+	lines := strings.Split(table, "\n")
+	if len(lines) != 4 {
+		t.Fatalf("Expected a title, a header and 2 rows, got %q", table)
+	}
+	if lines[2] != "page  1" || lines[3] != "q     Zoë Smith" {
+		t.Errorf("Expected aligned rows, got %q and %q", lines[2], lines[3])
+	}
+}
+
+func TestPromptDialog(t *testing.T) {
+	secrets, err := secret.NewMasker("")
+	if err != nil {
+		t.Fatalf("NewMasker failed: %v", err)
+	}
+	file := &parser.HTTPFile{
+		Variables: map[string]string{"baseUrl": "https://api.example.com"},
+		Requests: []parser.HTTPRequest{{
+			Method:  "POST",
+			URL:     "{{baseUrl}}/verify?tenant={{tenant}}&ref={{login.response.body.id}}",
+			Headers: map[string][]string{"X-Pin": {"{{pin}}"}},
+			Prompts: []parser.Prompt{{Name: "pin", Description: "Card PIN", Secret: true}},
+		}},
+	}
+	m := model{
+		state:            stateRequestList,
+		secrets:          secrets,
+		httpFile:         file,
+		requests:         file.Requests,
+		runtimeVariables: make(map[string]string),
+		width:            80,
+		height:           24,
+	}
+
+	if prompts := m.pendingPrompts(file.Requests[0]); len(prompts) != 1 || prompts[0].Name != "pin" {
+		t.Errorf("Expected only the @prompt without --prompt-undefined, got %v", prompts)
+	}
+
+	m.promptUndefined = true
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = updated.(model)
+	if m.state != statePrompt || len(m.prompts) != 2 || m.prompts[1].Name != "tenant" {
+		t.Fatalf("Expected prompts for pin and tenant, got state %v with %v", m.state, m.prompts)
+	}
+
+	for _, r := range "4321" {
+		updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+		m = updated.(model)
+	}
+	if view := m.View(); strings.Contains(view, "4321") || !strings.Contains(view, "Card PIN") {
+		t.Errorf("Expected a masked secret with its description, got %s", view)
+	}
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = updated.(model)
+
+	for _, r := range "acme" {
+		updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+		m = updated.(model)
+	}
+	updated, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = updated.(model)
+	if m.state != stateResponse || cmd == nil {
+		t.Errorf("Expected the request to be sent after the last prompt, got state %v", m.state)
+	}
+	if m.runtimeVariables["tenant"] != "acme" || m.runtimeVariables["pin"] != "" {
+		t.Errorf("Expected only the undefined variable to be kept, got %v", m.runtimeVariables)
+	}
+	if masked := secrets.Mask("pin 4321"); masked != "pin "+secret.Mask {
+		t.Errorf("Expected the secret answer to be masked, got %s", masked)
+	}
+}
+
+func TestCopyPrompts(t *testing.T) {
+	file := &parser.HTTPFile{
+		Variables: map[string]string{"baseUrl": "https://api.example.com"},
+		Requests: []parser.HTTPRequest{{
+			Method:  "GET",
+			URL:     "{{baseUrl}}/verify?otp={{otp}}",
+			Headers: map[string][]string{},
+			Prompts: []parser.Prompt{{Name: "otp"}},
+		}},
+	}
+	m := model{
+		state:            stateResponse,
+		exec:             executor.New(time.Second),
+		httpFile:         file,
+		requests:         file.Requests,
+		runtimeVariables: make(map[string]string),
+	}
+
+	updated, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'c'}})
+	m = updated.(model)
+	if m.state != statePrompt || !m.promptCopy || cmd != nil {
+		t.Fatalf("Expected the prompt dialog before copying, got state %v", m.state)
+	}
+
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'7'}})
+	m = updated.(model)
+	updated, cmd = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = updated.(model)
+	if m.state != stateResponse || cmd == nil {
+		t.Fatalf("Expected to go back to the response and copy in the background, got state %v", m.state)
+	}
+	if _, ok := cmd().(statusMsg); !ok {
+		t.Errorf("Expected the copy to report a status")
+	}
+}
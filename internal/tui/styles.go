@@ -30,6 +30,16 @@ var (
 		Foreground(lipgloss.Color("196")).
 		Bold(true)
 
+	statusStyle = lipgloss.NewStyle().
+		Foreground(lipgloss.Color("42"))
+
+	tagStyle = lipgloss.NewStyle().
+		Foreground(lipgloss.Color("135"))
+
+	queryHeaderStyle = lipgloss.NewStyle().
+		Foreground(lipgloss.Color("63")).
+		Bold(true)
+
 	helpStyle = lipgloss.NewStyle().
 		Foreground(lipgloss.Color("241")).
 		MarginTop(1)